@@ -0,0 +1,465 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/gangachris/hlf/compose"
+	"github.com/gangachris/hlf/configtx"
+	"github.com/gangachris/hlf/configtxgen"
+	"github.com/gangachris/hlf/cryptogen"
+	"github.com/gangachris/hlf/msp"
+	"github.com/gangachris/hlf/semver"
+	"github.com/gangachris/hlf/spec"
+	"github.com/spf13/cobra"
+)
+
+var (
+	artifactsForce      bool
+	artifactsConfigOnly bool
+	artifactsRotate     bool
+)
+
+// artifactsCmd represents the artifacts command
+var artifactsCmd = &cobra.Command{
+	Use:   "artifacts",
+	Short: "Generate the crypto material and channel artifacts of a network spec",
+	Long: `Generate the files a network needs in the artifacts directory of its spec, artifacts
+next to hlf.yaml by default. The fabric tools of the active version are used, see
+hlf download binaries.`,
+}
+
+// artifactsCryptoCmd generates the crypto material of a network
+var artifactsCryptoCmd = &cobra.Command{
+	Use:   "crypto",
+	Short: "Generate the MSP and TLS material of the network",
+	Long: `Generate the MSP and TLS material of the organizations, nodes and users of the network spec
+in crypto-config in the artifacts directory. Existing material is kept and the organizations and
+nodes added to the spec are generated, --force generates everything again.
+
+The material is generated by the generator of the crypto section of the spec: cryptogen writes
+crypto-config.yaml and runs cryptogen on it, native generates it with hlf, keeping the CAs of
+the organizations. --rotate issues new certificates to every node and user with the existing
+CAs, native only.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		network, err := loadNetworkSpec(specFile)
+		if err != nil {
+			errorExit(err)
+		}
+
+		tool, err := cryptogenTool()
+		if err != nil {
+			errorExit(err)
+		}
+
+		ctx, cancel := interruptContext()
+		defer cancel()
+
+		if err := generateCrypto(ctx, network, tool, artifactsForce, artifactsConfigOnly, artifactsRotate, os.Stdout); err != nil {
+			errorExit(err)
+		}
+	},
+}
+
+// artifactsConfigtxCmd writes the configtx.yaml of a network
+var artifactsConfigtxCmd = &cobra.Command{
+	Use:   "configtx",
+	Short: "Write configtx.yaml for the fabric version of the network",
+	Long: `Write configtx.yaml from the network spec to the artifacts directory, with the
+organizations, policies, capabilities and orderer of the fabric version of the network
+and a profile for the genesis block and each channel. etcdraft networks of fabric 2.3 and
+newer have no system channel, their channel profiles carry the orderer configuration.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		network, err := loadNetworkSpec(specFile)
+		if err != nil {
+			errorExit(err)
+		}
+
+		if err := writeConfigtx(network, os.Stdout); err != nil {
+			errorExit(err)
+		}
+	},
+}
+
+// artifactsComposeCmd writes the docker-compose file of a network
+var artifactsComposeCmd = &cobra.Command{
+	Use:   "compose",
+	Short: "Write the docker-compose file of the network",
+	Long: `Write docker-compose.yaml to the artifacts directory with a service for every orderer,
+peer, CA, CouchDB, kafka and zookeeper node of the network and a CLI container. The images are
+tagged for the fabric version of the network and host ports are allocated from the portBase of
+the spec. The crypto material must be generated first, see hlf artifacts crypto.
+
+The file can be run with docker-compose and committed, hlf network up creates the same containers.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		network, err := loadNetworkSpec(specFile)
+		if err != nil {
+			errorExit(err)
+		}
+
+		options, err := composeOptions(network)
+		if err != nil {
+			errorExit(err)
+		}
+
+		if err := writeCompose(network, options, os.Stdout); err != nil {
+			errorExit(err)
+		}
+	},
+}
+
+// artifactsGenerateCmd generates every artifact of a network
+var artifactsGenerateCmd = &cobra.Command{
+	Use:   "generate",
+	Short: "Generate the crypto material, configtx.yaml, the channel artifacts and docker-compose.yaml",
+	Long: `Generate the crypto material, write configtx.yaml and run configtxgen on it
+for the orderer genesis block, the creation transaction of every channel and the anchor peer
+updates of their organizations, written to channel-artifacts in the artifacts directory.
+etcdraft networks of fabric 2.3 and newer get the genesis block of every channel instead of
+the orderer genesis block and the creation transactions, the orderers join the channels with
+osnadmin through the channel participation API. Existing channel artifacts are kept, --force
+generates everything again. docker-compose.yaml is written last.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		network, err := loadNetworkSpec(specFile)
+		if err != nil {
+			errorExit(err)
+		}
+
+		crypto, err := cryptogenTool()
+		if err != nil {
+			errorExit(err)
+		}
+		configtxTool, err := configtxgenTool(network)
+		if err != nil {
+			errorExit(err)
+		}
+		options, err := composeOptions(network)
+		if err != nil {
+			errorExit(err)
+		}
+
+		ctx, cancel := interruptContext()
+		defer cancel()
+
+		if err := generateArtifacts(ctx, network, crypto, configtxTool, options, artifactsForce, os.Stdout); err != nil {
+			errorExit(err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(artifactsCmd)
+	artifactsCmd.AddCommand(artifactsCryptoCmd)
+	artifactsCmd.AddCommand(artifactsConfigtxCmd)
+	artifactsCmd.AddCommand(artifactsComposeCmd)
+	artifactsCmd.AddCommand(artifactsGenerateCmd)
+
+	artifactsCmd.PersistentFlags().StringVarP(&specFile, "file", "f", spec.FileName, "Network spec file")
+	artifactsCmd.PersistentFlags().BoolVar(&artifactsForce, "force", false, "Generate everything again instead of keeping what exists")
+	artifactsCryptoCmd.Flags().BoolVar(&artifactsConfigOnly, "config-only", false, "Only write crypto-config.yaml")
+	artifactsCryptoCmd.Flags().BoolVar(&artifactsRotate, "rotate", false, "Issue new certificates with the existing CAs")
+}
+
+// fabricBinary returns the path of a fabric tool in the platform binaries directory.
+func fabricBinary(name string) (string, error) {
+	dir, err := getPlatformBinariesDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "bin", name), nil
+}
+
+func cryptogenTool() (cryptogen.Tool, error) {
+	path, err := fabricBinary("cryptogen")
+	return cryptogen.Tool{Path: path}, err
+}
+
+// configtxgenTool returns configtxgen with the artifacts directory of a network
+// as its config directory.
+func configtxgenTool(network *spec.Network) (configtxgen.Tool, error) {
+	path, err := fabricBinary("configtxgen")
+	return configtxgen.Tool{Path: path, ConfigDir: network.ArtifactsDir()}, err
+}
+
+// checkToolVersion warns when a fabric tool is not the version of the network.
+func checkToolVersion(name, version, fabricVersion string, out io.Writer) {
+	newer, err := semver.CorrectVersion(fabricVersion, version)
+	if err != nil {
+		return
+	}
+	older, err := semver.CorrectVersion(version, fabricVersion)
+	if err != nil || (newer && older) {
+		return
+	}
+
+	color.New(color.FgYellow).Fprintf(out, "%s is version %s but the network uses fabric %s, run hlf download binaries --fabric-version %s\n",
+		name, version, fabricVersion, fabricVersion)
+}
+
+// generateCrypto generates the crypto material of a network with the generator
+// of its spec. With cryptogen, it writes the crypto-config.yaml of the network
+// and runs cryptogen on it.
+func generateCrypto(ctx context.Context, network *spec.Network, tool cryptogen.Tool, force, configOnly, rotate bool, out io.Writer) error {
+	dir := network.ArtifactsDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	if network.Crypto.Generator == spec.GeneratorNative {
+		if configOnly {
+			return fmt.Errorf("error: --config-only writes the config of cryptogen, the network uses the %s generator", spec.GeneratorNative)
+		}
+		return generateNativeCrypto(network, force, rotate, out)
+	}
+	if rotate {
+		return fmt.Errorf("error: certificates are rotated by the %s generator, set crypto.generator in %s", spec.GeneratorNative, spec.FileName)
+	}
+
+	data, err := cryptogen.FromSpec(network).Marshal()
+	if err != nil {
+		return err
+	}
+
+	configFile := filepath.Join(dir, cryptogen.ConfigFile)
+	if err := ioutil.WriteFile(configFile, data, 0644); err != nil {
+		return err
+	}
+	color.New(color.FgGreen).Fprintf(out, "Wrote %s\n", configFile)
+
+	if configOnly {
+		return nil
+	}
+
+	if version, err := tool.Version(ctx); err == nil {
+		checkToolVersion("cryptogen", version, network.Fabric, out)
+	}
+
+	output := filepath.Join(dir, cryptogen.OutputDir)
+	if force {
+		if err := os.RemoveAll(output); err != nil {
+			return err
+		}
+	}
+
+	if _, err := os.Stat(output); err == nil {
+		color.New(color.FgBlue).Fprintf(out, "Extending the crypto material in %s\n", output)
+		if err := tool.Extend(ctx, configFile, output); err != nil {
+			return err
+		}
+	} else {
+		color.New(color.FgBlue).Fprintf(out, "Generating the crypto material in %s\n", output)
+		if err := tool.Generate(ctx, configFile, output); err != nil {
+			return err
+		}
+	}
+
+	color.New(color.FgGreen).Fprintln(out, "Crypto material generated")
+	return nil
+}
+
+// generateNativeCrypto generates the crypto material of a network with hlf.
+func generateNativeCrypto(network *spec.Network, force, rotate bool, out io.Writer) error {
+	options, err := msp.OptionsFromSpec(network)
+	if err != nil {
+		return err
+	}
+
+	output := filepath.Join(network.ArtifactsDir(), cryptogen.OutputDir)
+	if force {
+		if err := os.RemoveAll(output); err != nil {
+			return err
+		}
+	}
+
+	generator := msp.Generator{Dir: output, Options: options}
+	color.New(color.FgBlue).Fprintf(out, "Generating the crypto material in %s\n", output)
+	for _, org := range msp.FromSpec(network) {
+		result, err := generator.Generate(org, rotate)
+		if err != nil {
+			return err
+		}
+		for _, name := range result.Generated {
+			fmt.Fprintf(out, "Generated %s\n", name)
+		}
+		if len(result.Kept) > 0 {
+			fmt.Fprintf(out, "Kept %d identities of %s\n", len(result.Kept), org.Domain)
+		}
+	}
+
+	color.New(color.FgGreen).Fprintln(out, "Crypto material generated")
+	return nil
+}
+
+// writeConfigtx writes the configtx.yaml of a network to its artifacts directory.
+func writeConfigtx(network *spec.Network, out io.Writer) error {
+	dir := network.ArtifactsDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	data, err := configtx.Render(network)
+	if err != nil {
+		return fmt.Errorf("error rendering %s: %s", configtx.ConfigFile, err.Error())
+	}
+
+	configFile := filepath.Join(dir, configtx.ConfigFile)
+	if err := ioutil.WriteFile(configFile, data, 0644); err != nil {
+		return err
+	}
+	color.New(color.FgGreen).Fprintf(out, "Wrote %s\n", configFile)
+	return nil
+}
+
+// composeOptions returns the image tags of the fabric version of a network,
+// with the configured fabric-ca version, and the docker socket of the
+// configured runtime.
+func composeOptions(network *spec.Network) (compose.Options, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return compose.Options{}, err
+	}
+	tags, err := configuredImageTags(cfg, network.Fabric)
+	if err != nil {
+		return compose.Options{}, err
+	}
+
+	options := compose.Options{
+		FabricTag:     tags.Fabric,
+		CATag:         tags.CA,
+		ThirdPartyTag: tags.ThirdParty,
+	}
+	if endpoint, err := dockerEndpoint(); err == nil && strings.HasPrefix(endpoint.Host, "unix://") {
+		options.DockerSocket = endpoint.SocketPath()
+	}
+	return options, nil
+}
+
+// writeCompose writes the docker-compose file of a network to its artifacts directory.
+func writeCompose(network *spec.Network, options compose.Options, out io.Writer) error {
+	project, err := compose.FromSpec(network, options)
+	if err != nil {
+		return err
+	}
+	data, err := project.Marshal()
+	if err != nil {
+		return fmt.Errorf("error rendering %s: %s", compose.FileName, err.Error())
+	}
+
+	file := filepath.Join(network.ArtifactsDir(), compose.FileName)
+	if err := ioutil.WriteFile(file, data, 0644); err != nil {
+		return err
+	}
+	color.New(color.FgGreen).Fprintf(out, "Wrote %s\n", file)
+	return nil
+}
+
+// generateArtifacts generates the crypto material, configtx.yaml, the channel
+// artifacts and the docker-compose file of a network.
+func generateArtifacts(ctx context.Context, network *spec.Network, crypto cryptogen.Tool, configtxTool configtxgen.Tool, options compose.Options, force bool, out io.Writer) error {
+	if err := generateCrypto(ctx, network, crypto, force, false, false, out); err != nil {
+		return err
+	}
+	if err := writeConfigtx(network, out); err != nil {
+		return err
+	}
+	if err := generateChannelArtifacts(ctx, network, configtxTool, force, out); err != nil {
+		return err
+	}
+	return writeCompose(network, options, out)
+}
+
+// generateChannelArtifacts runs configtxgen for the orderer genesis block, the
+// channel creation transactions and the anchor peer updates of a network. The
+// orderers of networks using the channel participation API have no system
+// channel, they join each channel from its genesis block. An existing artifact
+// is kept, the genesis block of a running network must not change.
+func generateChannelArtifacts(ctx context.Context, network *spec.Network, tool configtxgen.Tool, force bool, out io.Writer) error {
+	dir := filepath.Join(network.ArtifactsDir(), configtxgen.OutputDir)
+	if force {
+		if err := os.RemoveAll(dir); err != nil {
+			return err
+		}
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	if version, err := tool.Version(ctx); err == nil {
+		checkToolVersion("configtxgen", version, network.Fabric, out)
+	}
+
+	participation := configtx.ChannelParticipation(network)
+	if !participation {
+		genesis := filepath.Join(dir, configtxgen.GenesisBlockFile)
+		err := generateFile(genesis, out, func() error {
+			return tool.GenesisBlock(ctx, configtx.GenesisProfile, genesis)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, channel := range network.Channels {
+		profile := configtx.ChannelProfile(channel.Name)
+
+		var err error
+		if participation {
+			block := filepath.Join(dir, configtxgen.ChannelBlockFile(channel.Name))
+			err = generateFile(block, out, func() error {
+				return tool.ChannelBlock(ctx, profile, channel.Name, block)
+			})
+		} else {
+			tx := filepath.Join(dir, configtxgen.ChannelTxFile(channel.Name))
+			err = generateFile(tx, out, func() error {
+				return tool.ChannelTx(ctx, profile, channel.Name, tx)
+			})
+		}
+		if err != nil {
+			return err
+		}
+
+		for _, name := range channel.Organizations {
+			org, ok := network.Organization(name)
+			if !ok || len(org.AnchorPeers) == 0 {
+				continue
+			}
+
+			anchors := filepath.Join(dir, configtxgen.AnchorPeersFile(channel.Name, org.MSPID))
+			err := generateFile(anchors, out, func() error {
+				return tool.AnchorPeers(ctx, profile, channel.Name, org.Name, anchors)
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	color.New(color.FgGreen).Fprintln(out, "Channel artifacts generated")
+	return nil
+}
+
+// generateFile runs generate unless the file exists, a partial file is removed
+// when generate fails.
+func generateFile(file string, out io.Writer, generate func() error) error {
+	if _, err := os.Stat(file); err == nil {
+		color.New(color.FgBlue).Fprintf(out, "Keeping %s\n", file)
+		return nil
+	}
+
+	color.New(color.FgBlue).Fprintf(out, "Generating %s\n", file)
+	if err := generate(); err != nil {
+		os.Remove(file)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,233 @@
+package cmd
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gangachris/hlf/compose"
+	"github.com/gangachris/hlf/configtxgen"
+	"github.com/gangachris/hlf/spec"
+)
+
+// fakeConfigtxgen returns a configtxgen that writes the output file, the
+// argument after the output flag, and logs its arguments to the log file of
+// the network directory.
+func fakeConfigtxgen(t *testing.T, network *spec.Network) configtxgen.Tool {
+	script := filepath.Join(network.Dir, "configtxgen")
+	content := `#!/bin/sh
+printf '%s\n' "$*" >> ` + filepath.Join(network.Dir, "log") + `
+while [ $# -gt 0 ]; do
+	case "$1" in -output*) touch "$2";; esac
+	shift
+done
+`
+	if err := ioutil.WriteFile(script, []byte(content), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return configtxgen.Tool{Path: script, ConfigDir: network.ArtifactsDir()}
+}
+
+func Test_generateChannelArtifacts(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hlf-artifacts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	network, err := spec.Parse(filepath.Join(dir, spec.FileName), []byte(`version: 1
+name: dev
+fabric: 1.4.4
+organizations:
+  - name: Org1
+  - name: Org2
+channels:
+  - name: mychannel
+    organizations: [Org1]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	network.Dir = dir
+	tool := fakeConfigtxgen(t, network)
+
+	var out strings.Builder
+	if err := generateChannelArtifacts(context.Background(), network, tool, false, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(network.ArtifactsDir(), configtxgen.OutputDir, "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range files {
+		files[i] = filepath.Base(files[i])
+	}
+	sort.Strings(files)
+	want := []string{"genesis.block", "mychannel-Org1MSPanchors.tx", "mychannel.tx"}
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("channel artifacts = %v, want %v", files, want)
+	}
+
+	// existing artifacts are kept
+	out.Reset()
+	if err := generateChannelArtifacts(context.Background(), network, tool, false, &out); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out.String(), "Generating") {
+		t.Errorf("output =\n%s\nwant the existing artifacts kept", out.String())
+	}
+
+	log, err := ioutil.ReadFile(filepath.Join(dir, "log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Count(string(log), "-profile"); got != len(want) {
+		t.Errorf("configtxgen ran %d times, want %d:\n%s", got, len(want), log)
+	}
+}
+
+func Test_generateChannelArtifacts_channelParticipation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hlf-artifacts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	network, err := spec.Parse(filepath.Join(dir, spec.FileName), []byte(`version: 1
+name: dev
+fabric: 2.3.0
+orderer:
+  type: etcdraft
+  organizations:
+    - name: Orderer
+      nodes: 3
+organizations:
+  - name: Org1
+channels:
+  - name: mychannel
+  - name: private
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	network.Dir = dir
+	tool := fakeConfigtxgen(t, network)
+
+	var out strings.Builder
+	if err := generateChannelArtifacts(context.Background(), network, tool, false, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	// the orderers join each channel from its genesis block, there is no system channel
+	files, err := filepath.Glob(filepath.Join(network.ArtifactsDir(), configtxgen.OutputDir, "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range files {
+		files[i] = filepath.Base(files[i])
+	}
+	sort.Strings(files)
+	want := []string{"mychannel-Org1MSPanchors.tx", "mychannel.block", "private-Org1MSPanchors.tx", "private.block"}
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("channel artifacts = %v, want %v", files, want)
+	}
+
+	log, err := ioutil.ReadFile(filepath.Join(dir, "log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(log), "-profile mychannel -channelID mychannel -outputBlock") {
+		t.Errorf("configtxgen ran:\n%s\nwant the genesis block of mychannel", log)
+	}
+}
+
+func Test_composeOptions(t *testing.T) {
+	home, restore := withHome(t)
+	defer restore()
+
+	network, err := spec.Parse(filepath.Join(home, spec.FileName), []byte(`version: 1
+name: dev
+fabric: 2.2.0
+organizations:
+  - name: Org1
+    ca: true
+    stateDatabase: couchdb
+channels:
+  - name: mychannel
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	network.Dir = home
+
+	// the CA container is configured with the key of the generated CA
+	caDir := filepath.Join(network.ArtifactsDir(), "crypto-config", "peerOrganizations", "org1.example.com", "ca")
+	if err := os.MkdirAll(caDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(caDir, "4a1b_sk"), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		caVersion string
+		want      []string
+	}{
+		{
+			name: "fabric 2.x images are tagged with their version",
+			want: []string{
+				"hyperledger/fabric-ca:1.4.9",
+				"hyperledger/fabric-couchdb:0.4.18",
+				"hyperledger/fabric-orderer:2.2.0",
+				"hyperledger/fabric-peer:2.2.0",
+				"hyperledger/fabric-tools:2.2.0",
+			},
+		},
+		{
+			name:      "configured fabric-ca version",
+			caVersion: "1.5.2",
+			want: []string{
+				"hyperledger/fabric-ca:1.5.2",
+				"hyperledger/fabric-couchdb:0.4.18",
+				"hyperledger/fabric-orderer:2.2.0",
+				"hyperledger/fabric-peer:2.2.0",
+				"hyperledger/fabric-tools:2.2.0",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.caVersion != "" {
+				os.Setenv("HLF_FABRIC_CA_VERSION", tt.caVersion)
+				defer os.Unsetenv("HLF_FABRIC_CA_VERSION")
+			}
+
+			options, err := composeOptions(network)
+			if err != nil {
+				t.Fatal(err)
+			}
+			project, err := compose.FromSpec(network, options)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var images []string
+			for _, container := range project.Containers {
+				if !contains(images, container.Image) {
+					images = append(images, container.Image)
+				}
+			}
+			sort.Strings(images)
+			if !reflect.DeepEqual(images, tt.want) {
+				t.Errorf("images = %v, want %v", images, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/docker/go-units"
+	"github.com/fatih/color"
+	"github.com/gangachris/hlf/compose"
+	"github.com/gangachris/hlf/docker"
+	"github.com/spf13/cobra"
+)
+
+var (
+	cleanNetwork string
+	cleanAll     bool
+	cleanDryRun  bool
+	cleanForce   bool
+)
+
+// cleanCmd groups the commands removing what fabric leaves behind
+var cleanCmd = &cobra.Command{
+	Use:   "clean",
+	Short: "Remove artifacts fabric leaves behind",
+}
+
+// cleanChaincodeCmd removes chaincode containers and images
+var cleanChaincodeCmd = &cobra.Command{
+	Use:   "chaincode",
+	Short: "Remove the containers and images peers create for chaincodes",
+	Long: `Peers build an image and start a container for every chaincode version they run, named
+dev-<peer>-<chaincode>-<version>. They are left behind when networks are torn down or
+chaincodes are upgraded. Only the chaincodes of the peers of hlf networks are removed, the
+containers of peers with the same name in other networks are told by their docker network:
+
+	hlf clean chaincode              chaincodes of the peers of hlf networks that no longer exist
+	hlf clean chaincode -n dev       chaincodes of the peers of a network
+	hlf clean chaincode --all        chaincodes of the peers of every hlf network
+
+Running chaincode containers and their images are kept unless --force is given.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if cleanAll && cleanNetwork != "" {
+			errorExit(fmt.Errorf("error: --all and --network can not be used together"))
+		}
+
+		dockerClient, err := newDockerClient()
+		if err != nil {
+			errorExit(err)
+		}
+
+		ctx := context.Background()
+		filter := docker.ChaincodeFilter{}
+		stale := !cleanAll && cleanNetwork == ""
+		if filter.Peers, err = chaincodePeers(ctx, dockerClient, cleanNetwork, stale); err != nil {
+			errorExit(err)
+		}
+		if cleanNetwork != "" && len(filter.Peers) == 0 {
+			errorExit(fmt.Errorf("error: no peers found in network %s", cleanNetwork))
+		}
+
+		if err := cleanChaincodes(ctx, dockerClient, filter, cleanDryRun, cleanForce, os.Stdout); err != nil {
+			errorExit(err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(cleanCmd)
+	cleanCmd.AddCommand(cleanChaincodeCmd)
+
+	cleanChaincodeCmd.Flags().StringVarP(&cleanNetwork, "network", "n", "", "Only remove the chaincodes of the peers of this network")
+	cleanChaincodeCmd.Flags().BoolVar(&cleanAll, "all", false, "Remove the chaincodes of the peers of every hlf network")
+	cleanChaincodeCmd.Flags().BoolVar(&cleanDryRun, "dry-run", false, "Only list the containers and images that would be removed")
+	cleanChaincodeCmd.Flags().BoolVar(&cleanForce, "force", false, "Also remove running chaincode containers and their images")
+}
+
+// networkPeers returns the names of the peers of a network.
+func networkPeers(ctx context.Context, dockerClient *docker.Client, network string) ([]string, error) {
+	containers, err := dockerClient.List(ctx, map[string]string{
+		docker.LabelNetwork: network,
+		docker.LabelRole:    string(docker.RolePeer),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var peers []string
+	for _, cont := range containers {
+		peers = append(peers, docker.ContainerName(cont))
+	}
+	return peers, nil
+}
+
+// chaincodePeers returns the peers of hlf networks by the docker network of
+// their network, those recorded by hlf network up and those of existing
+// containers. network selects a single network when set, with stale set only
+// the peers whose container no longer exists are returned.
+func chaincodePeers(ctx context.Context, dockerClient *docker.Client, network string, stale bool) (map[string][]string, error) {
+	records, err := loadNetworkRecords()
+	if err != nil {
+		return nil, err
+	}
+
+	containers, err := dockerClient.List(ctx, map[string]string{
+		docker.LabelNetwork: network,
+		docker.LabelRole:    string(docker.RolePeer),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	existing := map[string]map[string]bool{}
+	for _, cont := range containers {
+		name := cont.Labels[docker.LabelNetwork]
+		if existing[name] == nil {
+			existing[name] = map[string]bool{}
+		}
+		existing[name][docker.ContainerName(cont)] = true
+	}
+
+	candidates := map[string][]string{}
+	for name, record := range records {
+		if network == "" || name == network {
+			candidates[name] = record.Peers
+		}
+	}
+	for name, peers := range existing {
+		for peer := range peers {
+			if !contains(candidates[name], peer) {
+				candidates[name] = append(candidates[name], peer)
+			}
+		}
+	}
+
+	peers := map[string][]string{}
+	for name, names := range candidates {
+		for _, peer := range names {
+			if stale && existing[name][peer] {
+				continue
+			}
+			peers[compose.NetworkName(name)] = append(peers[compose.NetworkName(name)], peer)
+		}
+	}
+	return peers, nil
+}
+
+// cleanChaincodes lists the chaincode artifacts selected by the filter with
+// their size and removes them unless dryRun is set. Running chaincode
+// containers and their images are only removed with force.
+func cleanChaincodes(ctx context.Context, dockerClient *docker.Client, filter docker.ChaincodeFilter, dryRun, force bool, out io.Writer) error {
+	artifacts, err := dockerClient.FindChaincodes(ctx, filter)
+	if err != nil {
+		return err
+	}
+
+	// running containers are kept with their images unless forced
+	if !force {
+		var containers, images []docker.ChaincodeArtifact
+		kept := map[string]bool{}
+		for _, cont := range artifacts.Containers {
+			if cont.Running {
+				fmt.Fprintf(out, "container %s is running, use --force to remove it\n", cont.Name)
+				kept[cont.Image] = true
+				continue
+			}
+			containers = append(containers, cont)
+		}
+		for _, image := range artifacts.Images {
+			if !kept[image.ID] {
+				images = append(images, image)
+			}
+		}
+		artifacts.Containers, artifacts.Images = containers, images
+	}
+
+	if artifacts.Empty() {
+		fmt.Fprintln(out, "No chaincode containers or images to remove")
+		return nil
+	}
+
+	for _, cont := range artifacts.Containers {
+		fmt.Fprintf(out, "container %s (%s)\n", cont.Name, units.HumanSize(float64(cont.Size)))
+	}
+	for _, image := range artifacts.Images {
+		fmt.Fprintf(out, "image     %s (%s)\n", image.Name, units.HumanSize(float64(image.Size)))
+	}
+
+	size := units.HumanSize(float64(artifacts.Size()))
+	if dryRun {
+		fmt.Fprintf(out, "%s would be freed\n", size)
+		return nil
+	}
+
+	if err := dockerClient.RemoveChaincodes(ctx, artifacts, force); err != nil {
+		return err
+	}
+
+	color.New(color.FgGreen).Fprintf(out, "Removed chaincode containers and images, %s freed\n", size)
+	return nil
+}
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/gangachris/hlf/docker"
+	"github.com/gangachris/hlf/docker/dockertest"
+	"github.com/gangachris/hlf/spec"
+)
+
+func Test_chaincodePeers(t *testing.T) {
+	home, restoreHome := withHome(t)
+	defer restoreHome()
+
+	network := &spec.Network{Name: "dev", Dir: home, Organizations: []spec.Organization{{Name: "Org1", Domain: "org1.example.com", Peers: 2}}}
+	if err := recordNetwork(network, false); err != nil {
+		t.Fatal(err)
+	}
+
+	// peer1 of dev was removed, test was not brought up by hlf network up
+	fake := dockertest.New()
+	fake.AddImage(types.ImageSummary{RepoTags: []string{"hyperledger/fabric-peer:1.4.4"}})
+	dockerClient := docker.NewWithAPI(fake, docker.RuntimeDocker)
+	ctx := context.Background()
+	for _, node := range []docker.Node{
+		{Network: "dev", Org: "Org1", Role: docker.RolePeer, Name: "peer0.org1.example.com"},
+		{Network: "test", Org: "Org1", Role: docker.RolePeer, Name: "peer0.org1.example.com-test"},
+	} {
+		if _, err := dockerClient.CreateContainer(ctx, docker.ContainerSpec{Node: node, Image: "hyperledger/fabric-peer:1.4.4"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		network string
+		stale   bool
+		want    map[string][]string
+	}{
+		{
+			name:  "peers that no longer exist",
+			stale: true,
+			want:  map[string][]string{"hlf-dev": {"peer1.org1.example.com"}},
+		},
+		{
+			name:    "peers of a network",
+			network: "dev",
+			want:    map[string][]string{"hlf-dev": {"peer0.org1.example.com", "peer1.org1.example.com"}},
+		},
+		{
+			name: "peers of every network",
+			want: map[string][]string{
+				"hlf-dev":  {"peer0.org1.example.com", "peer1.org1.example.com"},
+				"hlf-test": {"peer0.org1.example.com-test"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := chaincodePeers(ctx, dockerClient, tt.network, tt.stale)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, peers := range got {
+				sort.Strings(peers)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("chaincodePeers() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,324 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/fatih/color"
+	"github.com/gangachris/hlf/config"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// configCmd represents the config command
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Manage hlf configuration",
+}
+
+// configGetCmd prints the value of a configuration key
+var configGetCmd = &cobra.Command{
+	Use:   "get <key>",
+	Short: "Print the value of a configuration key",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		value, err := getConfig(args[0])
+		if err != nil {
+			errorExit(err)
+		}
+		fmt.Println(value)
+	},
+}
+
+// configSetCmd sets a configuration key in the config file
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Short: "Set a configuration key in the config file e.g hlf config set runtime podman",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := setConfig(args[0], args[1]); err != nil {
+			errorExit(err)
+		}
+		color.Green("%s set to %s", args[0], args[1])
+	},
+}
+
+// configUnsetCmd removes a configuration key from the config file
+var configUnsetCmd = &cobra.Command{
+	Use:   "unset <key>",
+	Short: "Remove a configuration key from the config file, its default is used again",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := unsetConfig(args[0]); err != nil {
+			errorExit(err)
+		}
+		color.Green("%s unset", args[0])
+	},
+}
+
+// configListCmd lists every configuration key
+var configListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List every configuration key with its value and where the value comes from",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := listConfig(os.Stdout); err != nil {
+			errorExit(err)
+		}
+	},
+}
+
+// configPathCmd prints the path of the config file
+var configPathCmd = &cobra.Command{
+	Use:   "path",
+	Short: "Print the path of the config file",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		configFile, err := configFilePath()
+		if err != nil {
+			errorExit(err)
+		}
+		fmt.Println(configFile)
+	},
+}
+
+// configValidateCmd checks the config file and environment variables
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check the config file and HLF_* environment variables",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		problems, err := validateConfig()
+		if err != nil {
+			errorExit(err)
+		}
+
+		if len(problems) > 0 {
+			for _, problem := range problems {
+				color.Red(problem.Error())
+			}
+			os.Exit(-1)
+		}
+		color.Green("Configuration is valid")
+	},
+}
+
+func init() {
+	var keys []string
+	for _, key := range config.Keys {
+		keys = append(keys, fmt.Sprintf("\t%-16s%s", key.Name, key.Description))
+	}
+
+	configCmd.Long = `Manage the hlf configuration. Each key is read, from the highest precedence to the lowest, from:
+
+	1. its command line flag e.g --fabric-version
+	2. its HLF_* environment variable e.g HLF_FABRIC_VERSION
+	3. the config file, see hlf config path
+	4. its default
+
+The following keys are supported:
+
+` + strings.Join(keys, "\n")
+
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configSetCmd)
+	configCmd.AddCommand(configUnsetCmd)
+	configCmd.AddCommand(configListCmd)
+	configCmd.AddCommand(configPathCmd)
+	configCmd.AddCommand(configValidateCmd)
+	rootCmd.AddCommand(configCmd)
+}
+
+func lookupConfigKey(name string) (config.Key, error) {
+	key, ok := config.Lookup(name)
+	if !ok {
+		return config.Key{}, fmt.Errorf("unknown config key %s, see hlf config --help", name)
+	}
+	return key, nil
+}
+
+// formatConfigValue prints lists comma separated, the way they are set.
+func formatConfigValue(value interface{}) string {
+	if list, ok := value.([]string); ok {
+		return strings.Join(list, ",")
+	}
+	return fmt.Sprint(value)
+}
+
+func getConfig(name string) (string, error) {
+	key, err := lookupConfigKey(name)
+	if err != nil {
+		return "", err
+	}
+
+	value, err := key.Parse(viper.Get(key.Name))
+	if err != nil {
+		return "", err
+	}
+	return formatConfigValue(value), nil
+}
+
+func setConfig(name, value string) error {
+	key, err := lookupConfigKey(name)
+	if err != nil {
+		return err
+	}
+
+	if err := key.CheckFile(); err != nil {
+		return err
+	}
+
+	parsed, err := key.Parse(value)
+	if err != nil {
+		return err
+	}
+
+	return updateConfigFile(func(settings map[string]interface{}) {
+		settings[key.Name] = parsed
+	})
+}
+
+func unsetConfig(name string) error {
+	key, err := lookupConfigKey(name)
+	if err != nil {
+		return err
+	}
+
+	return updateConfigFile(func(settings map[string]interface{}) {
+		delete(settings, key.Name)
+	})
+}
+
+// configSetting is a configuration key as listed by hlf config list.
+type configSetting struct {
+	Key    string      `json:"key"`
+	Value  interface{} `json:"value"`
+	Source string      `json:"source"`
+	Env    string      `json:"env"`
+}
+
+func listConfig(out io.Writer) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	var settings []configSetting
+	for _, key := range config.Keys {
+		value, err := key.Parse(viper.Get(key.Name))
+		if err != nil {
+			return err
+		}
+
+		flag := rootCmd.PersistentFlags().Lookup(key.Name)
+		settings = append(settings, configSetting{
+			Key:    key.Name,
+			Value:  value,
+			Source: config.Source(viper.GetViper(), key, flag != nil && flag.Changed),
+			Env:    key.Env(),
+		})
+	}
+
+	if cfg.Output == config.OutputJSON {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(settings)
+	}
+
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "KEY\tVALUE\tSOURCE\tENV")
+	for _, setting := range settings {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", setting.Key, formatConfigValue(setting.Value), setting.Source, setting.Env)
+	}
+	return w.Flush()
+}
+
+// validateConfig returns the problems of the config file and of the
+// environment variables and flags overriding it.
+func validateConfig() ([]error, error) {
+	configFile, err := configFilePath()
+	if err != nil {
+		return nil, err
+	}
+
+	settings, err := readConfigFile(configFile)
+	if err != nil {
+		return []error{err}, nil
+	}
+
+	problems := config.ValidateFile(settings)
+	for _, key := range config.Keys {
+		if os.Getenv(key.Env()) == "" {
+			continue
+		}
+		if _, err := key.Parse(os.Getenv(key.Env())); err != nil {
+			problems = append(problems, fmt.Errorf("%s: %s", key.Env(), err.Error()))
+		}
+	}
+
+	if len(problems) == 0 {
+		if _, err := loadConfig(); err != nil {
+			problems = append(problems, err)
+		}
+	}
+
+	return problems, nil
+}
+
+// readConfigFile returns the settings of a config file, none when it does not exist.
+func readConfigFile(configFile string) (map[string]interface{}, error) {
+	settings := map[string]interface{}{}
+	data, err := ioutil.ReadFile(configFile)
+	if os.IsNotExist(err) {
+		return settings, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := yaml.Unmarshal(data, &settings); err != nil {
+		return nil, fmt.Errorf("error reading %s: %s", configFile, err.Error())
+	}
+	return settings, nil
+}
+
+// updateConfigFile changes the settings of the config file, creating it when needed.
+func updateConfigFile(update func(settings map[string]interface{})) error {
+	configFile, err := configFilePath()
+	if err != nil {
+		return err
+	}
+
+	settings, err := readConfigFile(configFile)
+	if err != nil {
+		return err
+	}
+	update(settings)
+
+	out, err := yaml.Marshal(settings)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(configFile), 0755); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(configFile, out, 0644)
+}
+
+// configFilePath returns the config file in use, or the default one.
+func configFilePath() (string, error) {
+	if configFile := viper.ConfigFileUsed(); configFile != "" {
+		return configFile, nil
+	}
+
+	return defaultConfigFile()
+}
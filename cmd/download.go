@@ -21,22 +21,30 @@
 package cmd
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
+	"os"
+	"path"
+	"path/filepath"
 	"runtime"
+	"strings"
 
 	"github.com/fatih/color"
+	"github.com/gangachris/hlf/config"
 	"github.com/gangachris/hlf/docker"
+	"github.com/gangachris/hlf/samples"
+	"github.com/gangachris/hlf/semver"
 	"github.com/spf13/cobra"
 )
 
 const (
-	// FabricVersion is the current stable version of hyperledger fabric
-	FabricVersion = "1.1.0"
-
-	// ThirdPartyVersionTag represents the version of third party images released (couchdb, kafka and zookeeper)
-	ThirdPartyVersionTag = "0.4.6"
+	// FabricVersion is the current stable version of hyperledger fabric, the
+	// fabric-version config key selects another one
+	FabricVersion = config.DefaultFabricVersion
 
 	// PlatformBinariesURL is the root url for the platform binaries
 	PlatformBinariesURL = "https://nexus.hyperledger.org/content/repositories/releases/org/hyperledger/fabric/hyperledger-fabric"
@@ -45,14 +53,80 @@ const (
 	HYPERLEDGER = "hyperledger"
 )
 
-var platformBinariesURL string
+var (
+	// fabricDockerImages are the fabric images tagged with the fabric version
+	fabricDockerImages = []string{"peer", "orderer", "ccenv", "javaenv", "tools"}
+
+	// caDockerImages are the images tagged with the fabric-ca version
+	caDockerImages = []string{"ca"}
+
+	// thirdPartyDockerImages are the images tagged with the third party version
+	thirdPartyDockerImages = []string{"couchdb", "kafka", "zookeeper"}
+)
+
+// thirdPartyVersion returns the version of the third party images (couchdb,
+// kafka and zookeeper) released with a fabric version.
+func thirdPartyVersion(fabricVersion string) string {
+	switch {
+	case versionAtLeast(fabricVersion, "1.4"):
+		return "0.4.18"
+	case versionAtLeast(fabricVersion, "1.3"):
+		return "0.4.14"
+	case versionAtLeast(fabricVersion, "1.2"):
+		return "0.4.10"
+	}
+	return "0.4.6"
+}
+
+// imageTags are the tags download pulls and networks run the images with.
+type imageTags struct {
+	// Fabric is the tag of the peer, orderer, ccenv, javaenv and tools images.
+	Fabric string
+	// CA is the tag of the fabric-ca image, fabric-ca is versioned on its own.
+	CA string
+	// ThirdParty is the tag of the couchdb, kafka and zookeeper images.
+	ThirdParty string
+}
+
+// fabricImageTags returns the image tags of a fabric and a fabric-ca version.
+// Images were tagged with the machine hardware name e.g x86_64-1.1.0 up to
+// fabric 1.1 and third party 0.4.6, with the version alone e.g 2.2.0 since.
+func fabricImageTags(machineHardwareName, fabricVersion, caVersion string) imageTags {
+	tag := func(version, untagged string) string {
+		if versionAtLeast(version, untagged) {
+			return version
+		}
+		return machineHardwareName + "-" + version
+	}
+
+	return imageTags{
+		Fabric:     tag(fabricVersion, "1.2"),
+		CA:         tag(caVersion, "1.2"),
+		ThirdParty: tag(thirdPartyVersion(fabricVersion), "0.4.10"),
+	}
+}
+
+// configuredImageTags returns the image tags of a fabric version with the
+// configured fabric-ca version.
+func configuredImageTags(cfg *config.Config, fabricVersion string) (imageTags, error) {
+	machineHardwareName, err := getMachineHardwareName()
+	if err != nil {
+		return imageTags{}, err
+	}
+	return fabricImageTags(machineHardwareName, fabricVersion, cfg.CAVersion(fabricVersion)), nil
+}
+
+// versionAtLeast reports whether a version is the minimum version or newer.
+func versionAtLeast(version, minimum string) bool {
+	ok, err := semver.CorrectVersion(minimum, version)
+	return err == nil && ok
+}
 
-// TODO: @ganga we should be able to download samples too i.e hlf download samples (fabric-samples)
 // downloadCmd will download the platform binaries and the docker images
 // once the download is done, the images are tagged
 var downloadCmd = &cobra.Command{
 	Use:   "download [images,binaries,samples]",
-	Short: "Download Hyperledger Fabric Tools (Docker Images, Platform Binaries)",
+	Short: "Download Hyperledger Fabric Tools (Docker Images, Platform Binaries, Samples)",
 	Long: `This will download all the required Docker Images to set up a Hyperledger Fabric Environment.
 The following Images are downloaded:
 
@@ -73,7 +147,10 @@ The following binaries tools are also downloaded:
 	2. configtxlator
 	3. cryptogen
 	4. peer
-	5. orderer`,
+	5. orderer
+
+The sample networks that run on the fabric version are written to the hlf home,
+see hlf network up --sample.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		// We need to check the arguments whether there's images, binaries, or samples (instead of flags)
 		if len(args) > 4 {
@@ -96,10 +173,6 @@ The following binaries tools are also downloaded:
 }
 
 func init() {
-	// download setup and getting system architecture and machine hardware.
-	arch := runtime.GOOS + "-" + runtime.GOARCH
-	platformBinariesURL = fmt.Sprintf("%s/%s-%s/hyperledger-fabric-%s-%s.tar.gz", PlatformBinariesURL, arch, FabricVersion, arch, FabricVersion)
-
 	rootCmd.AddCommand(downloadCmd)
 
 	// Here you will define your flags and configuration settings.
@@ -150,34 +223,45 @@ func download(arg string) error {
 func downloadDockerImages() error {
 	// wrapper function for all docker related actions
 	color.Blue("Downloading docker images")
-	machineHardwareName, err := getMachineHardwareName()
+	endpoint, err := dockerEndpoint()
 	if err != nil {
-		errorExit(err)
+		return err
 	}
 
 	// check if docker is installed
-	if err := docker.Installed(); err != nil {
+	compose, err := docker.Installed(endpoint)
+	if err != nil {
 		return err
 	}
+	color.Blue("Using %s %s (%s)", compose, compose.Version, endpoint.Runtime)
 
-	dockerClient, err := docker.New()
+	dockerClient, err := docker.New(endpoint)
 	if err != nil {
 		return err
 	}
 
-	fabricDockerImages := []string{"peer", "orderer", "ccenv", "javaenv", "tools", "ca"}
-	fabricTag := machineHardwareName + "-" + FabricVersion
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
 
-	if err := dockerClient.DownloadDockerImages(fabricDockerImages, fabricTag); err != nil {
+	tags, err := configuredImageTags(cfg, cfg.FabricVersion)
+	if err != nil {
 		return err
 	}
 
-	thirdPartyDockerImages := []string{"couchdb", "kafka", "zookeeper"}
-	thirdPartyTag := machineHardwareName + "-" + ThirdPartyVersionTag
-	if err := dockerClient.DownloadDockerImages(thirdPartyDockerImages, thirdPartyTag); err != nil {
+	options := docker.PullOptions{Registry: cfg.Registry, Parallelism: cfg.Parallelism}
+	pulled, pullErr := pullDockerImages(dockerClient, tags, options)
+	if err := recordInstall(func(m *installManifest) {
+		m.Images = appendUnique(m.Images, pulled...)
+	}); err != nil {
 		return err
 	}
 
+	if pullErr != nil {
+		return pullErr
+	}
+
 	color.Green("Successfully downloaded docker images")
 
 	// TODO: Go should be installed. Maybe serve this as a warning
@@ -188,6 +272,45 @@ func downloadDockerImages() error {
 	return nil
 }
 
+// pullDockerImages pulls the fabric, fabric-ca and third party images with
+// their tags and tags them as latest. It returns the image references it
+// created, images present before the download are left out.
+func pullDockerImages(dockerClient *docker.Client, tags imageTags, options docker.PullOptions) ([]string, error) {
+	ctx := context.Background()
+	before, err := dockerClient.HyperledgerImages(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	pullErr := dockerClient.DownloadDockerImages(fabricDockerImages, tags.Fabric, options)
+	if pullErr == nil {
+		pullErr = dockerClient.DownloadDockerImages(caDockerImages, tags.CA, options)
+	}
+	if pullErr == nil {
+		pullErr = dockerClient.DownloadDockerImages(thirdPartyDockerImages, tags.ThirdParty, options)
+	}
+
+	after, err := dockerClient.HyperledgerImages(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	existing := map[string]string{}
+	for _, image := range before {
+		existing[image.Ref()] = image.ID
+	}
+
+	// latest tags moved to the pulled images were created by hlf too
+	var pulled []string
+	for _, image := range after {
+		if existing[image.Ref()] != image.ID {
+			pulled = append(pulled, image.Ref())
+		}
+	}
+
+	return pulled, pullErr
+}
+
 func downloadPlatformBinaries() error {
 	// download Platform Binaries
 	// TODO: @ganga maybe add to path???
@@ -195,17 +318,131 @@ func downloadPlatformBinaries() error {
 	// github.com/gosuri/uilive
 	// TODO: @ganga a way to check if platform binaries have been downloaded
 
-	color.Blue("Downloading platform binaries...")
-	res, err := http.Get(platformBinariesURL)
+	cfg, err := loadConfig()
 	if err != nil {
 		return err
 	}
+
+	archive, err := platformBinariesArchive(cfg)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	extracted, extractErr := extractTarGz(f)
+	if err := recordInstall(func(m *installManifest) {
+		m.Binaries = appendUnique(m.Binaries, archive)
+		m.Binaries = appendUnique(m.Binaries, extracted...)
+	}); err != nil {
+		return err
+	}
+
+	return extractErr
+}
+
+// platformBinariesArchive returns the platform binaries archive of the configured
+// fabric version from the cache, downloading it to the cache first when needed.
+func platformBinariesArchive(cfg *config.Config) (string, error) {
+	urls := platformBinariesURLs(cfg.FabricVersion, cfg.Mirrors)
+	archive := filepath.Join(cfg.CacheDir(), path.Base(urls[0]))
+	if _, err := os.Stat(archive); err == nil {
+		color.Blue("Using cached platform binaries %s", archive)
+		return archive, nil
+	}
+
+	if err := os.MkdirAll(cfg.CacheDir(), 0755); err != nil {
+		return "", err
+	}
+
+	color.Blue("Downloading platform binaries...")
+	res, err := getPlatformBinaries(urls)
+	if err != nil {
+		return "", err
+	}
 	defer res.Body.Close()
 
-	return extractTarGz(res.Body)
+	// downloads are written next to the archive and renamed once complete, an
+	// interrupted download is never mistaken for a cached archive
+	tmp, err := ioutil.TempFile(cfg.CacheDir(), ".download-")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, res.Body); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("error downloading platform binaries: %s", err.Error())
+	}
+
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+
+	return archive, os.Rename(tmp.Name(), archive)
 }
 
+// platformBinariesURLs returns the URLs of the platform binaries archive of
+// a fabric version on the mirrors, then on the hyperledger repository.
+func platformBinariesURLs(fabricVersion string, mirrors []string) []string {
+	arch := runtime.GOOS + "-" + runtime.GOARCH
+	archive := fmt.Sprintf("%s-%s/hyperledger-fabric-%s-%s.tar.gz", arch, fabricVersion, arch, fabricVersion)
+
+	var urls []string
+	for _, mirror := range append(mirrors, PlatformBinariesURL) {
+		urls = append(urls, strings.TrimSuffix(mirror, "/")+"/"+archive)
+	}
+	return urls
+}
+
+// getPlatformBinaries downloads the platform binaries archive from the first URL that has it.
+func getPlatformBinaries(urls []string) (*http.Response, error) {
+	var errs []string
+	for _, url := range urls {
+		res, err := http.Get(url)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+
+		if res.StatusCode == http.StatusOK {
+			return res, nil
+		}
+
+		res.Body.Close()
+		errs = append(errs, fmt.Sprintf("%s: %s", url, res.Status))
+	}
+
+	return nil, fmt.Errorf("error downloading platform binaries:\n\t%s", strings.Join(errs, "\n\t"))
+}
+
+// downloadSamples writes the spec and chaincode of the sample networks that
+// run on the configured fabric version.
 func downloadSamples() error {
-	color.Green("Downloading binaries")
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	color.Blue("Writing sample networks")
+	for _, name := range samples.Names() {
+		sample, err := samples.Get(name)
+		if err != nil {
+			return err
+		}
+		if sample.Supports(cfg.FabricVersion) != nil {
+			continue
+		}
+
+		file, err := writeSample(name)
+		if err != nil {
+			return err
+		}
+		color.Green("Wrote %s", file)
+	}
 	return nil
 }
@@ -0,0 +1,262 @@
+package cmd
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/gangachris/hlf/config"
+	"github.com/gangachris/hlf/docker"
+	"github.com/gangachris/hlf/docker/dockertest"
+)
+
+func Test_pullDockerImages(t *testing.T) {
+	// every image is pulled into an empty engine
+	allImages := []string{
+		"hyperledger/fabric-ca:latest",
+		"hyperledger/fabric-ca:x86_64-1.1.0",
+		"hyperledger/fabric-ccenv:latest",
+		"hyperledger/fabric-ccenv:x86_64-1.1.0",
+		"hyperledger/fabric-couchdb:latest",
+		"hyperledger/fabric-couchdb:x86_64-0.4.6",
+		"hyperledger/fabric-javaenv:latest",
+		"hyperledger/fabric-javaenv:x86_64-1.1.0",
+		"hyperledger/fabric-kafka:latest",
+		"hyperledger/fabric-kafka:x86_64-0.4.6",
+		"hyperledger/fabric-orderer:latest",
+		"hyperledger/fabric-orderer:x86_64-1.1.0",
+		"hyperledger/fabric-peer:latest",
+		"hyperledger/fabric-peer:x86_64-1.1.0",
+		"hyperledger/fabric-tools:latest",
+		"hyperledger/fabric-tools:x86_64-1.1.0",
+		"hyperledger/fabric-zookeeper:latest",
+		"hyperledger/fabric-zookeeper:x86_64-0.4.6",
+	}
+
+	// fabric 2.x and fabric-ca images are tagged with their version alone
+	fabric2Images := []string{
+		"hyperledger/fabric-ca:1.4.9",
+		"hyperledger/fabric-ca:latest",
+		"hyperledger/fabric-ccenv:2.2.0",
+		"hyperledger/fabric-ccenv:latest",
+		"hyperledger/fabric-couchdb:0.4.18",
+		"hyperledger/fabric-couchdb:latest",
+		"hyperledger/fabric-javaenv:2.2.0",
+		"hyperledger/fabric-javaenv:latest",
+		"hyperledger/fabric-kafka:0.4.18",
+		"hyperledger/fabric-kafka:latest",
+		"hyperledger/fabric-orderer:2.2.0",
+		"hyperledger/fabric-orderer:latest",
+		"hyperledger/fabric-peer:2.2.0",
+		"hyperledger/fabric-peer:latest",
+		"hyperledger/fabric-tools:2.2.0",
+		"hyperledger/fabric-tools:latest",
+		"hyperledger/fabric-zookeeper:0.4.18",
+		"hyperledger/fabric-zookeeper:latest",
+	}
+
+	tests := []struct {
+		name             string
+		pullStreamErrors map[string]string
+		existing         []string
+		tags             imageTags
+		options          docker.PullOptions
+		want             []string
+		wantPulled       []string
+		wantErr          bool
+	}{
+		{
+			name:       "images are pulled and tagged latest",
+			want:       allImages,
+			wantPulled: allImages,
+		},
+		{
+			name:       "fabric 2.x images are tagged with their version",
+			tags:       fabricImageTags("x86_64", "2.2.0", "1.4.9"),
+			want:       fabric2Images,
+			wantPulled: fabric2Images,
+		},
+		{
+			name: "failed pull stops the download",
+			pullStreamErrors: map[string]string{
+				"hyperledger/fabric-orderer:x86_64-1.1.0": "manifest for hyperledger/fabric-orderer:x86_64-1.1.0 not found",
+			},
+			want: []string{
+				"hyperledger/fabric-peer:latest",
+				"hyperledger/fabric-peer:x86_64-1.1.0",
+			},
+			wantPulled: []string{
+				"hyperledger/fabric-peer:latest",
+				"hyperledger/fabric-peer:x86_64-1.1.0",
+			},
+			wantErr: true,
+		},
+		{
+			name: "images present before the download are not reported as pulled",
+			pullStreamErrors: map[string]string{
+				"hyperledger/fabric-orderer:x86_64-1.1.0": "manifest for hyperledger/fabric-orderer:x86_64-1.1.0 not found",
+			},
+			existing: []string{"hyperledger/fabric-peer:x86_64-1.1.0", "hyperledger/fabric-peer:latest"},
+			want: []string{
+				"hyperledger/fabric-peer:latest",
+				"hyperledger/fabric-peer:x86_64-1.1.0",
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := dockertest.New()
+			for ref, message := range tt.pullStreamErrors {
+				fake.PullStreamErrors[ref] = message
+			}
+
+			if len(tt.existing) > 0 {
+				fake.AddImage(types.ImageSummary{RepoTags: tt.existing})
+			}
+
+			tags := tt.tags
+			if tags == (imageTags{}) {
+				tags = fabricImageTags("x86_64", FabricVersion, FabricVersion)
+			}
+
+			pulled, err := pullDockerImages(docker.NewWithAPI(fake, docker.RuntimeDocker), tags, tt.options)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("pullDockerImages() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(pulled, tt.wantPulled) {
+				t.Errorf("pullDockerImages() pulled = %v, want %v", pulled, tt.wantPulled)
+			}
+			if got := fake.ImageTags(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("pullDockerImages() images = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_fabricImageTags(t *testing.T) {
+	tests := []struct {
+		fabricVersion string
+		caVersion     string
+		want          imageTags
+	}{
+		{fabricVersion: "1.1.0", caVersion: "1.1.0", want: imageTags{Fabric: "x86_64-1.1.0", CA: "x86_64-1.1.0", ThirdParty: "x86_64-0.4.6"}},
+		{fabricVersion: "1.2.0", caVersion: "1.2.0", want: imageTags{Fabric: "1.2.0", CA: "1.2.0", ThirdParty: "0.4.10"}},
+		{fabricVersion: "1.4.4", caVersion: "1.4.4", want: imageTags{Fabric: "1.4.4", CA: "1.4.4", ThirdParty: "0.4.18"}},
+		{fabricVersion: "2.3.0", caVersion: "1.5.7", want: imageTags{Fabric: "2.3.0", CA: "1.5.7", ThirdParty: "0.4.18"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fabricVersion, func(t *testing.T) {
+			if got := fabricImageTags("x86_64", tt.fabricVersion, tt.caVersion); got != tt.want {
+				t.Errorf("fabricImageTags() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_platformBinariesArchive(t *testing.T) {
+	home, restore := withHome(t)
+	defer restore()
+
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.Path)
+		w.Write([]byte("archive"))
+	}))
+	defer server.Close()
+
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Mirrors = []string{server.URL}
+
+	want := filepath.Join(home, "cache", filepath.Base(platformBinariesURLs(config.DefaultFabricVersion, nil)[0]))
+
+	// the archive is downloaded once, then read from the cache
+	for i := 0; i < 2; i++ {
+		archive, err := platformBinariesArchive(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if archive != want {
+			t.Errorf("platformBinariesArchive() = %s, want %s", archive, want)
+		}
+	}
+
+	if len(requests) != 1 {
+		t.Errorf("requests = %v, want a single download", requests)
+	}
+
+	data, err := ioutil.ReadFile(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "archive" {
+		t.Errorf("archive = %q, want %q", data, "archive")
+	}
+
+	files, err := ioutil.ReadDir(filepath.Join(home, "cache"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Errorf("cache = %v, want only the archive", files)
+	}
+}
+
+func Test_extractTarGz(t *testing.T) {
+	_, restore := withHome(t)
+	defer restore()
+
+	var archive bytes.Buffer
+	gz := gzip.NewWriter(&archive)
+	tw := tar.NewWriter(gz)
+	for _, header := range []*tar.Header{
+		{Name: "bin/", Typeflag: tar.TypeDir, Mode: 0755},
+		{Name: "bin/peer", Typeflag: tar.TypeReg, Mode: 0755, Size: 4},
+	} {
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if header.Typeflag == tar.TypeReg {
+			tw.Write([]byte("peer"))
+		}
+	}
+	tw.Close()
+	gz.Close()
+
+	dir, err := getPlatformBinariesDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	peer := dir + "/bin/peer"
+
+	// the directories of a previous download do not stop the extraction, they
+	// are not reported as created
+	for _, want := range [][]string{{dir + "/bin/", peer}, {peer}} {
+		extracted, err := extractTarGz(bytes.NewReader(archive.Bytes()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(extracted, want) {
+			t.Errorf("extractTarGz() = %v, want %v", extracted, want)
+		}
+	}
+
+	info, err := os.Stat(peer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm()&0100 == 0 {
+		t.Errorf("peer mode = %v, want it executable", info.Mode())
+	}
+}
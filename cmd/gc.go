@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var (
+	gcDryRun  bool
+	gcVolumes bool
+	gcForce   bool
+)
+
+// gcCmd removes resources left behind by crashed hlf runs
+var gcCmd = &cobra.Command{
+	Use:   "gc",
+	Short: "Remove containers, networks and volumes left behind by crashed hlf runs",
+	Long: `Remove the resources of hlf networks that are gone. A network is gone when hlf network up
+did not record it, when the directory of its spec was deleted or when none of its containers
+exist e.g after hlf network down kept its volumes. The resources of a network hlf network up is
+creating are kept:
+
+	1. its containers that are not running
+	2. its docker networks without running containers
+	3. its volumes not mounted by running containers (only with --volumes, they hold ledger data)
+
+Volumes are only removed once confirmed, --force removes them without asking. The stopped
+containers of a network whose spec exists are kept, hlf network up starts them again.
+Resources not created by hlf are never touched.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := gc(gcDryRun, gcVolumes, gcForce, os.Stdin, os.Stdout); err != nil {
+			errorExit(err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(gcCmd)
+
+	gcCmd.Flags().BoolVar(&gcDryRun, "dry-run", false, "Only list the resources that would be removed")
+	gcCmd.Flags().BoolVar(&gcVolumes, "volumes", false, "Also remove orphaned volumes")
+	gcCmd.Flags().BoolVar(&gcForce, "force", false, "Remove orphaned volumes without asking")
+}
+
+func gc(dryRun, volumes, force bool, in io.Reader, out io.Writer) error {
+	dockerClient, err := newDockerClient()
+	if err != nil {
+		return err
+	}
+
+	known, starting, err := knownNetworks()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	orphans, err := dockerClient.FindOrphans(ctx, known, starting)
+	if err != nil {
+		return err
+	}
+
+	if orphans.Empty() {
+		color.New(color.FgGreen).Fprintln(out, "Nothing to clean up")
+		return nil
+	}
+
+	for _, name := range orphans.Containers {
+		fmt.Fprintln(out, "container", name)
+	}
+	for _, name := range orphans.Networks {
+		fmt.Fprintln(out, "network  ", name)
+	}
+	for _, name := range orphans.Volumes {
+		if volumes {
+			fmt.Fprintln(out, "volume   ", name)
+		} else {
+			fmt.Fprintln(out, "volume   ", name, "(kept, use --volumes to remove)")
+		}
+	}
+
+	if dryRun {
+		return nil
+	}
+
+	if volumes && len(orphans.Volumes) > 0 && !force {
+		ok, err := confirm(bufio.NewReader(in), out, fmt.Sprintf("Remove %d volumes? Their ledger data is lost", len(orphans.Volumes)))
+		if err != nil {
+			return err
+		}
+		volumes = ok
+	}
+
+	if err := dockerClient.RemoveOrphans(ctx, orphans, volumes); err != nil {
+		return err
+	}
+
+	color.New(color.FgGreen).Fprintln(out, "Removed orphaned hlf resources")
+	return nil
+}
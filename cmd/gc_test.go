@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"context"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/gangachris/hlf/docker"
+	"github.com/gangachris/hlf/docker/dockertest"
+	"github.com/gangachris/hlf/spec"
+)
+
+func Test_gc(t *testing.T) {
+	home, restoreHome := withHome(t)
+	defer restoreHome()
+
+	fake := dockertest.New()
+	fake.AddImage(types.ImageSummary{RepoTags: []string{"hyperledger/fabric-peer:1.4.4"}})
+	dockerClient, restoreClient := withDockerClient(fake)
+	defer restoreClient()
+	ctx := context.Background()
+
+	// dev is stopped and its spec exists, crashed was never recorded
+	if err := recordNetwork(&spec.Network{Name: "dev", Dir: home}, false); err != nil {
+		t.Fatal(err)
+	}
+	for _, network := range []string{"dev", "crashed"} {
+		node := docker.Node{Network: network, Org: "Org1", Role: docker.RolePeer, Name: "peer0." + network}
+		if _, err := dockerClient.CreateVolume(ctx, node, node.Name); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := dockerClient.CreateContainer(ctx, docker.ContainerSpec{Node: node, Image: "hyperledger/fabric-peer:1.4.4"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// starting is being brought up, it has no containers yet
+	if err := recordNetwork(&spec.Network{Name: "starting", Dir: home}, true); err != nil {
+		t.Fatal(err)
+	}
+	starting := docker.Node{Network: "starting", Org: "Org1", Role: docker.RolePeer, Name: "peer0.starting"}
+	if _, err := dockerClient.CreateNetwork(ctx, "starting", "starting"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dockerClient.CreateVolume(ctx, starting, starting.Name); err != nil {
+		t.Fatal(err)
+	}
+
+	// volumes are kept unless the removal is confirmed
+	if err := gc(false, true, false, strings.NewReader("n\n"), ioutil.Discard); err != nil {
+		t.Fatalf("gc() error = %v", err)
+	}
+	if _, err := dockerClient.Inspect(ctx, "peer0.crashed"); !docker.IsNotFound(err) {
+		t.Errorf("gc() kept the container of crashed: %v", err)
+	}
+	if _, err := dockerClient.Inspect(ctx, "peer0.dev"); err != nil {
+		t.Errorf("gc() removed the stopped container of dev: %v", err)
+	}
+	if _, err := dockerClient.InspectVolume(ctx, "peer0.crashed"); err != nil {
+		t.Errorf("gc() removed a volume without confirmation: %v", err)
+	}
+
+	if err := gc(false, true, true, strings.NewReader(""), ioutil.Discard); err != nil {
+		t.Fatalf("gc() error = %v", err)
+	}
+	if _, err := dockerClient.InspectVolume(ctx, "peer0.crashed"); !docker.IsNotFound(err) {
+		t.Errorf("gc(force) kept the volume of crashed: %v", err)
+	}
+	if _, err := dockerClient.InspectVolume(ctx, "peer0.dev"); err != nil {
+		t.Errorf("gc(force) removed the volume of dev: %v", err)
+	}
+	if _, err := dockerClient.InspectVolume(ctx, "peer0.starting"); err != nil {
+		t.Errorf("gc(force) removed the volume of a starting network: %v", err)
+	}
+
+	// once started, a network without containers is gone
+	if err := recordNetwork(&spec.Network{Name: "starting", Dir: home}, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := gc(false, true, true, strings.NewReader(""), ioutil.Discard); err != nil {
+		t.Fatalf("gc() error = %v", err)
+	}
+	if _, err := dockerClient.InspectVolume(ctx, "peer0.starting"); !docker.IsNotFound(err) {
+		t.Errorf("gc(force) kept the volume of starting: %v", err)
+	}
+}
@@ -3,6 +3,7 @@ package cmd
 
 import (
 	"archive/tar"
+	"bufio"
 	"compress/gzip"
 	"fmt"
 	"io"
@@ -11,7 +12,7 @@ import (
 	"strings"
 
 	"github.com/fatih/color"
-	homedir "github.com/mitchellh/go-homedir"
+	"github.com/gangachris/hlf/docker"
 )
 
 func errorExit(err error) {
@@ -30,14 +31,18 @@ func getMachineHardwareName() (string, error) {
 }
 
 // ref: https://gist.github.com/indraniel/1a91458984179ab4cf80#gistcomment-2122149
-func extractTarGz(gzipStream io.Reader) error {
+// extractTarGz extracts the platform binaries archive and returns the files and
+// directories it created.
+func extractTarGz(gzipStream io.Reader) ([]string, error) {
 	uncompressedStream, err := gzip.NewReader(gzipStream)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	tarReader := tar.NewReader(uncompressedStream)
 
+	var extracted []string
+
 	for true {
 		header, err := tarReader.Next()
 
@@ -46,54 +51,57 @@ func extractTarGz(gzipStream io.Reader) error {
 		}
 
 		if err != nil {
-			return err
+			return extracted, err
 		}
 
 		platformsBinariesDir, err := getPlatformBinariesDir()
 		if err != nil {
-			return err
+			return extracted, err
 		}
 
 		switch header.Typeflag {
 		case tar.TypeDir:
-			err := os.Mkdir(platformsBinariesDir+"/"+header.Name, 0755)
-
+			// directories of a previous download are extracted into
+			path := platformsBinariesDir + "/" + header.Name
+			err := os.Mkdir(path, 0755)
 			if os.IsExist(err) {
-				return nil
+				continue
 			}
 
 			if err != nil {
-				return err
-			}
-		case tar.TypeReg:
-			outFile, err := os.Create(platformsBinariesDir + "/" + header.Name)
-			if os.IsExist(err) {
-				return nil
+				return extracted, err
 			}
 
+			extracted = append(extracted, path)
+		case tar.TypeReg:
+			path := platformsBinariesDir + "/" + header.Name
+			outFile, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, header.FileInfo().Mode().Perm())
 			if err != nil {
-				return err
+				return extracted, err
 			}
 
-			defer outFile.Close()
-			if _, err := io.Copy(outFile, tarReader); err != nil {
-				return err
+			extracted = append(extracted, path)
+
+			_, err = io.Copy(outFile, tarReader)
+			outFile.Close()
+			if err != nil {
+				return extracted, err
 			}
 		default:
-			return fmt.Errorf("error extracting tar file: unknown type %s in %s", string(header.Typeflag), header.Name)
+			return extracted, fmt.Errorf("error extracting tar file: unknown type %s in %s", string(header.Typeflag), header.Name)
 		}
 	}
 
-	return nil
+	return extracted, nil
 }
 
 func getPlatformBinariesDir() (string, error) {
-	home, err := homedir.Dir()
+	cfg, err := loadConfig()
 	if err != nil {
 		return "", err
 	}
 
-	platformBinariesDir := home + "/.hlf-cli"
+	platformBinariesDir := cfg.BinariesDir()
 
 	err = os.MkdirAll(platformBinariesDir, 0755)
 
@@ -107,3 +115,80 @@ func getPlatformBinariesDir() (string, error) {
 
 	return platformBinariesDir, nil
 }
+
+// dockerEndpoint returns the docker API endpoint to use, honouring the runtime
+// set with hlf config set runtime.
+func dockerEndpoint() (*docker.Endpoint, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	runtime, err := docker.ParseRuntime(cfg.Runtime)
+	if err != nil {
+		return nil, err
+	}
+
+	return docker.DetectEndpoint(runtime)
+}
+
+// newDockerClient creates the docker client used by commands, tests replace it
+// with a client backed by dockertest.Fake.
+var newDockerClient = func() (*docker.Client, error) {
+	endpoint, err := dockerEndpoint()
+	if err != nil {
+		return nil, err
+	}
+
+	return docker.New(endpoint)
+}
+
+// configuredNetwork returns the network given with --network, or the configured one.
+func configuredNetwork(network string) (string, error) {
+	if network != "" {
+		return network, nil
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return "", err
+	}
+	return cfg.Network, nil
+}
+
+// confirm asks a yes or no question, anything but yes is a no.
+func confirm(answers *bufio.Reader, out io.Writer, question string) (bool, error) {
+	fmt.Fprintf(out, "%s [y/N] ", question)
+
+	answer, err := answers.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return false, err
+	}
+
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes", nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// ask asks a question, the answer is the default when none is given.
+func ask(answers *bufio.Reader, out io.Writer, question, defaultAnswer string) (string, error) {
+	fmt.Fprintf(out, "%s [%s] ", question, defaultAnswer)
+
+	answer, err := answers.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+
+	if answer = strings.TrimSpace(answer); answer == "" {
+		return defaultAnswer, nil
+	}
+	return answer, nil
+}
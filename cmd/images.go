@@ -0,0 +1,255 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/docker/go-units"
+	"github.com/fatih/color"
+	"github.com/gangachris/hlf/config"
+	"github.com/gangachris/hlf/docker"
+	"github.com/gangachris/hlf/semver"
+	"github.com/spf13/cobra"
+)
+
+var (
+	imagesKeepVersion   string
+	imagesKeepCAVersion string
+	imagesDryRun        bool
+)
+
+// imagesCmd lists the hyperledger images present locally
+var imagesCmd = &cobra.Command{
+	Use:   "images",
+	Short: "List the Hyperledger images present locally",
+	Long: `List every tag of the Hyperledger images present locally with its digest, size and
+creation date. Tags hlf expects for the active fabric version are marked with a *,
+NETWORKS lists the hlf networks with containers using the image.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		dockerClient, err := newDockerClient()
+		if err != nil {
+			errorExit(err)
+		}
+
+		cfg, err := loadConfig()
+		if err != nil {
+			errorExit(err)
+		}
+
+		tags, err := configuredImageTags(cfg, cfg.FabricVersion)
+		if err != nil {
+			errorExit(err)
+		}
+
+		if err := listImages(context.Background(), dockerClient, tags, cfg, os.Stdout); err != nil {
+			errorExit(err)
+		}
+	},
+}
+
+// imagesPruneCmd removes older fabric and third party image tags
+var imagesPruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove unused Hyperledger images older than the version to keep",
+	Long: `Remove the tags of Hyperledger images that no container uses and that are older than:
+
+	--keep-version      for fabric images (peer, orderer, ccenv, javaenv, tools...)
+	--keep-ca-version   for fabric-ca, which is versioned on its own
+
+Third party images (couchdb, kafka, zookeeper, baseos...) are kept from the version
+released with --keep-version. --keep-version defaults to the configured fabric version and
+--keep-ca-version to the configured fabric-ca version, the ones hlf download pulls. latest
+tags are kept, an image is deleted once its last tag is removed.
+
+	hlf images prune --keep-version 1.1
+	hlf images prune --keep-version 2.2 --keep-ca-version 1.4`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		dockerClient, err := newDockerClient()
+		if err != nil {
+			errorExit(err)
+		}
+
+		cfg, err := loadConfig()
+		if err != nil {
+			errorExit(err)
+		}
+
+		keepVersion, keepCAVersion := imagesKeepVersion, imagesKeepCAVersion
+		if keepVersion == "" {
+			keepVersion = cfg.FabricVersion
+		}
+		if keepCAVersion == "" {
+			keepCAVersion = cfg.CAVersion(keepVersion)
+		}
+
+		if err := pruneImages(context.Background(), dockerClient, keepVersion, keepCAVersion, imagesDryRun, os.Stdout); err != nil {
+			errorExit(err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(imagesCmd)
+	imagesCmd.AddCommand(imagesPruneCmd)
+
+	imagesPruneCmd.Flags().StringVar(&imagesKeepVersion, "keep-version", "", "Oldest fabric version to keep, the configured fabric version by default")
+	imagesPruneCmd.Flags().StringVar(&imagesKeepCAVersion, "keep-ca-version", "", "Oldest fabric-ca version to keep, the configured fabric-ca version by default")
+	imagesPruneCmd.Flags().BoolVar(&imagesDryRun, "dry-run", false, "Only list the image tags that would be removed")
+}
+
+// expectedImageTag returns the tag download pulls for a hyperledger repository.
+func expectedImageTag(repository string, tags imageTags) (string, bool) {
+	name := strings.TrimPrefix(repository, docker.HyperledgerRepository+"/fabric-")
+	switch {
+	case contains(fabricDockerImages, name):
+		return tags.Fabric, true
+	case contains(caDockerImages, name):
+		return tags.CA, true
+	case contains(thirdPartyDockerImages, name):
+		return tags.ThirdParty, true
+	}
+	return "", false
+}
+
+func listImages(ctx context.Context, dockerClient *docker.Client, tags imageTags, cfg *config.Config, out io.Writer) error {
+	images, err := dockerClient.HyperledgerImages(ctx)
+	if err != nil {
+		return err
+	}
+
+	expected := func(image docker.Image) bool {
+		tag, ok := expectedImageTag(image.Repository, tags)
+		return ok && tag == image.Tag
+	}
+
+	if cfg.Output == config.OutputJSON {
+		type jsonImage struct {
+			docker.Image
+			Expected bool `json:"expected"`
+		}
+
+		list := []jsonImage{}
+		for _, image := range images {
+			list = append(list, jsonImage{Image: image, Expected: expected(image)})
+		}
+
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(list)
+	}
+
+	if len(images) == 0 {
+		_, err := fmt.Fprintln(out, "No Hyperledger images found, run hlf download images")
+		return err
+	}
+
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "REPOSITORY\tTAG\tEXPECTED\tDIGEST\tSIZE\tCREATED\tNETWORKS")
+	for _, image := range images {
+		mark := ""
+		if expected(image) {
+			mark = "*"
+		}
+
+		digest := image.Digest
+		if len(digest) > 19 {
+			digest = digest[:19]
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s ago\t%s\n",
+			image.Repository,
+			image.Tag,
+			mark,
+			digest,
+			units.HumanSize(float64(image.Size)),
+			units.HumanDuration(time.Since(image.Created)),
+			strings.Join(image.Networks, ","),
+		)
+	}
+	return w.Flush()
+}
+
+// tagVersion matches the version in image tags such as x86_64-1.1.0, amd64-0.4.6 or 2.5.
+var tagVersion = regexp.MustCompile(`\d+\.\d+(\.\d+)?`)
+
+// staleImage reports whether an image tag is older than the version kept for
+// its repository, keepCAVersion for fabric-ca, the third party version of
+// keepVersion for third party images and keepVersion for the other fabric images.
+func staleImage(image docker.Image, keepVersion, keepCAVersion string) (bool, error) {
+	if image.InUse || image.Tag == "latest" {
+		return false, nil
+	}
+
+	version := tagVersion.FindString(image.Tag)
+	if version == "" {
+		return false, nil
+	}
+
+	name := strings.TrimPrefix(image.Repository, docker.HyperledgerRepository+"/fabric-")
+	switch {
+	case name == "ca":
+		keepVersion = keepCAVersion
+	case contains(thirdPartyDockerImages, name), strings.HasPrefix(name, "base"):
+		keepVersion = thirdPartyVersion(keepVersion)
+	}
+
+	recent, err := semver.CorrectVersion(keepVersion, version)
+	if err != nil {
+		return false, err
+	}
+	return !recent, nil
+}
+
+func pruneImages(ctx context.Context, dockerClient *docker.Client, keepVersion, keepCAVersion string, dryRun bool, out io.Writer) error {
+	for _, version := range []string{keepVersion, keepCAVersion} {
+		if tagVersion.FindString(version) != version {
+			return fmt.Errorf("error: invalid version %s, expected e.g 1.1 or 1.1.0", version)
+		}
+	}
+
+	images, err := dockerClient.HyperledgerImages(ctx)
+	if err != nil {
+		return err
+	}
+
+	var stale []docker.Image
+	for _, image := range images {
+		ok, err := staleImage(image, keepVersion, keepCAVersion)
+		if err != nil {
+			return err
+		}
+		if ok {
+			stale = append(stale, image)
+		}
+	}
+
+	if len(stale) == 0 {
+		fmt.Fprintln(out, "No image tags to remove")
+		return nil
+	}
+
+	for _, image := range stale {
+		fmt.Fprintf(out, "%s (%s)\n", image.Ref(), units.HumanSize(float64(image.Size)))
+		if dryRun {
+			continue
+		}
+
+		if err := dockerClient.RemoveImageTag(ctx, image); err != nil {
+			return err
+		}
+	}
+
+	if !dryRun {
+		color.New(color.FgGreen).Fprintf(out, "Removed %d image tags\n", len(stale))
+	}
+	return nil
+}
@@ -0,0 +1,194 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/gangachris/hlf/docker"
+	"github.com/gangachris/hlf/docker/dockertest"
+)
+
+func Test_pruneImages(t *testing.T) {
+	tests := []struct {
+		name          string
+		keepVersion   string
+		keepCAVersion string
+		dryRun        bool
+		want          []string
+		wantErr       bool
+	}{
+		{
+			name:          "older unused tags are removed",
+			keepVersion:   "1.1",
+			keepCAVersion: "1.1.0",
+			want: []string{
+				"hyperledger/fabric-ca:1.4.9",
+				"hyperledger/fabric-ca:x86_64-1.1.0",
+				"hyperledger/fabric-couchdb:0.4.18",
+				"hyperledger/fabric-couchdb:x86_64-0.4.6",
+				"hyperledger/fabric-orderer:x86_64-1.0.0",
+				"hyperledger/fabric-peer:2.2.0",
+				"hyperledger/fabric-peer:latest",
+				"hyperledger/fabric-peer:x86_64-1.1.0",
+				"hyperledger/fabric-peer:x86_64-1.2.0",
+			},
+		},
+		{
+			name:          "fabric-ca keeps its own version, third party images the one of the fabric version",
+			keepVersion:   "1.2.0",
+			keepCAVersion: "1.1.0",
+			want: []string{
+				"hyperledger/fabric-ca:1.4.9",
+				"hyperledger/fabric-ca:x86_64-1.1.0",
+				"hyperledger/fabric-couchdb:0.4.18",
+				"hyperledger/fabric-orderer:x86_64-1.0.0",
+				"hyperledger/fabric-peer:2.2.0",
+				"hyperledger/fabric-peer:latest",
+				"hyperledger/fabric-peer:x86_64-1.2.0",
+			},
+		},
+		{
+			name:          "fabric-ca tags older than the ca version to keep are removed",
+			keepVersion:   "1.0",
+			keepCAVersion: "1.2",
+			want: []string{
+				"hyperledger/fabric-ca:1.4.9",
+				"hyperledger/fabric-couchdb:0.4.18",
+				"hyperledger/fabric-couchdb:x86_64-0.4.6",
+				"hyperledger/fabric-orderer:x86_64-1.0.0",
+				"hyperledger/fabric-peer:2.2.0",
+				"hyperledger/fabric-peer:latest",
+				"hyperledger/fabric-peer:x86_64-1.0.0",
+				"hyperledger/fabric-peer:x86_64-1.1.0",
+				"hyperledger/fabric-peer:x86_64-1.2.0",
+			},
+		},
+		{
+			name:          "fabric-ca tags as old as the ca version to keep are kept",
+			keepVersion:   "2.5",
+			keepCAVersion: "1.0",
+			want: []string{
+				"hyperledger/fabric-ca:1.4.9",
+				"hyperledger/fabric-ca:x86_64-1.0.0",
+				"hyperledger/fabric-ca:x86_64-1.1.0",
+				"hyperledger/fabric-couchdb:0.4.18",
+				"hyperledger/fabric-orderer:x86_64-1.0.0",
+				"hyperledger/fabric-peer:latest",
+			},
+		},
+		{
+			name:          "fabric 2.x tags",
+			keepVersion:   "2.2",
+			keepCAVersion: "1.4.9",
+			want: []string{
+				"hyperledger/fabric-ca:1.4.9",
+				"hyperledger/fabric-couchdb:0.4.18",
+				"hyperledger/fabric-orderer:x86_64-1.0.0",
+				"hyperledger/fabric-peer:2.2.0",
+				"hyperledger/fabric-peer:latest",
+			},
+		},
+		{
+			name:          "dry run removes nothing",
+			keepVersion:   "1.1",
+			keepCAVersion: "1.1.0",
+			dryRun:        true,
+			want: []string{
+				"hyperledger/fabric-ca:1.4.9",
+				"hyperledger/fabric-ca:x86_64-1.0.0",
+				"hyperledger/fabric-ca:x86_64-1.1.0",
+				"hyperledger/fabric-couchdb:0.4.18",
+				"hyperledger/fabric-couchdb:x86_64-0.4.5",
+				"hyperledger/fabric-couchdb:x86_64-0.4.6",
+				"hyperledger/fabric-orderer:x86_64-1.0.0",
+				"hyperledger/fabric-peer:2.2.0",
+				"hyperledger/fabric-peer:latest",
+				"hyperledger/fabric-peer:x86_64-1.0.0",
+				"hyperledger/fabric-peer:x86_64-1.1.0",
+				"hyperledger/fabric-peer:x86_64-1.2.0",
+			},
+		},
+		{
+			name:          "invalid version",
+			keepVersion:   "latest",
+			keepCAVersion: "1.1.0",
+			wantErr:       true,
+		},
+		{
+			name:          "invalid ca version",
+			keepVersion:   "1.1",
+			keepCAVersion: "latest",
+			wantErr:       true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := dockertest.New()
+			for _, tags := range [][]string{
+				{"hyperledger/fabric-peer:x86_64-1.0.0"},
+				{"hyperledger/fabric-peer:x86_64-1.1.0", "hyperledger/fabric-peer:latest"},
+				{"hyperledger/fabric-peer:x86_64-1.2.0"},
+				{"hyperledger/fabric-orderer:x86_64-1.0.0"},
+				{"hyperledger/fabric-ca:x86_64-1.0.0"},
+				{"hyperledger/fabric-ca:x86_64-1.1.0"},
+				{"hyperledger/fabric-couchdb:x86_64-0.4.5"},
+				{"hyperledger/fabric-couchdb:x86_64-0.4.6"},
+				{"hyperledger/fabric-peer:2.2.0"},
+				{"hyperledger/fabric-ca:1.4.9"},
+				{"hyperledger/fabric-couchdb:0.4.18"},
+			} {
+				fake.AddImage(types.ImageSummary{RepoTags: tags})
+			}
+
+			dockerClient := docker.NewWithAPI(fake, docker.RuntimeDocker)
+			ctx := context.Background()
+
+			// images used by containers are never removed
+			spec := docker.ContainerSpec{
+				Node:  docker.Node{Network: "dev", Role: docker.RoleOrderer, Name: "orderer"},
+				Image: "hyperledger/fabric-orderer:x86_64-1.0.0",
+			}
+			if _, err := dockerClient.CreateContainer(ctx, spec); err != nil {
+				t.Fatal(err)
+			}
+
+			var out bytes.Buffer
+			err := pruneImages(ctx, dockerClient, tt.keepVersion, tt.keepCAVersion, tt.dryRun, &out)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("pruneImages() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if got := fake.ImageTags(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("pruneImages() images = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_expectedImageTag(t *testing.T) {
+	tags := fabricImageTags("x86_64", "2.2.0", "1.4.9")
+	tests := []struct {
+		repository string
+		want       string
+		wantOK     bool
+	}{
+		{repository: "hyperledger/fabric-peer", want: "2.2.0", wantOK: true},
+		{repository: "hyperledger/fabric-ca", want: "1.4.9", wantOK: true},
+		{repository: "hyperledger/fabric-couchdb", want: "0.4.18", wantOK: true},
+		{repository: "hyperledger/fabric-baseos"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.repository, func(t *testing.T) {
+			got, ok := expectedImageTag(tt.repository, tags)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("expectedImageTag() = %s, %v, want %s, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,241 @@
+package cmd
+
+import (
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/gangachris/hlf/docker"
+	"github.com/spf13/cobra"
+)
+
+var (
+	logsNetwork string
+	logsFollow  bool
+	logsSince   string
+	logsTail    string
+	logsGrep    string
+	logsLevel   string
+	logsModule  string
+)
+
+// logsCmd streams the logs of the nodes of a network
+var logsCmd = &cobra.Command{
+	Use:   "logs [node...]",
+	Short: "Show the logs of the nodes of a network",
+	Long: `Show the logs of every node of a network, or of the given nodes, in one stream.
+Each line is prefixed with the node it comes from. Fabric log lines are parsed so they
+can be filtered by level and module, lines logged by other nodes (CouchDB, Kafka...)
+are only shown when neither --level nor --module are set.
+
+	hlf logs -f
+	hlf logs peer0.org1.example.com --since 10m --level WARN
+	hlf logs --module gossip --grep "Membership view"`,
+	Run: func(cmd *cobra.Command, args []string) {
+		filter, err := newLogFilter(logsLevel, logsModule, logsGrep)
+		if err != nil {
+			errorExit(err)
+		}
+
+		network, err := configuredNetwork(logsNetwork)
+		if err != nil {
+			errorExit(err)
+		}
+
+		options := docker.LogOptions{Follow: logsFollow, Since: logsSince, Tail: logsTail}
+		if err := logs(network, args, options, filter); err != nil {
+			errorExit(err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(logsCmd)
+
+	logsCmd.Flags().StringVarP(&logsNetwork, "network", "n", "", "Name of the hlf network, the configured network by default")
+	logsCmd.Flags().BoolVarP(&logsFollow, "follow", "f", false, "Follow log output")
+	logsCmd.Flags().StringVar(&logsSince, "since", "", "Show logs since a timestamp (2018-04-01T10:00:00) or a duration (10m)")
+	logsCmd.Flags().StringVar(&logsTail, "tail", "all", "Number of lines to show from the end of the logs of each node")
+	logsCmd.Flags().StringVar(&logsGrep, "grep", "", "Only show lines matching the regular expression")
+	logsCmd.Flags().StringVar(&logsLevel, "level", "", "Only show fabric lines of this level or higher: DEBUG, INFO, WARN, ERROR, PANIC, FATAL")
+	logsCmd.Flags().StringVar(&logsModule, "module", "", "Only show fabric lines of modules starting with this prefix e.g gossip")
+}
+
+func logs(network string, nodes []string, options docker.LogOptions, filter *logFilter) error {
+	dockerClient, err := newDockerClient()
+	if err != nil {
+		return err
+	}
+
+	// stop following on ctrl+c
+	ctx, cancel := interruptContext()
+	defer cancel()
+
+	containers, err := dockerClient.List(ctx, map[string]string{docker.LabelNetwork: network})
+	if err != nil {
+		return err
+	}
+
+	var names []string
+	width := 0
+	for _, cont := range containers {
+		name := docker.ContainerName(cont)
+		if len(nodes) > 0 && !contains(nodes, name) {
+			continue
+		}
+		names = append(names, name)
+		if len(name) > width {
+			width = len(name)
+		}
+	}
+
+	for _, node := range nodes {
+		if !contains(names, node) {
+			return fmt.Errorf("error: node %s not found", node)
+		}
+	}
+
+	if len(names) == 0 {
+		return fmt.Errorf("error: no nodes found, is the network up?")
+	}
+
+	lines := make(chan docker.LogLine)
+	errs := make(chan error, 1)
+	go func() {
+		errs <- dockerClient.StreamLogs(ctx, names, options, lines)
+		close(lines)
+	}()
+
+	for line := range lines {
+		if !filter.match(line) {
+			continue
+		}
+		prefix := nodeColor(line.Container).Sprintf("%-*s |", width, line.Container)
+		fmt.Println(prefix, line.Text)
+	}
+
+	return <-errs
+}
+
+// nodeColors are the colors node prefixes are printed in, red is left out for errors.
+var nodeColors = []color.Attribute{
+	color.FgCyan, color.FgGreen, color.FgYellow, color.FgBlue, color.FgMagenta,
+	color.FgHiCyan, color.FgHiGreen, color.FgHiYellow, color.FgHiBlue, color.FgHiMagenta,
+}
+
+// nodeColor returns the color of a node, the same node always gets the same color.
+func nodeColor(node string) *color.Color {
+	hash := fnv.New32a()
+	hash.Write([]byte(node))
+	return color.New(nodeColors[hash.Sum32()%uint32(len(nodeColors))])
+}
+
+var (
+	// ansiEscape matches the color codes fabric adds to its log lines.
+	ansiEscape = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+	// fabricLogLine matches the default log format of fabric 1.x:
+	// 2018-04-03 10:00:00.000 UTC [gossip/service] func1 -> INFO 001 message
+	fabricLogLine = regexp.MustCompile(`^\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}\.\d+ \S+ \[([^\]]+)\] \S+ -> ([A-Z]{4}) [0-9a-f]+ `)
+
+	// fabric2LogLine matches the default log format of fabric 2.x, the level
+	// comes before the module:
+	// 2021-03-22 08:11:54.276 UTC 0001 INFO [gossip.service] func1 -> message
+	fabric2LogLine = regexp.MustCompile(`^\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}\.\d+ \S+ [0-9a-f]+ ([A-Z]{4}) \[([^\]]+)\] \S+ -> `)
+)
+
+// fabricLogLevels orders the four letter levels of fabric 1.x (go-logging) and 1.4+ (zap).
+var fabricLogLevels = map[string]int{
+	"DEBU": 10,
+	"INFO": 20,
+	"NOTI": 25,
+	"WARN": 30,
+	"ERRO": 40,
+	"CRIT": 50,
+	"DPAN": 50,
+	"PANI": 50,
+	"FATA": 60,
+}
+
+// fabricLogEntry is the level and module of a fabric log line.
+type fabricLogEntry struct {
+	level  string
+	module string
+}
+
+// parseFabricLogLine returns the level and module of a fabric log line.
+func parseFabricLogLine(text string) (fabricLogEntry, bool) {
+	text = ansiEscape.ReplaceAllString(text, "")
+
+	if match := fabricLogLine.FindStringSubmatch(text); match != nil {
+		return fabricLogEntry{module: match[1], level: match[2]}, true
+	}
+	if match := fabric2LogLine.FindStringSubmatch(text); match != nil {
+		return fabricLogEntry{level: match[1], module: match[2]}, true
+	}
+	return fabricLogEntry{}, false
+}
+
+// logFilter filters log lines by fabric level and module and by a pattern.
+type logFilter struct {
+	level  int
+	module string
+	grep   *regexp.Regexp
+
+	// last is the last fabric entry of each container, lines that are not
+	// fabric entries (stack traces, multi line messages) belong to it.
+	last map[string]fabricLogEntry
+}
+
+func newLogFilter(level, module, grep string) (*logFilter, error) {
+	filter := &logFilter{module: module, last: map[string]fabricLogEntry{}}
+
+	if level != "" {
+		name := strings.ToUpper(level)
+		if len(name) > 4 {
+			name = name[:4]
+		}
+
+		var ok bool
+		if filter.level, ok = fabricLogLevels[name]; !ok {
+			return nil, fmt.Errorf("unknown log level %s, expected one of DEBUG, INFO, WARN, ERROR, PANIC, FATAL", level)
+		}
+	}
+
+	if grep != "" {
+		pattern, err := regexp.Compile(grep)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --grep pattern: %s", err.Error())
+		}
+		filter.grep = pattern
+	}
+
+	return filter, nil
+}
+
+func (f *logFilter) match(line docker.LogLine) bool {
+	entry, ok := parseFabricLogLine(line.Text)
+	if ok {
+		f.last[line.Container] = entry
+	} else {
+		entry, ok = f.last[line.Container]
+	}
+
+	if f.level > 0 || f.module != "" {
+		if !ok {
+			return false
+		}
+
+		if fabricLogLevels[entry.level] < f.level {
+			return false
+		}
+
+		if !strings.HasPrefix(entry.module, f.module) {
+			return false
+		}
+	}
+
+	return f.grep == nil || f.grep.MatchString(ansiEscape.ReplaceAllString(line.Text, ""))
+}
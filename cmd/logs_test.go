@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gangachris/hlf/docker"
+)
+
+func Test_parseFabricLogLine(t *testing.T) {
+	tests := []struct {
+		name   string
+		text   string
+		want   fabricLogEntry
+		wantOk bool
+	}{
+		{
+			name:   "fabric 1.1 line",
+			text:   "2018-04-03 10:00:00.000 UTC [gossip/service] func1 -> INFO 001 Anchor peers for channel mychannel",
+			want:   fabricLogEntry{level: "INFO", module: "gossip/service"},
+			wantOk: true,
+		},
+		{
+			name:   "colored fabric 1.4 line",
+			text:   "\x1b[33m2019-06-12 08:11:54.276 UTC [gossip.discovery] expireDeadMembers -> WARN 05a\x1b[0m Entering [peer1]",
+			want:   fabricLogEntry{level: "WARN", module: "gossip.discovery"},
+			wantOk: true,
+		},
+		{
+			name:   "colored fabric 2.x line",
+			text:   "\x1b[33m2021-03-22 08:11:54.276 UTC 005a WARN\x1b[0m [gossip.discovery] expireDeadMembers -> Entering [peer1]",
+			want:   fabricLogEntry{level: "WARN", module: "gossip.discovery"},
+			wantOk: true,
+		},
+		{
+			name:   "fabric 2.x line",
+			text:   "2021-03-22 08:11:50.102 UTC 0001 INFO [nodeCmd] serve -> Starting peer:",
+			want:   fabricLogEntry{level: "INFO", module: "nodeCmd"},
+			wantOk: true,
+		},
+		{
+			name: "couchdb line",
+			text: "[notice] 2018-04-03T10:00:00.000000Z nonode@nohost <0.8.0> -------- Apache CouchDB has started",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseFabricLogLine(tt.text)
+			if ok != tt.wantOk {
+				t.Errorf("parseFabricLogLine() ok = %v, want %v", ok, tt.wantOk)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseFabricLogLine() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_logFilter_match(t *testing.T) {
+	lines := []docker.LogLine{
+		{Container: "peer0", Text: "2018-04-03 10:00:00.000 UTC [gossip/service] func1 -> INFO 001 joined"},
+		{Container: "peer0", Text: "2018-04-03 10:00:01.000 UTC [ledgermgmt] func2 -> ERRO 002 cannot open ledger"},
+		{Container: "peer0", Text: "goroutine 1 [running]:"},
+		{Container: "couchdb0", Text: "[notice] Apache CouchDB has started"},
+		{Container: "peer0", Text: "2018-04-03 10:00:02.000 UTC [gossip/discovery] func3 -> WARN 003 peer1 is dead"},
+	}
+
+	tests := []struct {
+		name   string
+		level  string
+		module string
+		grep   string
+		want   []string
+	}{
+		{
+			name: "no filter",
+			want: []string{"peer0", "peer0", "peer0", "couchdb0", "peer0"},
+		},
+		{
+			name:  "warnings and errors, with the stack trace of the error",
+			level: "warning",
+			want:  []string{"peer0", "peer0", "peer0"},
+		},
+		{
+			name:   "gossip module",
+			module: "gossip",
+			want:   []string{"peer0", "peer0"},
+		},
+		{
+			name: "grep",
+			grep: "CouchDB|dead",
+			want: []string{"couchdb0", "peer0"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := newLogFilter(tt.level, tt.module, tt.grep)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, line := range lines {
+				if filter.match(line) {
+					got = append(got, line.Container)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("logFilter.match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// installManifestFile is where hlf records what it installs, in the platform binaries dir.
+const installManifestFile = "manifest.json"
+
+// installManifest records what hlf installed so that hlf uninstall removes
+// exactly that and nothing the user installed by other means.
+type installManifest struct {
+	// Binaries are the files and directories extracted from the platform binaries archive.
+	Binaries []string `json:"binaries,omitempty"`
+
+	// Images are the image references hlf pulled or tagged.
+	Images []string `json:"images,omitempty"`
+
+	// Samples are the files and directories of downloaded samples.
+	Samples []string `json:"samples,omitempty"`
+}
+
+func installManifestPath() (string, error) {
+	dir, err := getPlatformBinariesDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, installManifestFile), nil
+}
+
+// loadInstallManifest reads the install manifest, it is empty when nothing was installed.
+func loadInstallManifest() (*installManifest, error) {
+	manifest := &installManifest{}
+
+	path, err := installManifestPath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return manifest, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// save writes the manifest, the file is removed once the manifest is empty.
+func (m *installManifest) save() error {
+	path, err := installManifestPath()
+	if err != nil {
+		return err
+	}
+
+	if len(m.Binaries) == 0 && len(m.Images) == 0 && len(m.Samples) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+// recordInstall updates the install manifest with what was just installed.
+func recordInstall(record func(m *installManifest)) error {
+	manifest, err := loadInstallManifest()
+	if err != nil {
+		return err
+	}
+
+	record(manifest)
+	return manifest.save()
+}
+
+// appendUnique appends the items missing from a sorted list and keeps it sorted.
+func appendUnique(list []string, items ...string) []string {
+	for _, item := range items {
+		if !contains(list, item) {
+			list = append(list, item)
+		}
+	}
+	sort.Strings(list)
+	return list
+}
@@ -0,0 +1,18 @@
+package cmd
+
+import (
+	"github.com/gangachris/hlf/spec"
+	"github.com/spf13/cobra"
+)
+
+// networkCmd represents the network command
+var networkCmd = &cobra.Command{
+	Use:   "network",
+	Short: "Create and run networks described by a network spec, see hlf spec --help",
+}
+
+func init() {
+	rootCmd.AddCommand(networkCmd)
+
+	networkCmd.PersistentFlags().StringVarP(&specFile, "file", "f", spec.FileName, "Network spec file")
+}
@@ -0,0 +1,347 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/gangachris/hlf/compose"
+	"github.com/gangachris/hlf/docker"
+	"github.com/gangachris/hlf/semver"
+	"github.com/gangachris/hlf/spec"
+)
+
+// deployChaincodes deploys the chaincodes of a network on their channels with
+// the CLI container, with the legacy lifecycle before fabric 2.0 and with the
+// chaincode lifecycle from 2.0. The steps that are done already are skipped
+// and a chaincode whose version changed in the spec is upgraded.
+func deployChaincodes(ctx context.Context, dockerClient *docker.Client, network *spec.Network, out io.Writer) error {
+	if len(network.Chaincodes) == 0 {
+		return nil
+	}
+
+	lifecycle, err := semver.CorrectVersion("2.0", network.Fabric)
+	if err != nil {
+		return fmt.Errorf("error reading fabric version %s: %s", network.Fabric, err.Error())
+	}
+
+	cli := compose.CLIName(network)
+	for _, chaincode := range network.Chaincodes {
+		deploy := deployLegacyChaincode
+		if lifecycle {
+			deploy = deployLifecycleChaincode
+		}
+		if err := deploy(ctx, dockerClient, cli, network, chaincode, out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deployLegacyChaincode installs a chaincode on the peers of its channels and
+// instantiates it on every channel, or upgrades it when another version is
+// instantiated.
+func deployLegacyChaincode(ctx context.Context, dockerClient *docker.Client, cli string, network *spec.Network, chaincode spec.Chaincode, out io.Writer) error {
+	id := chaincode.Name + ":" + chaincode.Version
+
+	for _, org := range chaincodeOrganizations(network, chaincode) {
+		for i := 0; i < org.Peers; i++ {
+			env := compose.PeerEnv(network, org, i)
+			host := org.PeerHost(i)
+
+			output, err := peerCommand(ctx, dockerClient, cli, env, "chaincode", "list", "--installed")
+			if err != nil {
+				return err
+			}
+			if contains(chaincodeNames(output), id) {
+				fmt.Fprintf(out, "%s is installed on %s\n", id, host)
+				continue
+			}
+
+			color.New(color.FgBlue).Fprintf(out, "Installing %s on %s\n", id, host)
+			install := []string{"chaincode", "install", "-n", chaincode.Name, "-v", chaincode.Version, "-l", chaincode.Language, "-p", chaincodePath(network, chaincode, false)}
+			if _, err := peerCommand(ctx, dockerClient, cli, env, install...); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, channel := range chaincode.Channels {
+		orgs := channelOrganizations(network, channel)
+		if len(orgs) == 0 {
+			continue
+		}
+		env := compose.PeerEnv(network, orgs[0], 0)
+
+		output, err := peerCommand(ctx, dockerClient, cli, env, "chaincode", "list", "--instantiated", "-C", channel)
+		if err != nil {
+			return err
+		}
+		instantiated := chaincodeNames(output)
+		if contains(instantiated, id) {
+			fmt.Fprintf(out, "%s is instantiated on %s\n", id, channel)
+			continue
+		}
+
+		action := "instantiate"
+		for _, name := range instantiated {
+			if strings.HasPrefix(name, chaincode.Name+":") {
+				action = "upgrade"
+			}
+		}
+
+		color.New(color.FgBlue).Fprintf(out, "Running %s of %s on %s\n", action, id, channel)
+		args := []string{"chaincode", action, "-C", channel, "-n", chaincode.Name, "-v", chaincode.Version, "-l", chaincode.Language, "-c", chaincodeInitArgs(chaincode)}
+		if chaincode.EndorsementPolicy != "" {
+			args = append(args, "-P", chaincode.EndorsementPolicy)
+		}
+		args = append(args, compose.OrdererArgs(network, orgs[0])...)
+		if _, err := peerCommand(ctx, dockerClient, cli, env, args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deployLifecycleChaincode packages and installs a chaincode on the peers of
+// its channels, then on every channel approves its definition for each member
+// organization, commits it and calls its init function when it has one.
+func deployLifecycleChaincode(ctx context.Context, dockerClient *docker.Client, cli string, network *spec.Network, chaincode spec.Chaincode, out io.Writer) error {
+	label := chaincode.Name + "_" + chaincode.Version
+	archive := label + ".tar.gz"
+	packaged := false
+
+	// the package installed on the peers of each organization
+	packageIDs := map[string]string{}
+	for _, org := range chaincodeOrganizations(network, chaincode) {
+		for i := 0; i < org.Peers; i++ {
+			env := compose.PeerEnv(network, org, i)
+			host := org.PeerHost(i)
+
+			output, err := peerCommand(ctx, dockerClient, cli, env, "lifecycle", "chaincode", "queryinstalled")
+			if err != nil {
+				return err
+			}
+			if id := installedPackageID(output, label); id != "" {
+				fmt.Fprintf(out, "%s is installed on %s\n", label, host)
+				packageIDs[org.Name] = id
+				continue
+			}
+
+			if !packaged {
+				pkg := []string{"lifecycle", "chaincode", "package", archive, "--path", chaincodePath(network, chaincode, true), "--lang", chaincode.Language, "--label", label}
+				if _, err := peerCommand(ctx, dockerClient, cli, env, pkg...); err != nil {
+					return err
+				}
+				packaged = true
+			}
+
+			color.New(color.FgBlue).Fprintf(out, "Installing %s on %s\n", label, host)
+			if _, err := peerCommand(ctx, dockerClient, cli, env, "lifecycle", "chaincode", "install", archive); err != nil {
+				return err
+			}
+
+			output, err = peerCommand(ctx, dockerClient, cli, env, "lifecycle", "chaincode", "queryinstalled")
+			if err != nil {
+				return err
+			}
+			if packageIDs[org.Name] = installedPackageID(output, label); packageIDs[org.Name] == "" {
+				return fmt.Errorf("error: %s is not listed by %s after it was installed", label, host)
+			}
+		}
+	}
+
+	for _, channel := range chaincode.Channels {
+		orgs := channelOrganizations(network, channel)
+		if len(orgs) == 0 {
+			continue
+		}
+		env := compose.PeerEnv(network, orgs[0], 0)
+
+		output, err := peerCommand(ctx, dockerClient, cli, env, "lifecycle", "chaincode", "querycommitted", "-C", channel, "--output", "json")
+		if err != nil {
+			return err
+		}
+		committed, err := committedDefinitions(output)
+		if err != nil {
+			return err
+		}
+
+		sequence := 1
+		if definition, ok := committed[chaincode.Name]; ok {
+			if definition.Version == chaincode.Version {
+				fmt.Fprintf(out, "%s is committed on %s\n", label, channel)
+				continue
+			}
+			sequence = definition.Sequence + 1
+		}
+
+		definition := []string{"-C", channel, "-n", chaincode.Name, "-v", chaincode.Version, "--sequence", strconv.Itoa(sequence)}
+		if len(chaincode.Init) > 0 {
+			definition = append(definition, "--init-required")
+		}
+		if chaincode.EndorsementPolicy != "" {
+			definition = append(definition, "--signature-policy", chaincode.EndorsementPolicy)
+		}
+
+		output, err = peerCommand(ctx, dockerClient, cli, env, append([]string{"lifecycle", "chaincode", "checkcommitreadiness", "--output", "json"}, definition...)...)
+		if err != nil {
+			return err
+		}
+		approvals, err := commitApprovals(output)
+		if err != nil {
+			return err
+		}
+
+		for _, org := range orgs {
+			if approvals[org.MSPID] {
+				fmt.Fprintf(out, "%s approved %s on %s\n", org.Name, label, channel)
+				continue
+			}
+
+			color.New(color.FgBlue).Fprintf(out, "Approving %s for %s on %s\n", label, org.Name, channel)
+			approve := append([]string{"lifecycle", "chaincode", "approveformyorg", "--package-id", packageIDs[org.Name]}, definition...)
+			approve = append(approve, compose.OrdererArgs(network, org)...)
+			if _, err := peerCommand(ctx, dockerClient, cli, compose.PeerEnv(network, org, 0), approve...); err != nil {
+				return err
+			}
+		}
+
+		color.New(color.FgBlue).Fprintf(out, "Committing %s on %s\n", label, channel)
+		commit := append([]string{"lifecycle", "chaincode", "commit"}, definition...)
+		commit = append(commit, compose.OrdererArgs(network, orgs[0])...)
+		commit = append(commit, compose.PeerArgs(network, orgs)...)
+		if _, err := peerCommand(ctx, dockerClient, cli, env, commit...); err != nil {
+			return err
+		}
+
+		if len(chaincode.Init) == 0 {
+			continue
+		}
+
+		color.New(color.FgBlue).Fprintf(out, "Initializing %s on %s\n", label, channel)
+		invoke := []string{"chaincode", "invoke", "-C", channel, "-n", chaincode.Name, "--isInit", "-c", chaincodeInitArgs(chaincode), "--waitForEvent"}
+		invoke = append(invoke, compose.OrdererArgs(network, orgs[0])...)
+		invoke = append(invoke, compose.PeerArgs(network, orgs)...)
+		if _, err := peerCommand(ctx, dockerClient, cli, env, invoke...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// chaincodeOrganizations returns the member organizations of the channels of
+// a chaincode, the organizations whose peers it is installed on.
+func chaincodeOrganizations(network *spec.Network, chaincode spec.Chaincode) []*spec.Organization {
+	var orgs []*spec.Organization
+	seen := map[string]bool{}
+	for _, channel := range chaincode.Channels {
+		for _, org := range channelOrganizations(network, channel) {
+			if !seen[org.Name] {
+				seen[org.Name] = true
+				orgs = append(orgs, org)
+			}
+		}
+	}
+	return orgs
+}
+
+// chaincodePath returns the path of a chaincode the peer CLI packages: the
+// GOPATH import path of golang chaincodes, or their directory when they are
+// go modules from fabric 2.0, and the directory of other chaincodes.
+func chaincodePath(network *spec.Network, chaincode spec.Chaincode, lifecycle bool) string {
+	if chaincode.Language != spec.Golang {
+		return compose.ChaincodeDir(chaincode.Name)
+	}
+
+	if lifecycle {
+		path := chaincode.Path
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(network.Dir, path)
+		}
+		if _, err := os.Stat(filepath.Join(path, "go.mod")); err == nil {
+			return compose.ChaincodeDir(chaincode.Name)
+		}
+	}
+	return compose.ChaincodeImportPath(chaincode.Name)
+}
+
+// chaincodeInitArgs returns the init arguments of a chaincode as the -c flag of
+// the peer CLI expects them e.g {"Args":["init","a","100"]}.
+func chaincodeInitArgs(chaincode spec.Chaincode) string {
+	args := struct {
+		Args []string `json:"Args"`
+	}{[]string{}}
+	args.Args = append(args.Args, chaincode.Init...)
+
+	data, _ := json.Marshal(args)
+	return string(data)
+}
+
+// installedPackage matches the packages listed by peer lifecycle chaincode
+// queryinstalled e.g Package ID: mycc_1.0:6f6e..., Label: mycc_1.0
+var installedPackage = regexp.MustCompile(`Package ID: (\S+), Label: (\S+)`)
+
+// installedPackageID returns the ID of the installed package of a label, empty
+// when it is not installed.
+func installedPackageID(output, label string) string {
+	for _, match := range installedPackage.FindAllStringSubmatch(output, -1) {
+		if match[2] == label {
+			return match[1]
+		}
+	}
+	return ""
+}
+
+// committedDefinition is a chaincode definition committed on a channel.
+type committedDefinition struct {
+	Name     string `json:"name"`
+	Version  string `json:"version"`
+	Sequence int    `json:"sequence"`
+}
+
+// committedDefinitions parses the output of peer lifecycle chaincode
+// querycommitted --output json, by chaincode name.
+func committedDefinitions(output string) (map[string]committedDefinition, error) {
+	var committed struct {
+		Definitions []committedDefinition `json:"chaincode_definitions"`
+	}
+	if err := json.Unmarshal([]byte(jsonOutput(output)), &committed); err != nil {
+		return nil, fmt.Errorf("error reading the committed chaincodes: %s", err.Error())
+	}
+
+	definitions := map[string]committedDefinition{}
+	for _, definition := range committed.Definitions {
+		definitions[definition.Name] = definition
+	}
+	return definitions, nil
+}
+
+// commitApprovals parses the output of peer lifecycle chaincode
+// checkcommitreadiness --output json, the approvals by MSP ID.
+func commitApprovals(output string) (map[string]bool, error) {
+	var readiness struct {
+		Approvals map[string]bool `json:"approvals"`
+	}
+	if err := json.Unmarshal([]byte(jsonOutput(output)), &readiness); err != nil {
+		return nil, fmt.Errorf("error reading the approvals of the chaincode: %s", err.Error())
+	}
+	return readiness.Approvals, nil
+}
+
+// jsonOutput returns the JSON document of the output of a peer command, the
+// peer logs warnings around it on stderr.
+func jsonOutput(output string) string {
+	start, end := strings.Index(output, "{"), strings.LastIndex(output, "}")
+	if start == -1 || end < start {
+		return output
+	}
+	return output[start : end+1]
+}
@@ -0,0 +1,195 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/gangachris/hlf/compose"
+	"github.com/gangachris/hlf/config"
+	"github.com/gangachris/hlf/docker"
+	"github.com/gangachris/hlf/docker/dockertest"
+	"github.com/gangachris/hlf/samples"
+	"github.com/gangachris/hlf/spec"
+)
+
+// sampleNetwork starts the containers of a sample network on a fake docker.
+func sampleNetwork(t *testing.T, name, fabric string) (*spec.Network, *docker.Client, *dockertest.Fake) {
+	sample, err := samples.Get(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := sample.Spec(fabric)
+	if err != nil {
+		t.Fatal(err)
+	}
+	network, err := spec.Parse(spec.FileName, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	network.Dir = "/tmp/" + name
+
+	tags := fabricImageTags("x86_64", fabric, config.DefaultCAVersion(fabric))
+	project, err := compose.FromSpec(network, compose.Options{FabricTag: tags.Fabric, CATag: tags.CA, ThirdPartyTag: tags.ThirdParty})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fake := dockertest.New()
+	for _, c := range project.Containers {
+		fake.AddImage(types.ImageSummary{RepoTags: []string{c.Image}})
+	}
+	dockerClient := docker.NewWithAPI(fake, docker.RuntimeDocker)
+	if err := startNetwork(context.Background(), dockerClient, project, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+	return network, dockerClient, fake
+}
+
+// execEnv returns the environment of the last command executed on the fake.
+func execEnv(fake *dockertest.Fake) []string {
+	execs := fake.CallsTo("ContainerExecCreate")
+	return execs[len(execs)-1].Args[1].(types.ExecConfig).Env
+}
+
+func Test_deployChaincodes_legacy(t *testing.T) {
+	network, dockerClient, fake := sampleNetwork(t, "first-network", "1.4.4")
+	ctx := context.Background()
+
+	var commands [][]string
+	installed := map[string]bool{}
+	instantiated := false
+	fake.Exec = func(container string, cmd []string) (string, string, int) {
+		commands = append(commands, cmd)
+		switch strings.Join(cmd[1:3], " ") {
+		case "chaincode list":
+			if cmd[3] == "--installed" && installed[envValue(execEnv(fake), "CORE_PEER_ADDRESS")] ||
+				cmd[3] == "--instantiated" && instantiated {
+				return "Name: mycc, Version: 1.0, Path: chaincode/chaincode_example02, Id: 476fca\n", "", 0
+			}
+		case "chaincode install":
+			installed[envValue(execEnv(fake), "CORE_PEER_ADDRESS")] = true
+		case "chaincode instantiate":
+			instantiated = true
+		}
+		return "", "", 0
+	}
+
+	var out bytes.Buffer
+	if err := deployChaincodes(ctx, dockerClient, network, &out); err != nil {
+		t.Fatalf("deployChaincodes() error = %v", err)
+	}
+	if len(installed) != 4 {
+		t.Errorf("mycc is installed on %d peers, want 4", len(installed))
+	}
+
+	var instantiate []string
+	for _, cmd := range commands {
+		if cmd[2] == "instantiate" {
+			instantiate = cmd
+		}
+	}
+	want := []string{"peer", "chaincode", "instantiate", "-C", "mychannel", "-n", "mycc", "-v", "1.0", "-l", "golang",
+		"-c", `{"Args":["init","a","100","b","200"]}`, "-P", "AND ('Org1MSP.peer','Org2MSP.peer')"}
+	if len(instantiate) < len(want) || !reflect.DeepEqual(instantiate[:len(want)], want) {
+		t.Errorf("instantiate = %v, want %v", instantiate, want)
+	}
+
+	// everything is deployed already
+	commands = nil
+	if err := deployChaincodes(ctx, dockerClient, network, &out); err != nil {
+		t.Fatalf("deployChaincodes() error = %v", err)
+	}
+	for _, cmd := range commands {
+		if cmd[2] != "list" {
+			t.Errorf("deployChaincodes() ran %v on a deployed chaincode", cmd)
+		}
+	}
+}
+
+func Test_deployChaincodes_lifecycle(t *testing.T) {
+	network, dockerClient, fake := sampleNetwork(t, "test-network", "2.2.0")
+	ctx := context.Background()
+
+	var commands []string
+	var commit []string
+	installed := map[string]string{}
+	approved := map[string]bool{"Org1MSP": true}
+	committed := ""
+	fake.Exec = func(container string, cmd []string) (string, string, int) {
+		command := strings.Join(cmd[1:4], " ")
+		commands = append(commands, command)
+
+		env := execEnv(fake)
+		switch command {
+		case "lifecycle chaincode queryinstalled":
+			if label := installed[envValue(env, "CORE_PEER_ADDRESS")]; label != "" {
+				return "Installed chaincodes on peer:\nPackage ID: " + label + ":6f6e, Label: " + label + "\n", "", 0
+			}
+			return "Installed chaincodes on peer:\n", "", 0
+		case "lifecycle chaincode install":
+			installed[envValue(env, "CORE_PEER_ADDRESS")] = strings.TrimSuffix(cmd[4], ".tar.gz")
+		case "lifecycle chaincode querycommitted":
+			if committed != "" {
+				return `{"chaincode_definitions":[{"name":"basic","sequence":1,"version":"` + committed + `"}]}`, "", 0
+			}
+			return "{}", "", 0
+		case "lifecycle chaincode checkcommitreadiness":
+			return `{"approvals":{"Org1MSP":` + strconv.FormatBool(approved["Org1MSP"]) + `,"Org2MSP":` + strconv.FormatBool(approved["Org2MSP"]) + `}}`, "", 0
+		case "lifecycle chaincode approveformyorg":
+			if cmd[5] != "basic_1.0:6f6e" && cmd[5] != "basic_1.1:6f6e" {
+				t.Errorf("approveformyorg --package-id %s, want the installed package", cmd[5])
+			}
+			approved[envValue(env, "CORE_PEER_LOCALMSPID")] = true
+		case "lifecycle chaincode commit":
+			commit = cmd
+			committed = cmd[9]
+		}
+		return "", "", 0
+	}
+
+	var out bytes.Buffer
+	if err := deployChaincodes(ctx, dockerClient, network, &out); err != nil {
+		t.Fatalf("deployChaincodes() error = %v", err)
+	}
+	want := []string{
+		"lifecycle chaincode queryinstalled", "lifecycle chaincode package", "lifecycle chaincode install", "lifecycle chaincode queryinstalled",
+		"lifecycle chaincode queryinstalled", "lifecycle chaincode install", "lifecycle chaincode queryinstalled",
+		"lifecycle chaincode querycommitted", "lifecycle chaincode checkcommitreadiness",
+		"lifecycle chaincode approveformyorg", "lifecycle chaincode commit",
+	}
+	if !reflect.DeepEqual(commands, want) {
+		t.Errorf("deployChaincodes() ran %v, want %v", commands, want)
+	}
+
+	// the definition is committed with the endorsements of both organizations
+	for _, peer := range []string{"peer0.org1.example.com:7051", "peer0.org2.example.com:7051"} {
+		if !contains(commit, peer) {
+			t.Errorf("commit = %v, want --peerAddresses %s", commit, peer)
+		}
+	}
+
+	// everything is deployed already
+	commands = nil
+	if err := deployChaincodes(ctx, dockerClient, network, &out); err != nil {
+		t.Fatalf("deployChaincodes() error = %v", err)
+	}
+	want = []string{"lifecycle chaincode queryinstalled", "lifecycle chaincode queryinstalled", "lifecycle chaincode querycommitted"}
+	if !reflect.DeepEqual(commands, want) {
+		t.Errorf("deployChaincodes() ran %v, want %v", commands, want)
+	}
+
+	// a new version is committed with the next sequence
+	network.Chaincodes[0].Version = "1.1"
+	approved = map[string]bool{}
+	if err := deployChaincodes(ctx, dockerClient, network, &out); err != nil {
+		t.Fatalf("deployChaincodes() error = %v", err)
+	}
+	if committed != "1.1" || !reflect.DeepEqual(commit[10:12], []string{"--sequence", "2"}) {
+		t.Errorf("commit = %v, want version 1.1 with sequence 2", commit)
+	}
+}
@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/fatih/color"
+	"github.com/gangachris/hlf/compose"
+	"github.com/gangachris/hlf/docker"
+	"github.com/gangachris/hlf/spec"
+	"github.com/spf13/cobra"
+)
+
+var (
+	networkDownVolumes   bool
+	networkDownArtifacts bool
+	networkDownSample    string
+)
+
+// networkDownCmd tears down the network of a spec
+var networkDownCmd = &cobra.Command{
+	Use:   "down",
+	Short: "Remove the containers of the network of the spec",
+	Long: `Remove the containers of the network described by the network spec, the chaincode
+containers and images of its peers and its docker network. The ledgers are kept in the volumes
+of the nodes unless --volumes is given. --artifacts removes the generated crypto material and
+channel artifacts, and the volumes with them: ledgers can not be used with new crypto material.
+
+Down only removes what hlf created for the network, what is already removed is skipped.
+--sample tears down a sample network brought up with hlf network up --sample.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		file := specFile
+		if networkDownSample != "" {
+			dir, err := sampleDir(networkDownSample)
+			if err != nil {
+				errorExit(err)
+			}
+			file = filepath.Join(dir, spec.FileName)
+			if _, err := os.Stat(file); err != nil {
+				errorExit(fmt.Errorf("error: the %s sample is not up, %s does not exist", networkDownSample, file))
+			}
+		}
+
+		network, err := loadNetworkSpec(file)
+		if err != nil {
+			errorExit(err)
+		}
+
+		dockerClient, err := newDockerClient()
+		if err != nil {
+			errorExit(err)
+		}
+
+		if err := networkDown(context.Background(), dockerClient, network, networkDownVolumes, networkDownArtifacts, os.Stdout); err != nil {
+			errorExit(err)
+		}
+	},
+}
+
+func init() {
+	networkCmd.AddCommand(networkDownCmd)
+
+	networkDownCmd.Flags().BoolVar(&networkDownVolumes, "volumes", false, "Remove the volumes holding the ledgers of the nodes")
+	networkDownCmd.Flags().BoolVar(&networkDownArtifacts, "artifacts", false, "Remove the crypto material and the channel artifacts, implies --volumes")
+	networkDownCmd.Flags().StringVar(&networkDownSample, "sample", "", "Tear down a sample network instead of the network of the spec")
+}
+
+// networkDown removes the containers of a network and its docker network, the
+// volumes when volumes is set and the artifacts, along with the volumes whose
+// ledgers were written with them, when artifacts is set.
+func networkDown(ctx context.Context, dockerClient *docker.Client, network *spec.Network, volumes, artifacts bool, out io.Writer) error {
+	volumes = volumes || artifacts
+
+	// the peers of the spec, chaincodes are removed even when their peers were
+	// removed by a previous run
+	var peers []string
+	for _, org := range network.Organizations {
+		for i := 0; i < org.Peers; i++ {
+			peers = append(peers, org.PeerHost(i))
+		}
+	}
+	if len(peers) > 0 {
+		// the chaincodes of the network are removed with it, running or not
+		filter := docker.ChaincodeFilter{Peers: map[string][]string{compose.NetworkName(network.Name): peers}}
+		if err := cleanChaincodes(ctx, dockerClient, filter, false, true, out); err != nil {
+			return err
+		}
+	}
+
+	labels := map[string]string{docker.LabelNetwork: network.Name}
+	removed, err := dockerClient.RemoveContainers(ctx, labels)
+	for _, name := range removed {
+		fmt.Fprintf(out, "Removed %s\n", name)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := dockerClient.RemoveNetwork(ctx, network.Name, compose.NetworkName(network.Name)); err != nil {
+		return err
+	}
+
+	if volumes {
+		existing, err := dockerClient.Volumes(ctx, labels)
+		if err != nil {
+			return err
+		}
+		for _, volume := range existing {
+			if err := dockerClient.RemoveVolume(ctx, network.Name, volume.Name); err != nil {
+				return fmt.Errorf("error removing volume %s: %s", volume.Name, err.Error())
+			}
+			fmt.Fprintf(out, "Removed volume %s\n", volume.Name)
+		}
+
+		// nothing of the network is left
+		if err := forgetNetwork(network.Name); err != nil {
+			return err
+		}
+	}
+
+	if artifacts {
+		dir := network.ArtifactsDir()
+		if err := os.RemoveAll(dir); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Removed %s\n", dir)
+	}
+
+	color.New(color.FgGreen).Fprintf(out, "Network %s is down\n", network.Name)
+	return nil
+}
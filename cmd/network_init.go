@@ -0,0 +1,445 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/fatih/color"
+	"github.com/gangachris/hlf/spec"
+	"github.com/mattn/go-isatty"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// networkLayout is what hlf network init asks for.
+type networkLayout struct {
+	Name          string
+	Organizations int
+	Peers         int
+	Orderer       string
+	StateDatabase string
+	TLS           bool
+	CA            bool
+	Channels      []string
+}
+
+// networkTemplates are the built in layouts hlf network init starts from.
+var networkTemplates = map[string]networkLayout{
+	"dev": {
+		Organizations: 1,
+		Peers:         1,
+		Orderer:       spec.OrdererSolo,
+		StateDatabase: spec.LevelDB,
+		TLS:           false,
+		Channels:      []string{"mychannel"},
+	},
+	"test-network": {
+		Organizations: 2,
+		Peers:         1,
+		Orderer:       spec.OrdererEtcdRaft,
+		StateDatabase: spec.LevelDB,
+		TLS:           true,
+		CA:            true,
+		Channels:      []string{"mychannel"},
+	},
+	"raft": {
+		Organizations: 3,
+		Peers:         1,
+		Orderer:       spec.OrdererEtcdRaft,
+		StateDatabase: spec.LevelDB,
+		TLS:           true,
+		CA:            true,
+		Channels:      []string{"mychannel"},
+	},
+}
+
+var (
+	networkInitTemplate string
+	networkInitLayout   networkLayout
+	networkInitForce    bool
+)
+
+// networkInitCmd writes a network spec
+var networkInitCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Write a network spec and a README for a new network",
+	Long: `Write hlf.yaml, or the file given with -f, and a README next to it. On a terminal hlf asks
+for everything that is not set with a flag, otherwise the flags, the template and defaults
+are used. Templates:
+
+` + networkTemplatesHelp() + `
+
+	hlf network init
+	hlf network init --template test-network --fabric-version 2.2.0 --state-database couchdb
+	hlf network init --name ci --orgs 3 --peers 2 --channels a,b < /dev/null`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		var set []string
+		cmd.Flags().Visit(func(flag *pflag.Flag) {
+			set = append(set, flag.Name)
+		})
+
+		cfg, err := loadConfig()
+		if err != nil {
+			errorExit(err)
+		}
+
+		interactive := isatty.IsTerminal(os.Stdin.Fd())
+		if err := networkInit(specFile, networkInitTemplate, cfg.FabricVersion, networkInitLayout, set, networkInitForce, interactive, os.Stdin, os.Stdout); err != nil {
+			errorExit(err)
+		}
+	},
+}
+
+func init() {
+	networkCmd.AddCommand(networkInitCmd)
+
+	flags := networkInitCmd.Flags()
+	flags.StringVar(&networkInitTemplate, "template", "dev", "Layout to start from: "+strings.Join(networkTemplateNames(), ", "))
+	flags.StringVar(&networkInitLayout.Name, "name", "", "Name of the network (default is the name of the directory)")
+	flags.IntVar(&networkInitLayout.Organizations, "orgs", 0, "Number of peer organizations")
+	flags.IntVar(&networkInitLayout.Peers, "peers", 0, "Number of peers per organization")
+	flags.StringVar(&networkInitLayout.Orderer, "orderer", "", "Orderer type: "+spec.OrdererSolo+", "+spec.OrdererKafka+" or "+spec.OrdererEtcdRaft)
+	flags.StringVar(&networkInitLayout.StateDatabase, "state-database", "", "State database of the peers: "+spec.LevelDB+" or "+spec.CouchDB)
+	flags.BoolVar(&networkInitLayout.TLS, "tls", false, "Enable TLS")
+	flags.BoolVar(&networkInitLayout.CA, "ca", false, "Run a CA per organization")
+	flags.StringSliceVar(&networkInitLayout.Channels, "channels", nil, "Comma separated channel names")
+	flags.BoolVar(&networkInitForce, "force", false, "Overwrite an existing network spec")
+}
+
+func networkTemplateNames() []string {
+	var names []string
+	for name := range networkTemplates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func networkTemplatesHelp() string {
+	var lines []string
+	for _, name := range networkTemplateNames() {
+		layout := networkTemplates[name]
+		lines = append(lines, fmt.Sprintf("\t%-14s%d organizations with %d peers, %s orderer with %d nodes, %s, TLS %s",
+			name, layout.Organizations, layout.Peers, layout.Orderer, ordererNodes(layout.Orderer), layout.StateDatabase, onOff(layout.TLS)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func onOff(enabled bool) string {
+	if enabled {
+		return "on"
+	}
+	return "off"
+}
+
+// ordererNodes is the number of orderer nodes of a new network, etcdraft runs
+// 3 consenters so that the failure of one is tolerated.
+func ordererNodes(ordererType string) int {
+	if ordererType == spec.OrdererEtcdRaft {
+		return 3
+	}
+	return 1
+}
+
+// networkNamePattern matches the characters network names can not have.
+var networkNamePattern = regexp.MustCompile(`[^a-z0-9_-]+`)
+
+// networkInit writes the network spec of a layout and its README, pinned to
+// the fabric version fabric. The layout starts from a template, flags override
+// it and, when interactive, everything that is not set with a flag is asked for.
+func networkInit(file, templateName, fabric string, flags networkLayout, set []string, force, interactive bool, in io.Reader, out io.Writer) error {
+	layout, ok := networkTemplates[templateName]
+	if !ok {
+		return fmt.Errorf("error: unknown template %s, expected one of %s", templateName, strings.Join(networkTemplateNames(), ", "))
+	}
+
+	if _, err := os.Stat(file); err == nil && !force {
+		return fmt.Errorf("error: %s already exists, use --force to overwrite it", file)
+	}
+
+	dir, err := filepath.Abs(filepath.Dir(file))
+	if err != nil {
+		return err
+	}
+	layout.Name = strings.Trim(networkNamePattern.ReplaceAllString(strings.ToLower(filepath.Base(dir)), "-"), "-_")
+	if layout.Name == "" {
+		layout.Name = "dev"
+	}
+
+	for _, flag := range set {
+		switch flag {
+		case "name":
+			layout.Name = flags.Name
+		case "orgs":
+			layout.Organizations = flags.Organizations
+		case "peers":
+			layout.Peers = flags.Peers
+		case "orderer":
+			layout.Orderer = flags.Orderer
+		case "state-database":
+			layout.StateDatabase = flags.StateDatabase
+		case "tls":
+			layout.TLS = flags.TLS
+		case "ca":
+			layout.CA = flags.CA
+		case "channels":
+			layout.Channels = flags.Channels
+		}
+	}
+
+	if interactive {
+		if err := askNetworkLayout(&layout, set, bufio.NewReader(in), out); err != nil {
+			return err
+		}
+	}
+
+	if layout.Orderer == spec.OrdererEtcdRaft && !versionAtLeast(fabric, "1.4.1") {
+		return fmt.Errorf("error: the %s orderer needs fabric 1.4.1 or later, the configured fabric version is %s: use --fabric-version or hlf config set fabric-version", spec.OrdererEtcdRaft, fabric)
+	}
+
+	data, err := renderNetworkSpec(layout, fabric)
+	if err != nil {
+		return err
+	}
+
+	// the spec is checked before anything is written
+	network, err := spec.Parse(filepath.Base(file), data)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	if err := ioutil.WriteFile(file, data, 0644); err != nil {
+		return err
+	}
+
+	readme, err := renderNetworkReadme(network, filepath.Base(file))
+	if err != nil {
+		return err
+	}
+
+	readmeFile := filepath.Join(filepath.Dir(file), "README.md")
+	if _, err := os.Stat(readmeFile); err == nil && !force {
+		color.New(color.FgYellow).Fprintf(out, "%s already exists, it is left as is\n", readmeFile)
+	} else if err := ioutil.WriteFile(readmeFile, readme, 0644); err != nil {
+		return err
+	}
+
+	color.New(color.FgGreen).Fprintf(out, "Wrote %s and %s\n", file, readmeFile)
+	return nil
+}
+
+// askNetworkLayout asks for the fields of a layout not set with a flag, an
+// invalid answer is asked again.
+func askNetworkLayout(layout *networkLayout, set []string, answers *bufio.Reader, out io.Writer) error {
+	questions := []struct {
+		flag     string
+		question string
+		value    func() string
+		parse    func(answer string) error
+	}{
+		{"name", "Network name", func() string { return layout.Name }, func(answer string) error {
+			layout.Name = answer
+			return nil
+		}},
+		{"orgs", "Number of peer organizations", func() string { return strconv.Itoa(layout.Organizations) }, func(answer string) error {
+			return parsePositive(answer, &layout.Organizations)
+		}},
+		{"peers", "Peers per organization", func() string { return strconv.Itoa(layout.Peers) }, func(answer string) error {
+			return parsePositive(answer, &layout.Peers)
+		}},
+		{"orderer", "Orderer type (" + spec.OrdererSolo + ", " + spec.OrdererKafka + ", " + spec.OrdererEtcdRaft + ")", func() string { return layout.Orderer }, func(answer string) error {
+			if answer != spec.OrdererSolo && answer != spec.OrdererKafka && answer != spec.OrdererEtcdRaft {
+				return fmt.Errorf("expected %s, %s or %s", spec.OrdererSolo, spec.OrdererKafka, spec.OrdererEtcdRaft)
+			}
+			layout.Orderer = answer
+			return nil
+		}},
+		{"state-database", "State database (" + spec.LevelDB + ", " + spec.CouchDB + ")", func() string { return layout.StateDatabase }, func(answer string) error {
+			if answer != spec.LevelDB && answer != spec.CouchDB {
+				return fmt.Errorf("expected %s or %s", spec.LevelDB, spec.CouchDB)
+			}
+			layout.StateDatabase = answer
+			return nil
+		}},
+		{"tls", "Enable TLS (y, n)", func() string { return yesNo(layout.TLS) }, func(answer string) error {
+			return parseYesNo(answer, &layout.TLS)
+		}},
+		{"ca", "Run a CA per organization (y, n)", func() string { return yesNo(layout.CA) }, func(answer string) error {
+			return parseYesNo(answer, &layout.CA)
+		}},
+		{"channels", "Channels, comma separated", func() string { return strings.Join(layout.Channels, ",") }, func(answer string) error {
+			layout.Channels = nil
+			for _, channel := range strings.Split(answer, ",") {
+				if channel = strings.TrimSpace(channel); channel != "" {
+					layout.Channels = append(layout.Channels, channel)
+				}
+			}
+			return nil
+		}},
+	}
+
+	for _, q := range questions {
+		if contains(set, q.flag) {
+			continue
+		}
+
+		for {
+			answer, err := ask(answers, out, q.question, q.value())
+			if err != nil {
+				return err
+			}
+
+			err = q.parse(answer)
+			if err == nil {
+				break
+			}
+			color.New(color.FgRed).Fprintf(out, "invalid answer %s: %s\n", answer, err.Error())
+		}
+	}
+	return nil
+}
+
+func parsePositive(answer string, value *int) error {
+	n, err := strconv.Atoi(answer)
+	if err != nil || n < 1 {
+		return fmt.Errorf("expected a number greater than 0")
+	}
+	*value = n
+	return nil
+}
+
+func yesNo(yes bool) string {
+	if yes {
+		return "y"
+	}
+	return "n"
+}
+
+func parseYesNo(answer string, value *bool) error {
+	switch strings.ToLower(answer) {
+	case "y", "yes":
+		*value = true
+	case "n", "no":
+		*value = false
+	default:
+		return fmt.Errorf("expected y or n")
+	}
+	return nil
+}
+
+var networkSpecTemplate = template.Must(template.New("hlf.yaml").Parse(`# Network spec written by hlf network init, see hlf spec --help.
+version: {{ .Version }}
+name: {{ .Layout.Name }}
+fabric: {{ .Fabric }}
+domain: example.com
+tls:
+  enabled: {{ .Layout.TLS }}
+# crypto material is generated with cryptogen, native generates it with hlf
+crypto:
+  generator: cryptogen
+# host ports are allocated from here upwards
+portBase: 7050
+stateDatabase: {{ .Layout.StateDatabase }}
+
+# etcdraft runs a raft consenter on every orderer node, e.g 3 nodes tolerate
+# the failure of one
+orderer:
+  type: {{ .Layout.Orderer }}
+  organizations:
+    - name: Orderer
+      nodes: {{ .OrdererNodes }}
+
+organizations:
+{{- range .Organizations }}
+  - name: {{ . }}
+    peers: {{ $.Layout.Peers }}
+    ca: {{ $.Layout.CA }}
+{{- end }}
+
+channels:
+{{- range .Layout.Channels }}
+  - name: {{ . }}
+    organizations: [{{ $.Members }}]
+{{- end }}
+
+# chaincodes are deployed on every channel unless channels are listed
+# chaincodes:
+#   - name: mycc
+#     path: ./chaincode/mycc
+#     language: golang
+`))
+
+func renderNetworkSpec(layout networkLayout, fabric string) ([]byte, error) {
+	var organizations []string
+	for i := 1; i <= layout.Organizations; i++ {
+		organizations = append(organizations, fmt.Sprintf("Org%d", i))
+	}
+
+	var buf bytes.Buffer
+	err := networkSpecTemplate.Execute(&buf, struct {
+		Version       int
+		Fabric        string
+		Layout        networkLayout
+		OrdererNodes  int
+		Organizations []string
+		Members       string
+	}{spec.Version, fabric, layout, ordererNodes(layout.Orderer), organizations, strings.Join(organizations, ", ")})
+	return buf.Bytes(), err
+}
+
+var networkReadmeTemplate = template.Must(template.New("README.md").Parse(`# {{ .Network.Name }}
+
+A Hyperledger Fabric network described by [{{ .File }}]({{ .File }}), written by ` + "`hlf network init`" + `.
+
+| Organization | MSP ID | Peers | State database | CA |
+|---|---|---|---|---|
+{{- range .Network.Organizations }}
+| {{ .Name }} | {{ .MSPID }} | {{ range $i, $_ := call $.Peers . }}{{ if $i }}, {{ end }}{{ . }}{{ end }} | {{ .StateDatabase }} | {{ if .CA }}yes{{ else }}no{{ end }} |
+{{- end }}
+
+The {{ .Network.Orderer.Type }} ordering service runs on {{ range $i, $node := .Network.OrdererNodes }}{{ if $i }}, {{ end }}{{ $node.Host }}{{ end }}.
+TLS is {{ if .Network.TLS.Enabled }}enabled{{ else }}disabled{{ end }}.
+
+Channels:
+{{ range .Network.Channels }}
+- {{ .Name }}: {{ range $i, $org := .Organizations }}{{ if $i }}, {{ end }}{{ $org }}{{ end }}
+{{- end }}
+
+Check the spec after editing it, and print it with the defaults of the fields that are not set:
+
+` + "```" + `
+hlf spec validate -f {{ .File }}
+hlf spec show -f {{ .File }}
+` + "```" + `
+`))
+
+func renderNetworkReadme(network *spec.Network, file string) ([]byte, error) {
+	var buf bytes.Buffer
+	err := networkReadmeTemplate.Execute(&buf, struct {
+		Network *spec.Network
+		File    string
+		Peers   func(org spec.Organization) []string
+	}{network, file, func(org spec.Organization) []string {
+		var hosts []string
+		for i := 0; i < org.Peers; i++ {
+			hosts = append(hosts, org.PeerHost(i))
+		}
+		return hosts
+	}})
+	return buf.Bytes(), err
+}
@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gangachris/hlf/spec"
+)
+
+func Test_networkInit(t *testing.T) {
+	tests := []struct {
+		name        string
+		template    string
+		fabric      string
+		flags       networkLayout
+		set         []string
+		interactive bool
+		answers     string
+		want        func(network *spec.Network) bool
+		wantErr     bool
+	}{
+		{
+			name:     "template",
+			template: "test-network",
+			fabric:   "2.2.0",
+			want: func(network *spec.Network) bool {
+				return network.Name == "my-network" && network.Fabric == "2.2.0" && len(network.Organizations) == 2 && network.TLS.Enabled &&
+					len(network.OrdererNodes()) == 3 && network.Organizations[1].CA &&
+					reflect.DeepEqual(network.Channels[0].Organizations, []string{"Org1", "Org2"})
+			},
+		},
+		{
+			name:     "raft template",
+			template: "raft",
+			fabric:   "2.2.0",
+			want: func(network *spec.Network) bool {
+				return len(network.Organizations) == 3 && network.Orderer.Type == spec.OrdererEtcdRaft && network.TLS.Enabled &&
+					len(network.OrdererNodes()) == 3 && reflect.DeepEqual(network.Channels[0].Organizations, []string{"Org1", "Org2", "Org3"})
+			},
+		},
+		{
+			name:     "etcdraft before fabric 1.4.1",
+			template: "test-network",
+			fabric:   "1.1.0",
+			wantErr:  true,
+		},
+		{
+			name:     "flags override the template",
+			template: "dev",
+			fabric:   "1.1.0",
+			flags:    networkLayout{Name: "ci", Organizations: 3, Peers: 2, StateDatabase: spec.CouchDB, Channels: []string{"a", "b"}},
+			set:      []string{"name", "orgs", "peers", "state-database", "channels"},
+			want: func(network *spec.Network) bool {
+				return network.Name == "ci" && network.Fabric == "1.1.0" && len(network.OrdererNodes()) == 1 && len(network.Organizations) == 3 && network.Organizations[2].Peers == 2 &&
+					network.Organizations[0].StateDatabase == spec.CouchDB && len(network.Channels) == 2
+			},
+		},
+		{
+			name:        "answers, invalid ones are asked again",
+			template:    "dev",
+			fabric:      "1.4.4",
+			flags:       networkLayout{Peers: 3},
+			set:         []string{"peers"},
+			interactive: true,
+			answers:     "shop\nzero\n2\nraft\nkafka\n\ny\n\ntrade, audit\n",
+			want: func(network *spec.Network) bool {
+				return network.Name == "shop" && len(network.Organizations) == 2 && network.Organizations[0].Peers == 3 &&
+					network.Orderer.Type == spec.OrdererKafka && network.StateDatabase == spec.LevelDB && network.TLS.Enabled &&
+					len(network.Channels) == 2 && network.Channels[1].Name == "audit"
+			},
+		},
+		{
+			name:     "unknown template",
+			template: "five-org",
+			fabric:   "1.4.4",
+			wantErr:  true,
+		},
+		{
+			name:     "invalid spec is not written",
+			template: "dev",
+			fabric:   "1.4.4",
+			flags:    networkLayout{Channels: []string{"My Channel"}},
+			set:      []string{"channels"},
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "hlf-network-init")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+
+			file := filepath.Join(dir, "My Network", spec.FileName)
+			err = networkInit(file, tt.template, tt.fabric, tt.flags, tt.set, false, tt.interactive, strings.NewReader(tt.answers), ioutil.Discard)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("networkInit() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				if _, err := os.Stat(file); !os.IsNotExist(err) {
+					t.Errorf("networkInit() wrote %s", file)
+				}
+				return
+			}
+
+			network, err := spec.Load(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !tt.want(network) {
+				t.Errorf("networkInit() wrote %+v", network)
+			}
+
+			if _, err := os.Stat(filepath.Join(dir, "My Network", "README.md")); err != nil {
+				t.Errorf("networkInit() README: %v", err)
+			}
+
+			// an existing spec is only overwritten with --force
+			if err := networkInit(file, tt.template, tt.fabric, tt.flags, tt.set, false, false, strings.NewReader(""), ioutil.Discard); err == nil {
+				t.Errorf("networkInit() overwrote %s", file)
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gangachris/hlf/spec"
+)
+
+// networkStartGracePeriod is how long a network is starting at most, its start
+// crashed when its containers were not created by then.
+const networkStartGracePeriod = 10 * time.Minute
+
+// networkRecord records a network hlf brought up in the state directory. The
+// docker resources of networks without a record, or whose spec is gone, were
+// left behind by runs that crashed or by specs that were deleted.
+type networkRecord struct {
+	Name string `json:"name"`
+
+	// Dir is the directory of the spec of the network.
+	Dir string `json:"dir"`
+
+	// Peers are the peers of the network, fabric names their chaincode
+	// containers and images after them.
+	Peers []string `json:"peers,omitempty"`
+
+	// Starting is when hlf network up began to create the docker network,
+	// volumes and containers of the network, zero once they are created.
+	Starting time.Time `json:"starting"`
+}
+
+// networkRecordsDir returns the directory network records are kept in.
+func networkRecordsDir() (string, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(cfg.StateDir(), "networks"), nil
+}
+
+// recordNetwork records a network, its record is replaced when it exists. A
+// starting network is one whose docker resources are being created, hlf gc
+// leaves them alone until its containers exist.
+func recordNetwork(network *spec.Network, starting bool) error {
+	dir, err := networkRecordsDir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	record := networkRecord{Name: network.Name, Dir: network.Dir}
+	if starting {
+		record.Starting = time.Now()
+	}
+	for _, org := range network.Organizations {
+		for i := 0; i < org.Peers; i++ {
+			record.Peers = append(record.Peers, org.PeerHost(i))
+		}
+	}
+
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(dir, network.Name+".json"), data, 0644)
+}
+
+// forgetNetwork removes the record of a network.
+func forgetNetwork(name string) error {
+	dir, err := networkRecordsDir()
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(filepath.Join(dir, name+".json"))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// loadNetworkRecords returns the recorded networks by name.
+func loadNetworkRecords() (map[string]*networkRecord, error) {
+	dir, err := networkRecordsDir()
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return map[string]*networkRecord{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	records := map[string]*networkRecord{}
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".json") {
+			continue
+		}
+
+		data, err := ioutil.ReadFile(filepath.Join(dir, file.Name()))
+		if err != nil {
+			return nil, err
+		}
+		record := &networkRecord{}
+		if err := json.Unmarshal(data, record); err != nil {
+			return nil, err
+		}
+		records[record.Name] = record
+	}
+	return records, nil
+}
+
+// knownNetworks returns the names of the recorded networks whose spec directory
+// still exists, and of those the ones that started less than the grace period ago.
+func knownNetworks() (known, starting map[string]bool, err error) {
+	records, err := loadNetworkRecords()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	known, starting = map[string]bool{}, map[string]bool{}
+	for name, record := range records {
+		if _, err := os.Stat(record.Dir); err != nil {
+			continue
+		}
+		known[name] = true
+		if !record.Starting.IsZero() && time.Since(record.Starting) < networkStartGracePeriod {
+			starting[name] = true
+		}
+	}
+	return known, starting, nil
+}
@@ -0,0 +1,353 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/fatih/color"
+	"github.com/gangachris/hlf/compose"
+	"github.com/gangachris/hlf/config"
+	"github.com/gangachris/hlf/docker"
+	"github.com/gangachris/hlf/semver"
+	"github.com/gangachris/hlf/spec"
+	"github.com/spf13/cobra"
+)
+
+var networkStatusNetwork string
+
+// networkStatusCmd shows the nodes and channels of a running network
+var networkStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the nodes, channels and chaincodes of a running network",
+	Long: `Show the state, health, version and ports of the containers of a network, and for
+every peer the height of the ledger of its channels, the chaincodes committed on them and the
+chaincodes installed on the peer. The peers are queried with the CLI container of the network.
+
+For etcdraft orderers, the raft leader of every channel and how many of the consenters follow it
+are read from the logs of the orderers.
+
+The network is found by the labels of its containers: the network given with --network, the
+one of the spec in the current directory or the configured network.
+
+	hlf network status
+	hlf network status -n dev -o json`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, err := loadConfig()
+		if err != nil {
+			errorExit(err)
+		}
+
+		name := networkStatusNetwork
+		if name == "" {
+			if network, err := spec.Load(specFile); err == nil {
+				name = network.Name
+			} else {
+				name = cfg.Network
+			}
+		}
+		if name == "" {
+			errorExit(fmt.Errorf("error: no network given, use --network or run it next to %s", spec.FileName))
+		}
+
+		dockerClient, err := newDockerClient()
+		if err != nil {
+			errorExit(err)
+		}
+
+		ctx, cancel := interruptContext()
+		defer cancel()
+
+		status, err := networkStatus(ctx, dockerClient, name)
+		if err != nil {
+			errorExit(err)
+		}
+
+		if cfg.Output == config.OutputJSON {
+			err = writeNetworkStatusJSON(os.Stdout, status)
+		} else {
+			err = writeNetworkStatusTable(os.Stdout, status)
+		}
+		if err != nil {
+			errorExit(err)
+		}
+	},
+}
+
+func init() {
+	networkCmd.AddCommand(networkStatusCmd)
+
+	networkStatusCmd.Flags().StringVarP(&networkStatusNetwork, "network", "n", "", "Name of the hlf network, the network of the spec or the configured network by default")
+}
+
+// networkStatusReport is the state of the nodes and channels of a network.
+type networkStatusReport struct {
+	Network string              `json:"network"`
+	Nodes   []docker.NodeStatus `json:"nodes"`
+	Peers   []peerStatus        `json:"peers"`
+
+	// Raft is the raft consensus of the channels of etcdraft orderers.
+	Raft []docker.RaftStatus `json:"raft,omitempty"`
+}
+
+// peerStatus is what a peer reports about its channels and chaincodes.
+type peerStatus struct {
+	Peer      string          `json:"peer"`
+	Channels  []channelStatus `json:"channels"`
+	Installed []string        `json:"installed"`
+
+	// Error is why the peer could not be queried.
+	Error string `json:"error,omitempty"`
+}
+
+// channelStatus is a channel a peer has joined.
+type channelStatus struct {
+	Name      string   `json:"name"`
+	Height    uint64   `json:"height"`
+	Committed []string `json:"committed"`
+}
+
+func networkStatus(ctx context.Context, dockerClient *docker.Client, network string) (*networkStatusReport, error) {
+	nodes, err := dockerClient.NodeStatuses(ctx, map[string]string{docker.LabelNetwork: network})
+	if err != nil {
+		return nil, err
+	}
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("error: no containers found for network %s, is it up?", network)
+	}
+
+	status := &networkStatusReport{Network: network, Nodes: nodes, Peers: []peerStatus{}}
+
+	if status.Raft, err = dockerClient.RaftStatuses(ctx, map[string]string{docker.LabelNetwork: network}); err != nil {
+		return nil, err
+	}
+
+	cli := ""
+	for _, node := range nodes {
+		if node.Role == docker.RoleCLI && node.State == "running" {
+			cli = node.Name
+		}
+	}
+
+	for _, node := range nodes {
+		if node.Role != docker.RolePeer {
+			continue
+		}
+
+		peer := peerStatus{Peer: node.Name, Channels: []channelStatus{}, Installed: []string{}}
+		switch {
+		case node.State != "running":
+			peer.Error = "the peer is " + node.State
+		case cli == "":
+			peer.Error = "the CLI container of the network is not running"
+		default:
+			if err := queryPeer(ctx, dockerClient, cli, node, &peer); err != nil {
+				peer.Error = err.Error()
+			}
+		}
+		status.Peers = append(status.Peers, peer)
+	}
+	return status, nil
+}
+
+// queryPeer queries the channels and chaincodes of a peer as the admin of its
+// organization, found from the environment of the peer container.
+func queryPeer(ctx context.Context, dockerClient *docker.Client, cli string, node docker.NodeStatus, peer *peerStatus) error {
+	inspect, err := dockerClient.Inspect(ctx, node.Name)
+	if err != nil {
+		return err
+	}
+
+	tls := spec.TLS{
+		Enabled:    envValue(inspect.Config.Env, "CORE_PEER_TLS_ENABLED") == "true",
+		ClientAuth: envValue(inspect.Config.Env, "CORE_PEER_TLS_CLIENTAUTHREQUIRED") == "true",
+	}
+	domain := node.Name[strings.Index(node.Name, ".")+1:]
+	env := compose.AdminEnv(tls, envValue(inspect.Config.Env, "CORE_PEER_LOCALMSPID"), domain, node.Name)
+
+	// the chaincode lifecycle of fabric 2
+	lifecycle, err := semver.CorrectVersion("2.0", node.Version)
+	lifecycle = err == nil && lifecycle
+
+	output, err := peerCommand(ctx, dockerClient, cli, env, "channel", "list")
+	if err != nil {
+		return err
+	}
+	for _, name := range joinedChannels(output) {
+		channel := channelStatus{Name: name, Committed: []string{}}
+
+		output, err := peerCommand(ctx, dockerClient, cli, env, "channel", "getinfo", "-c", name)
+		if err != nil {
+			return err
+		}
+		if channel.Height, err = ledgerHeight(output); err != nil {
+			return err
+		}
+
+		query := []string{"chaincode", "list", "--instantiated", "-C", name}
+		if lifecycle {
+			query = []string{"lifecycle", "chaincode", "querycommitted", "-C", name}
+		}
+		if output, err = peerCommand(ctx, dockerClient, cli, env, query...); err != nil {
+			return err
+		}
+		channel.Committed = append(channel.Committed, chaincodeNames(output)...)
+
+		peer.Channels = append(peer.Channels, channel)
+	}
+
+	query := []string{"chaincode", "list", "--installed"}
+	if lifecycle {
+		query = []string{"lifecycle", "chaincode", "queryinstalled"}
+	}
+	if output, err = peerCommand(ctx, dockerClient, cli, env, query...); err != nil {
+		return err
+	}
+	peer.Installed = append(peer.Installed, chaincodeNames(output)...)
+	return nil
+}
+
+func envValue(env []string, key string) string {
+	for _, variable := range env {
+		if strings.HasPrefix(variable, key+"=") {
+			return strings.TrimPrefix(variable, key+"=")
+		}
+	}
+	return ""
+}
+
+// joinedChannels parses the output of peer channel list.
+func joinedChannels(output string) []string {
+	var channels []string
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.Contains(line, " ") {
+			continue
+		}
+		channels = append(channels, line)
+	}
+	return channels
+}
+
+// blockchainInfo matches the output of peer channel getinfo e.g
+// Blockchain info: {"height":5,"currentBlockHash":"..."}
+var blockchainInfo = regexp.MustCompile(`Blockchain info: (\{.*\})`)
+
+func ledgerHeight(output string) (uint64, error) {
+	match := blockchainInfo.FindStringSubmatch(output)
+	if match == nil {
+		return 0, fmt.Errorf("error reading the ledger height: %s", strings.TrimSpace(output))
+	}
+
+	var info struct {
+		Height uint64 `json:"height"`
+	}
+	if err := json.Unmarshal([]byte(match[1]), &info); err != nil {
+		return 0, fmt.Errorf("error reading the ledger height: %s", err.Error())
+	}
+	return info.Height, nil
+}
+
+// chaincodeLine matches the chaincodes listed by the peer: Name: mycc, Version: 1.0
+// before fabric 2 and for committed definitions, Label: mycc_1 for installed packages.
+var chaincodeLine = regexp.MustCompile(`Name: ([^,\s]+), Version: ([^,\s]+)|Label: (\S+)`)
+
+// chaincodeNames parses the chaincodes listed by the peer e.g mycc:1.0.
+func chaincodeNames(output string) []string {
+	var names []string
+	for _, match := range chaincodeLine.FindAllStringSubmatch(output, -1) {
+		if match[3] != "" {
+			names = append(names, match[3])
+			continue
+		}
+		names = append(names, match[1]+":"+match[2])
+	}
+	return names
+}
+
+func writeNetworkStatusJSON(out io.Writer, status *networkStatusReport) error {
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(status)
+}
+
+func writeNetworkStatusTable(out io.Writer, status *networkStatusReport) error {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NODE\tROLE\tORG\tSTATE\tHEALTH\tVERSION\tPORTS")
+	for _, node := range status.Nodes {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			node.Name,
+			node.Role,
+			orDash(node.Org),
+			node.State,
+			orDash(node.Health),
+			node.Version,
+			orDash(strings.Join(node.Ports, ",")),
+		)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if len(status.Raft) > 0 {
+		fmt.Fprintln(out)
+		w = tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "CHANNEL\tRAFT LEADER\tFOLLOWING")
+		for _, raft := range status.Raft {
+			fmt.Fprintf(w, "%s\t%s\t%d/%d\n", raft.Channel, orDash(raft.Leader), len(raft.Following), len(raft.Consenters))
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+
+	if len(status.Peers) == 0 {
+		return nil
+	}
+
+	fmt.Fprintln(out)
+	w = tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "PEER\tCHANNEL\tHEIGHT\tCOMMITTED\tINSTALLED")
+	var errors []string
+	for _, peer := range status.Peers {
+		if peer.Error != "" {
+			errors = append(errors, peer.Peer+": "+peer.Error)
+		}
+
+		installed := orDash(strings.Join(peer.Installed, ","))
+		if len(peer.Channels) == 0 {
+			fmt.Fprintf(w, "%s\t-\t-\t-\t%s\n", peer.Peer, installed)
+		}
+		for _, channel := range peer.Channels {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+				peer.Peer,
+				channel.Name,
+				strconv.FormatUint(channel.Height, 10),
+				orDash(strings.Join(channel.Committed, ",")),
+				installed,
+			)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	for _, message := range errors {
+		color.New(color.FgYellow).Fprintln(out, message)
+	}
+	return nil
+}
+
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
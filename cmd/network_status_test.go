@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/gangachris/hlf/compose"
+	"github.com/gangachris/hlf/docker"
+	"github.com/gangachris/hlf/docker/dockertest"
+	"github.com/gangachris/hlf/spec"
+)
+
+func Test_networkStatus(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hlf-network")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	network, err := spec.Parse(spec.FileName, []byte(twoOrgsSpec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	network.Dir = dir
+	network.Fabric = "2.2.0"
+
+	project, err := compose.FromSpec(network, compose.Options{FabricTag: "2.2.0", CATag: "1.4.9", ThirdPartyTag: "0.4.18"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fake := dockertest.New()
+	for _, c := range project.Containers {
+		fake.AddImage(types.ImageSummary{RepoTags: []string{c.Image}})
+	}
+	dockerClient := docker.NewWithAPI(fake, docker.RuntimeDocker)
+	ctx := context.Background()
+	if err := startNetwork(ctx, dockerClient, project, ioutil.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if err := fake.Exit("peer0.org2.example.com", 137); err != nil {
+		t.Fatal(err)
+	}
+
+	fake.Exec = func(container string, cmd []string) (string, string, int) {
+		switch strings.Join(cmd[1:], " ") {
+		case "channel list":
+			return "Channels peers has joined: \nmychannel\n", "2020-07-01 10:00:00.000 UTC [channelCmd] InitCmdFactory -> INFO 001 Endorser and orderer connections initialized\n", 0
+		case "channel getinfo -c mychannel":
+			return `Blockchain info: {"height":5,"currentBlockHash":"c2Jt","previousBlockHash":"cHJl"}` + "\n", "", 0
+		case "lifecycle chaincode querycommitted -C mychannel":
+			return "Committed chaincode definitions on channel 'mychannel':\nName: basic, Version: 1.0, Sequence: 1, Endorsement Plugin: escc, Validation Plugin: vscc\n", "", 0
+		case "lifecycle chaincode queryinstalled":
+			return "Installed chaincodes on peer:\nPackage ID: basic_1.0:4ec191e793b27e953ff2ede5a8bcc63152cecb1e4c3f301a26e22692c61967ad, Label: basic_1.0\n", "", 0
+		}
+		return "", "Error: unknown command " + strings.Join(cmd, " "), 1
+	}
+
+	status, err := networkStatus(ctx, dockerClient, "dev")
+	if err != nil {
+		t.Fatalf("networkStatus() error = %v", err)
+	}
+	if len(status.Nodes) != len(project.Containers) {
+		t.Errorf("networkStatus() found %d nodes, want %d", len(status.Nodes), len(project.Containers))
+	}
+
+	want := []peerStatus{
+		{
+			Peer:      "peer0.org1.example.com",
+			Channels:  []channelStatus{{Name: "mychannel", Height: 5, Committed: []string{"basic:1.0"}}},
+			Installed: []string{"basic_1.0"},
+		},
+		{Peer: "peer0.org2.example.com", Channels: []channelStatus{}, Installed: []string{}, Error: "the peer is exited"},
+	}
+	if !reflect.DeepEqual(status.Peers, want) {
+		t.Errorf("networkStatus() peers = %+v, want %+v", status.Peers, want)
+	}
+
+	// the peer is queried as the admin of its organization
+	execs := fake.CallsTo("ContainerExecCreate")
+	env := execs[0].Args[1].(types.ExecConfig).Env
+	if !contains(env, "CORE_PEER_LOCALMSPID=Org1MSP") || !contains(env, "CORE_PEER_TLS_ENABLED=true") {
+		t.Errorf("peer command env = %v, want the admin of Org1 with TLS", env)
+	}
+
+	var out bytes.Buffer
+	if err := writeNetworkStatusJSON(&out, status); err != nil {
+		t.Fatal(err)
+	}
+	var decoded networkStatusReport
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil || decoded.Network != "dev" {
+		t.Errorf("writeNetworkStatusJSON() = %s, %v", out.String(), err)
+	}
+
+	out.Reset()
+	if err := writeNetworkStatusTable(&out, status); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"peer0.org1.example.com  mychannel  5       basic:1.0  basic_1.0", "peer0.org2.example.com: the peer is exited"} {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("writeNetworkStatusTable() =\n%s\nwant a line with %q", out.String(), line)
+		}
+	}
+}
+
+func Test_chaincodeNames(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   []string
+	}{
+		{
+			name:   "installed before fabric 2",
+			output: "Get installed chaincodes on peer:\nName: mycc, Version: 1.0, Path: github.com/chaincode/mycc, Id: 333a19b11063d0ade7be691f9f22c04ad369baba15660f7ae9511fd1a6488474\n",
+			want:   []string{"mycc:1.0"},
+		},
+		{
+			name:   "instantiated before fabric 2",
+			output: "Get instantiated chaincodes on channel mychannel:\nName: mycc, Version: 1.0, Path: github.com/chaincode/mycc, Escc: escc, Vscc: vscc\nName: fabcar, Version: 2, Path: fabcar, Escc: escc, Vscc: vscc\n",
+			want:   []string{"mycc:1.0", "fabcar:2"},
+		},
+		{
+			name:   "none",
+			output: "Get installed chaincodes on peer:\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := chaincodeNames(tt.output); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("chaincodeNames() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
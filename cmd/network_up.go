@@ -0,0 +1,526 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/gangachris/hlf/compose"
+	"github.com/gangachris/hlf/configtx"
+	"github.com/gangachris/hlf/configtxgen"
+	"github.com/gangachris/hlf/cryptogen"
+	"github.com/gangachris/hlf/docker"
+	"github.com/gangachris/hlf/samples"
+	"github.com/gangachris/hlf/spec"
+	"github.com/spf13/cobra"
+)
+
+var (
+	networkUpTimeout time.Duration
+	networkUpSample  string
+	networkUpWatch   bool
+
+	networkUpRestart     string
+	networkUpMaxRestarts int
+)
+
+// networkUpCmd starts the network of a spec
+var networkUpCmd = &cobra.Command{
+	Use:   "up",
+	Short: "Start the network of the spec and set up its channels",
+	Long: `Start the network described by the network spec:
+
+	1. generate the crypto material, configtx.yaml, the channel artifacts and
+	   docker-compose.yaml, keeping what exists, see hlf artifacts generate
+	2. create the docker network, the volumes and the containers of the nodes
+	3. wait for the nodes to be ready, see hlf wait, and for the etcdraft
+	   consenters to elect a leader
+	4. create the channels, join the peers of their organizations and update
+	   the anchor peers, with the CLI container
+	5. install the chaincodes on the peers of their channels and instantiate
+	   them, or approve and commit their definitions from fabric 2.0
+
+Every step is skipped when it is done already, so up can be run again after a
+failure or a change to the spec: existing containers are reused and started,
+existing channels are not created again, joined peers are not joined again and
+deployed chaincodes are not deployed again.
+Run hlf network down to recreate the containers.
+
+--watch keeps watching the network once it is up and reports the peers, orderers
+and chaincode containers that die, restarting them according to --restart, see hlf watch.
+
+--sample brings up a network of fabric-samples for the configured fabric version
+instead of the spec, without the samples repository or its scripts. Its spec and
+chaincode are written to the state directory of hlf, see hlf config:
+
+` + samplesHelp() + `
+
+	hlf network up
+	hlf network up --watch --restart on-failure
+	hlf network up --sample first-network
+	hlf network down --sample first-network`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		policy, err := restartPolicy(networkUpRestart, networkUpMaxRestarts)
+		if err != nil {
+			errorExit(err)
+		}
+
+		file := specFile
+		if networkUpSample != "" {
+			if file, err = writeSample(networkUpSample); err != nil {
+				errorExit(err)
+			}
+		}
+
+		network, err := loadNetworkSpec(file)
+		if err != nil {
+			errorExit(err)
+		}
+
+		crypto, err := cryptogenTool()
+		if err != nil {
+			errorExit(err)
+		}
+		configtxTool, err := configtxgenTool(network)
+		if err != nil {
+			errorExit(err)
+		}
+
+		options, err := composeOptions(network)
+		if err != nil {
+			errorExit(err)
+		}
+
+		dockerClient, err := newDockerClient()
+		if err != nil {
+			errorExit(err)
+		}
+
+		ctx, cancel := interruptContext()
+		defer cancel()
+
+		if err := networkUp(ctx, dockerClient, network, crypto, configtxTool, options, networkUpTimeout, os.Stdout); err != nil {
+			errorExit(err)
+		}
+
+		if networkUpWatch {
+			color.Blue("Watching containers, press ctrl+c to stop")
+			if err := watch(ctx, dockerClient, network.Name, policy, os.Stdout); err != nil {
+				errorExit(err)
+			}
+		}
+	},
+}
+
+func init() {
+	networkCmd.AddCommand(networkUpCmd)
+
+	networkUpCmd.Flags().DurationVar(&networkUpTimeout, "timeout", docker.DefaultReadyTimeout, "How long each node is given to become ready")
+	networkUpCmd.Flags().StringVar(&networkUpSample, "sample", "", "Bring up a sample network instead of the spec: "+strings.Join(samples.Names(), ", "))
+	networkUpCmd.Flags().BoolVar(&networkUpWatch, "watch", false, "Watch the containers once the network is up and report those that die")
+	networkUpCmd.Flags().StringVar(&networkUpRestart, "restart", docker.RestartNever, "Restart policy of --watch: never, on-failure or always")
+	networkUpCmd.Flags().IntVar(&networkUpMaxRestarts, "max-restarts", 3, "Maximum number of restarts of a container with --watch, 0 is unlimited")
+}
+
+func samplesHelp() string {
+	var lines []string
+	for _, name := range samples.Names() {
+		sample, _ := samples.Get(name)
+		lines = append(lines, fmt.Sprintf("\t%-15s%s", name, sample.Description))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// sampleDir returns the directory a sample network is written to.
+func sampleDir(name string) (string, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(cfg.StateDir(), "samples", name), nil
+}
+
+// writeSample writes the spec and chaincode of a sample network for the
+// configured fabric version and returns the spec file. The directory is
+// recorded in the install manifest, hlf uninstall --samples removes it.
+func writeSample(name string) (string, error) {
+	sample, err := samples.Get(name)
+	if err != nil {
+		return "", err
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return "", err
+	}
+	if err := sample.Supports(cfg.FabricVersion); err != nil {
+		return "", fmt.Errorf("%s, select the fabric version with --fabric-version", err.Error())
+	}
+
+	dir, err := sampleDir(name)
+	if err != nil {
+		return "", err
+	}
+
+	file, err := sample.Write(dir, cfg.FabricVersion)
+	if err != nil {
+		return "", err
+	}
+
+	err = recordInstall(func(m *installManifest) {
+		m.Samples = appendUnique(m.Samples, dir)
+	})
+	return file, err
+}
+
+func networkUp(ctx context.Context, dockerClient *docker.Client, network *spec.Network, crypto cryptogen.Tool, configtxTool configtxgen.Tool, options compose.Options, timeout time.Duration, out io.Writer) error {
+	if err := generateArtifacts(ctx, network, crypto, configtxTool, options, false, out); err != nil {
+		return err
+	}
+
+	project, err := compose.FromSpec(network, options)
+	if err != nil {
+		return err
+	}
+
+	// the record tells the resources of the network from those left behind by
+	// networks that are gone, see hlf gc, which keeps them while they are
+	// created
+	if err := recordNetwork(network, true); err != nil {
+		return err
+	}
+
+	if err := startNetwork(ctx, dockerClient, project, out); err != nil {
+		return err
+	}
+	if err := recordNetwork(network, false); err != nil {
+		return err
+	}
+
+	checks, err := dockerClient.ReadinessChecks(ctx, map[string]string{docker.LabelNetwork: network.Name})
+	if err != nil {
+		return err
+	}
+	for i := range checks {
+		checks[i].Timeout = timeout
+	}
+	color.New(color.FgBlue).Fprintf(out, "Waiting for %d nodes to be ready\n", len(checks))
+	if err := dockerClient.WaitReady(ctx, checks); err != nil {
+		return err
+	}
+
+	// orderers using the channel participation API have no system channel
+	if network.Orderer.Type == spec.OrdererEtcdRaft && !configtx.ChannelParticipation(network) {
+		if err := waitRaftLeader(ctx, dockerClient, network, configtxgen.SystemChannel, timeout, out); err != nil {
+			return err
+		}
+	}
+
+	if err := setupChannels(ctx, dockerClient, network, timeout, out); err != nil {
+		return err
+	}
+
+	if err := deployChaincodes(ctx, dockerClient, network, out); err != nil {
+		return err
+	}
+
+	color.New(color.FgGreen).Fprintf(out, "Network %s is up\n", network.Name)
+	printConnectionDetails(network, project, out)
+	return nil
+}
+
+// startNetwork creates the docker network, the volumes and the containers of
+// a project and starts them, what exists is reused.
+func startNetwork(ctx context.Context, dockerClient *docker.Client, project *compose.Project, out io.Writer) error {
+	if _, err := dockerClient.CreateNetwork(ctx, project.Name, project.Network); err != nil {
+		return err
+	}
+
+	for _, volume := range project.Volumes {
+		if _, err := dockerClient.CreateVolume(ctx, volume.Node, volume.Name); err != nil {
+			return err
+		}
+	}
+
+	for _, container := range project.Containers {
+		created, err := dockerClient.RunContainer(ctx, container)
+		if err != nil {
+			return err
+		}
+		if created {
+			fmt.Fprintf(out, "Created %s\n", container.Node.Name)
+		} else {
+			fmt.Fprintf(out, "Reusing %s\n", container.Node.Name)
+		}
+	}
+	return nil
+}
+
+// setupChannels creates the channels of a network, joins the peers of their
+// organizations and updates their anchor peers. The steps that are done
+// already are skipped, a raft leader of a new channel is waited for timeout.
+func setupChannels(ctx context.Context, dockerClient *docker.Client, network *spec.Network, timeout time.Duration, out io.Writer) error {
+	cli := compose.CLIName(network)
+	for _, channel := range network.Channels {
+		orgs := channelOrganizations(network, channel.Name)
+		if len(orgs) == 0 {
+			continue
+		}
+
+		if err := createChannel(ctx, dockerClient, cli, network, orgs[0], channel.Name, out); err != nil {
+			return err
+		}
+		if network.Orderer.Type == spec.OrdererEtcdRaft {
+			if err := waitRaftLeader(ctx, dockerClient, network, channel.Name, timeout, out); err != nil {
+				return err
+			}
+		}
+
+		for _, org := range orgs {
+			for i := 0; i < org.Peers; i++ {
+				if err := joinChannel(ctx, dockerClient, cli, network, org, i, channel.Name, out); err != nil {
+					return err
+				}
+			}
+		}
+
+		for _, org := range orgs {
+			if len(org.AnchorPeers) == 0 {
+				continue
+			}
+			if err := updateAnchorPeers(ctx, dockerClient, cli, network, org, channel.Name, out); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// raftPollInterval is how often the orderer logs are read while waiting for a
+// raft leader.
+var raftPollInterval = 500 * time.Millisecond
+
+// waitRaftLeader waits until every etcdraft consenter of a channel follows the
+// same leader, the orderers only order transactions of a channel once it has one.
+func waitRaftLeader(ctx context.Context, dockerClient *docker.Client, network *spec.Network, channel string, timeout time.Duration, out io.Writer) error {
+	consenters := len(network.OrdererNodes())
+	deadline := time.Now().Add(timeout)
+	for {
+		statuses, err := dockerClient.RaftStatuses(ctx, map[string]string{docker.LabelNetwork: network.Name})
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			if status.Channel == channel && status.Leader != "" && len(status.Following) == consenters {
+				fmt.Fprintf(out, "Raft leader of %s is %s, followed by %d consenters\n", channel, status.Leader, consenters)
+				return nil
+			}
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("error: the %d consenters did not elect a raft leader of %s in %s, see hlf logs", consenters, channel, timeout)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(raftPollInterval):
+		}
+	}
+}
+
+// channelOrganizations returns the member organizations of a channel.
+func channelOrganizations(network *spec.Network, channel string) []*spec.Organization {
+	var orgs []*spec.Organization
+	for _, c := range network.Channels {
+		if c.Name != channel {
+			continue
+		}
+		for _, name := range c.Organizations {
+			if org, ok := network.Organization(name); ok {
+				orgs = append(orgs, org)
+			}
+		}
+	}
+	return orgs
+}
+
+// createChannel creates a channel as the admin of an organization, the genesis
+// block of the channel is written to the working directory of the CLI
+// container. The block of an existing channel is fetched from the orderer.
+// The orderers of networks using the channel participation API join the
+// channel from its genesis block in the channel artifacts instead.
+func createChannel(ctx context.Context, dockerClient *docker.Client, cli string, network *spec.Network, org *spec.Organization, channel string, out io.Writer) error {
+	if configtx.ChannelParticipation(network) {
+		return joinOrderers(ctx, dockerClient, cli, network, channel, out)
+	}
+
+	env := compose.PeerEnv(network, org, 0)
+	block := configtxgen.ChannelBlockFile(channel)
+
+	fetch := append([]string{"channel", "fetch", "oldest", block, "-c", channel}, compose.OrdererArgs(network, org)...)
+	if _, err := peerCommand(ctx, dockerClient, cli, env, fetch...); err == nil {
+		fmt.Fprintf(out, "Channel %s exists\n", channel)
+		return nil
+	}
+
+	color.New(color.FgBlue).Fprintf(out, "Creating channel %s\n", channel)
+	create := append([]string{"channel", "create", "-c", channel, "-f", compose.ChannelArtifact(configtxgen.ChannelTxFile(channel))}, compose.OrdererArgs(network, org)...)
+	_, err := peerCommand(ctx, dockerClient, cli, env, create...)
+	return err
+}
+
+// joinOrderers joins the orderers of a network to a channel with osnadmin,
+// unless they have joined it already.
+func joinOrderers(ctx context.Context, dockerClient *docker.Client, cli string, network *spec.Network, channel string, out io.Writer) error {
+	block := compose.ChannelArtifact(configtxgen.ChannelBlockFile(channel))
+	for _, orderer := range network.OrdererNodes() {
+		args := compose.OrdererAdminArgs(network, orderer)
+
+		status, body, err := osnadminCommand(ctx, dockerClient, cli, append([]string{"channel", "list", "--channelID", channel}, args...)...)
+		if err != nil {
+			return err
+		}
+		if status == http.StatusOK {
+			fmt.Fprintf(out, "%s has joined %s\n", orderer.Host(), channel)
+			continue
+		}
+		if status != http.StatusNotFound {
+			return fmt.Errorf("error listing the channels of %s: status %d: %s", orderer.Host(), status, body)
+		}
+
+		color.New(color.FgBlue).Fprintf(out, "Joining %s to %s\n", orderer.Host(), channel)
+		status, body, err = osnadminCommand(ctx, dockerClient, cli, append([]string{"channel", "join", "--channelID", channel, "--config-block", block}, args...)...)
+		if err != nil {
+			return err
+		}
+		if status != http.StatusCreated {
+			return fmt.Errorf("error joining %s to %s: status %d: %s", orderer.Host(), channel, status, body)
+		}
+	}
+	return nil
+}
+
+// channelBlock returns the genesis block peers join a channel with in the CLI
+// container, the channel artifact of networks using the channel participation
+// API and the block createChannel wrote otherwise.
+func channelBlock(network *spec.Network, channel string) string {
+	if configtx.ChannelParticipation(network) {
+		return compose.ChannelArtifact(configtxgen.ChannelBlockFile(channel))
+	}
+	return configtxgen.ChannelBlockFile(channel)
+}
+
+// joinChannel joins a peer to a channel unless it has joined it already.
+func joinChannel(ctx context.Context, dockerClient *docker.Client, cli string, network *spec.Network, org *spec.Organization, peer int, channel string, out io.Writer) error {
+	env := compose.PeerEnv(network, org, peer)
+	host := org.PeerHost(peer)
+
+	output, err := peerCommand(ctx, dockerClient, cli, env, "channel", "list")
+	if err != nil {
+		return err
+	}
+	if contains(joinedChannels(output), channel) {
+		fmt.Fprintf(out, "%s has joined %s\n", host, channel)
+		return nil
+	}
+
+	color.New(color.FgBlue).Fprintf(out, "Joining %s to %s\n", host, channel)
+	_, err = peerCommand(ctx, dockerClient, cli, env, "channel", "join", "-b", channelBlock(network, channel))
+	return err
+}
+
+// updateAnchorPeers sends the anchor peers update of an organization. The
+// orderer rejects an update that was applied already, it is not an error.
+func updateAnchorPeers(ctx context.Context, dockerClient *docker.Client, cli string, network *spec.Network, org *spec.Organization, channel string, out io.Writer) error {
+	update := append([]string{"channel", "update", "-c", channel, "-f", compose.ChannelArtifact(configtxgen.AnchorPeersFile(channel, org.MSPID))}, compose.OrdererArgs(network, org)...)
+	output, err := peerCommand(ctx, dockerClient, cli, compose.PeerEnv(network, org, 0), update...)
+	if err != nil && strings.Contains(output, "but it is currently at version") {
+		fmt.Fprintf(out, "Anchor peers of %s on %s are up to date\n", org.Name, channel)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "Updated the anchor peers of %s on %s\n", org.Name, channel)
+	return nil
+}
+
+// peerCommand runs a peer command in the CLI container and returns its output,
+// the last line of the output is reported when it fails.
+func peerCommand(ctx context.Context, dockerClient *docker.Client, cli string, env []string, args ...string) (string, error) {
+	result, err := dockerClient.Exec(ctx, cli, append([]string{"peer"}, args...), env...)
+	if err != nil {
+		return "", err
+	}
+
+	output := result.Stdout + result.Stderr
+	if result.ExitCode != 0 {
+		lines := strings.Split(strings.TrimSpace(output), "\n")
+		return output, fmt.Errorf("error running peer %s %s: %s", args[0], args[1], lines[len(lines)-1])
+	}
+	return output, nil
+}
+
+// osnadminStatus matches the status osnadmin prints before the response body.
+var osnadminStatus = regexp.MustCompile(`^Status: (\d+)`)
+
+// osnadminCommand runs an osnadmin command in the CLI container and returns
+// the status and the body of the response. osnadmin exits successfully
+// whatever the status is, the caller checks it.
+func osnadminCommand(ctx context.Context, dockerClient *docker.Client, cli string, args ...string) (int, string, error) {
+	result, err := dockerClient.Exec(ctx, cli, append([]string{"osnadmin"}, args...))
+	if err != nil {
+		return 0, "", err
+	}
+
+	output := strings.TrimSpace(result.Stdout + result.Stderr)
+	match := osnadminStatus.FindStringSubmatch(output)
+	if result.ExitCode != 0 || match == nil {
+		lines := strings.Split(output, "\n")
+		return 0, output, fmt.Errorf("error running osnadmin %s %s: %s", args[0], args[1], lines[len(lines)-1])
+	}
+
+	status, _ := strconv.Atoi(match[1])
+	return status, strings.TrimSpace(strings.TrimPrefix(output, match[0])), nil
+}
+
+// printConnectionDetails prints the host endpoints of the nodes and where the
+// crypto material of clients is.
+func printConnectionDetails(network *spec.Network, project *compose.Project, out io.Writer) {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NODE\tROLE\tENDPOINT\tOPERATIONS")
+	for _, container := range project.Containers {
+		endpoint, operations := "-", "-"
+		if port := container.Labels[docker.LabelPort]; port != "" {
+			endpoint = "localhost:" + port
+		}
+		if port := container.Labels[docker.LabelOperationsPort]; port != "" {
+			operations = "localhost:" + port
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", container.Node.Name, container.Node.Role, endpoint, operations)
+	}
+	w.Flush()
+
+	fmt.Fprintf(out, "\nCrypto material: %s\n", filepath.Join(network.ArtifactsDir(), cryptogen.OutputDir))
+	fmt.Fprintf(out, "Channels: %s\n", strings.Join(channelNames(network), ", "))
+	fmt.Fprintf(out, "Peer commands can be run with docker exec %s peer ...\n", compose.CLIName(network))
+}
+
+func channelNames(network *spec.Network) []string {
+	var names []string
+	for _, channel := range network.Channels {
+		names = append(names, channel.Name)
+	}
+	return names
+}
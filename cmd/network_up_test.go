@@ -0,0 +1,285 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/gangachris/hlf/compose"
+	"github.com/gangachris/hlf/docker"
+	"github.com/gangachris/hlf/docker/dockertest"
+	"github.com/gangachris/hlf/spec"
+)
+
+const twoOrgsSpec = `version: 1
+name: dev
+fabric: 1.4.4
+tls:
+  enabled: true
+organizations:
+  - name: Org1
+  - name: Org2
+    stateDatabase: couchdb
+channels:
+  - name: mychannel
+`
+
+func Test_networkUpDown(t *testing.T) {
+	_, restoreHome := withHome(t)
+	defer restoreHome()
+
+	dir, err := ioutil.TempDir("", "hlf-network")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	network, err := spec.Parse(spec.FileName, []byte(twoOrgsSpec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	network.Dir = dir
+
+	project, err := compose.FromSpec(network, compose.Options{FabricTag: "1.4.4", CATag: "1.4.4", ThirdPartyTag: "0.4.18"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fake := dockertest.New()
+	for _, c := range project.Containers {
+		fake.AddImage(types.ImageSummary{RepoTags: []string{c.Image}})
+	}
+	dockerClient := docker.NewWithAPI(fake, docker.RuntimeDocker)
+	ctx := context.Background()
+
+	// containers are created once, then reused and started when they exited
+	if err := recordNetwork(network, false); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err := startNetwork(ctx, dockerClient, project, &out); err != nil {
+		t.Fatal(err)
+	}
+	if err := fake.Exit("peer0.org1.example.com", 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := startNetwork(ctx, dockerClient, project, &out); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(fake.CallsTo("ContainerCreate")); got != len(project.Containers) {
+		t.Errorf("ContainerCreate called %d times, want %d", got, len(project.Containers))
+	}
+	running, err := dockerClient.List(ctx, map[string]string{docker.LabelNetwork: "dev"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range running {
+		if c.State != "running" {
+			t.Errorf("%s is %s, want running", docker.ContainerName(c), c.State)
+		}
+	}
+
+	// a peer, the orderer, channels and anchor peers that remember their state
+	var commands []string
+	created, joined := false, false
+	updated := map[string]bool{}
+	fake.Exec = func(container string, cmd []string) (string, string, int) {
+		command := strings.Join(cmd[:3], " ")
+		commands = append(commands, command)
+		switch command {
+		case "peer channel fetch":
+			if !created {
+				return "", "Error: can't read the block: &{NOT_FOUND}", 1
+			}
+		case "peer channel create":
+			created = true
+		case "peer channel list":
+			if joined {
+				return "Channels peers has joined: \nmychannel\n", "", 0
+			}
+			return "Channels peers has joined: \n", "", 0
+		case "peer channel update":
+			file := cmd[6]
+			if updated[file] {
+				return "", "Error: got unexpected status: BAD_REQUEST -- error applying config update to existing channel 'mychannel': error authorizing update: error validating ReadSet: proposed update requires that key [Group]  /Channel/Application/Org1MSP be at version 0, but it is currently at version 1", 1
+			}
+			updated[file] = true
+		}
+		return "", "", 0
+	}
+
+	if err := setupChannels(ctx, dockerClient, network, docker.DefaultReadyTimeout, &out); err != nil {
+		t.Fatalf("setupChannels() error = %v", err)
+	}
+	want := []string{
+		"peer channel fetch", "peer channel create",
+		"peer channel list", "peer channel join",
+		"peer channel list", "peer channel join",
+		"peer channel update", "peer channel update",
+	}
+	if !reflect.DeepEqual(commands, want) {
+		t.Errorf("setupChannels() ran %v, want %v", commands, want)
+	}
+
+	// peers are joined with the admin of their organization
+	execs := fake.CallsTo("ContainerExecCreate")
+	join := execs[5].Args[1].(types.ExecConfig)
+	if !contains(join.Env, "CORE_PEER_ADDRESS=peer0.org2.example.com:7051") || !contains(join.Env, "CORE_PEER_LOCALMSPID=Org2MSP") {
+		t.Errorf("join env = %v, want the admin of Org2", join.Env)
+	}
+
+	// everything is done already
+	commands, joined = nil, true
+	if err := setupChannels(ctx, dockerClient, network, docker.DefaultReadyTimeout, &out); err != nil {
+		t.Fatalf("setupChannels() error = %v", err)
+	}
+	want = []string{"peer channel fetch", "peer channel list", "peer channel list", "peer channel update", "peer channel update"}
+	if !reflect.DeepEqual(commands, want) {
+		t.Errorf("setupChannels() ran %v, want %v", commands, want)
+	}
+
+	// volumes are kept unless asked for, or the artifacts are removed, down
+	// can run again
+	for _, down := range []struct{ volumes, artifacts bool }{{false, false}, {false, true}, {true, true}} {
+		if err := networkDown(ctx, dockerClient, network, down.volumes, down.artifacts, &out); err != nil {
+			t.Fatalf("networkDown() error = %v", err)
+		}
+		volumes := down.volumes || down.artifacts
+
+		remaining, err := dockerClient.Volumes(ctx, map[string]string{docker.LabelNetwork: "dev"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if volumes != (len(remaining) == 0) {
+			t.Errorf("networkDown(volumes %v) left %d volumes", volumes, len(remaining))
+		}
+
+		// the network is forgotten once nothing of it is left
+		known, _, err := knownNetworks()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if known["dev"] == volumes {
+			t.Errorf("networkDown(volumes %v) known networks = %v", volumes, known)
+		}
+	}
+	if containers, _ := dockerClient.List(ctx, map[string]string{docker.LabelNetwork: "dev"}); len(containers) != 0 {
+		t.Errorf("networkDown() left %d containers", len(containers))
+	}
+	if networks, _ := dockerClient.Networks(ctx, map[string]string{docker.LabelNetwork: "dev"}); len(networks) != 0 {
+		t.Errorf("networkDown() left the docker network")
+	}
+	if _, err := os.Stat(network.ArtifactsDir()); !os.IsNotExist(err) {
+		t.Errorf("networkDown() left the artifacts")
+	}
+}
+
+func Test_setupChannels_channelParticipation(t *testing.T) {
+	network, err := spec.Parse(spec.FileName, []byte(`version: 1
+name: dev
+fabric: 2.3.0
+tls:
+  enabled: true
+orderer:
+  type: etcdraft
+  organizations:
+    - name: Orderer
+      nodes: 2
+organizations:
+  - name: Org1
+channels:
+  - name: mychannel
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	project, err := compose.FromSpec(network, compose.Options{FabricTag: "2.3.0", CATag: "1.5.7", ThirdPartyTag: "0.4.18"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fake := dockertest.New()
+	for _, c := range project.Containers {
+		fake.AddImage(types.ImageSummary{RepoTags: []string{c.Image}})
+	}
+	dockerClient := docker.NewWithAPI(fake, docker.RuntimeDocker)
+	ctx := context.Background()
+	var out bytes.Buffer
+	if err := startNetwork(ctx, dockerClient, project, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	prefix := "2021-01-01 10:00:00.000 UTC [orderer.consensus.etcdraft] "
+	for i, orderer := range []string{"orderer0.example.com", "orderer1.example.com"} {
+		fake.Logs[orderer] = fmt.Sprintf("%sserveRequest -> INFO 001 Raft leader changed: 0 -> 1 channel=mychannel node=%d\n", prefix, i+1)
+	}
+
+	// orderer0 joined the channel before a previous run failed
+	var commands []string
+	joined := map[string]bool{"orderer0.example.com:7053": true}
+	var peerBlock string
+	fake.Exec = func(container string, cmd []string) (string, string, int) {
+		commands = append(commands, strings.Join(cmd[:3], " "))
+		var orderer string
+		for i, arg := range cmd {
+			if arg == "-o" {
+				orderer = cmd[i+1]
+			}
+			if arg == "-b" {
+				peerBlock = cmd[i+1]
+			}
+		}
+
+		switch strings.Join(cmd[:3], " ") {
+		case "osnadmin channel list":
+			if joined[orderer] {
+				return "Status: 200\n{\n\t\"name\": \"mychannel\"\n}\n", "", 0
+			}
+			return "Status: 404\n{\n\t\"error\": \"channel not found\"\n}\n", "", 0
+		case "osnadmin channel join":
+			joined[orderer] = true
+			return "Status: 201\n{\n\t\"name\": \"mychannel\"\n}\n", "", 0
+		case "peer channel list":
+			return "Channels peers has joined: \n", "", 0
+		}
+		return "", "", 0
+	}
+
+	if err := setupChannels(ctx, dockerClient, network, docker.DefaultReadyTimeout, &out); err != nil {
+		t.Fatalf("setupChannels() error = %v", err)
+	}
+	want := []string{
+		"osnadmin channel list", "osnadmin channel list", "osnadmin channel join",
+		"peer channel list", "peer channel join",
+		"peer channel update",
+	}
+	if !reflect.DeepEqual(commands, want) {
+		t.Errorf("setupChannels() ran %v, want %v", commands, want)
+	}
+	if !joined["orderer1.example.com:7053"] {
+		t.Errorf("orderer1 did not join mychannel")
+	}
+	if wantBlock := compose.ChannelArtifact("mychannel.block"); peerBlock != wantBlock {
+		t.Errorf("peers joined with %s, want %s", peerBlock, wantBlock)
+	}
+
+	// the orderers refuse a block that is not the genesis block of the channel
+	joined = map[string]bool{}
+	fake.Exec = func(container string, cmd []string) (string, string, int) {
+		if strings.Join(cmd[:3], " ") == "osnadmin channel join" {
+			return "Status: 400\n{\n\t\"error\": \"invalid join block\"\n}\n", "", 0
+		}
+		return "Status: 404\n", "", 0
+	}
+	err = setupChannels(ctx, dockerClient, network, docker.DefaultReadyTimeout, &out)
+	if err == nil || !strings.Contains(err.Error(), "invalid join block") {
+		t.Errorf("setupChannels() error = %v, want the error of osnadmin", err)
+	}
+}
@@ -21,9 +21,13 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 
+	"github.com/fatih/color"
+	"github.com/gangachris/hlf/config"
 	homedir "github.com/mitchellh/go-homedir"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -49,40 +53,100 @@ func Execute() {
 }
 
 func init() {
+	config.Setup(viper.GetViper())
 	cobra.OnInitialize(initConfig)
 
 	// Here you will define your flags and configuration settings.
 	// Cobra supports persistent flags, which, if defined here,
 	// will be global for your application.
-	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.hlf-cli/.hlf-cli.yaml)")
+	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is config.yaml in the hlf home)")
+
+	// flags overriding configuration keys, see hlf config --help
+	rootCmd.PersistentFlags().String(config.KeyHome, "", "Directory of binaries, cache, state and config, overrides HLF_HOME (default is $HOME/.hlf-cli)")
+	rootCmd.PersistentFlags().String(config.KeyFabricVersion, "", "Fabric version to use, overrides the config")
+	rootCmd.PersistentFlags().String(config.KeyFabricCAVersion, "", "Fabric CA version to use, overrides the config (default is the one released with the fabric version)")
+	rootCmd.PersistentFlags().String(config.KeyRegistry, "", "Registry images are pulled from, overrides the config")
+	rootCmd.PersistentFlags().Int(config.KeyParallelism, 0, "Number of images pulled at the same time, overrides the config")
+	rootCmd.PersistentFlags().StringP(config.KeyOutput, "o", "", "Output format: table or json, overrides the config")
+	rootCmd.PersistentFlags().String(config.KeyRuntime, "", "Container runtime: auto, docker, rootless-docker or podman, overrides the config")
+	for _, key := range config.Keys {
+		if flag := rootCmd.PersistentFlags().Lookup(key.Name); flag != nil {
+			viper.BindPFlag(key.Name, flag)
+		}
+	}
 
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
 	rootCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 }
 
-// initConfig reads in config file and ENV variables if set.
+// initConfig reads in config file and HLF_* environment variables.
 func initConfig() {
-	if cfgFile != "" {
-		// Use config file from the flag.
-		viper.SetConfigFile(cfgFile)
-	} else {
-		// Find home directory.
-		home, err := homedir.Dir()
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
+	// the config file is in the hlf home, so the home is only read from
+	// --home and HLF_HOME, before the config file
+	home := viper.Get(config.KeyHome)
+
+	configFile := cfgFile
+	if configFile == "" {
+		var err error
+		if configFile, err = defaultConfigFile(); err != nil {
+			errorExit(err)
 		}
+	}
+	viper.SetConfigFile(configFile)
 
-		// Search config in home directory with name ".hlf-cli" (without extension).
-		viper.AddConfigPath(home)
-		viper.SetConfigName(".hlf-cli")
+	// a broken config file is reported, hlf config can still fix it
+	if _, err := os.Stat(configFile); err == nil {
+		if err := viper.ReadInConfig(); err != nil {
+			color.New(color.FgYellow).Fprintf(os.Stderr, "error reading config file %s: %s\n", configFile, err.Error())
+		}
 	}
 
-	viper.AutomaticEnv() // read in environment variables that match
+	viper.Set(config.KeyHome, home)
+}
+
+// defaultConfigFile returns the config file in the hlf home directory, or the
+// one in the home directory hlf used before when only that one exists and the
+// hlf home is the default one.
+func defaultConfigFile() (string, error) {
+	key, _ := config.Lookup(config.KeyHome)
+	value, err := key.Parse(viper.Get(key.Name))
+	if err != nil {
+		return "", err
+	}
+
+	hlfHome, err := config.ExpandHome(value.(string))
+	if err != nil {
+		return "", err
+	}
 
-	// If a config file is found, read it in.
-	if err := viper.ReadInConfig(); err == nil {
-		fmt.Println("Using config file:", viper.ConfigFileUsed())
+	configFile := filepath.Join(hlfHome, config.FileName)
+	flag := rootCmd.PersistentFlags().Lookup(key.Name)
+	if config.Source(viper.GetViper(), key, flag.Changed) != "default" {
+		return configFile, nil
 	}
+
+	home, err := homedir.Dir()
+	if err != nil {
+		return "", err
+	}
+
+	if home == "" {
+		return "", errors.New("error: could not find the home directory")
+	}
+
+	legacyConfigFile := filepath.Join(home, ".hlf-cli.yaml")
+	if _, err := os.Stat(configFile); os.IsNotExist(err) {
+		if _, err := os.Stat(legacyConfigFile); err == nil {
+			return legacyConfigFile, nil
+		}
+	}
+
+	return configFile, nil
+}
+
+// loadConfig returns the configuration from flags, environment variables, the
+// config file and defaults.
+func loadConfig() (*config.Config, error) {
+	return config.Load(viper.GetViper())
 }
@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/fatih/color"
+	"github.com/gangachris/hlf/spec"
+	"github.com/spf13/cobra"
+)
+
+// specFile is the network spec read by network commands.
+var specFile string
+
+// specCmd represents the spec command
+var specCmd = &cobra.Command{
+	Use:   "spec",
+	Short: "Check and show the network spec, hlf.yaml",
+	Long: `hlf.yaml describes a network: its organizations and peers, the orderer type and nodes,
+CAs, the state database, channels and their member organizations, chaincodes, TLS and
+the first host port of the nodes. Every network command reads this one file.
+
+	version: 1
+	name: dev
+	fabric: 1.1.0          # the configured fabric-version when not set
+	domain: example.com
+	tls:
+	  enabled: true
+	portBase: 7050
+	stateDatabase: leveldb # or couchdb
+	orderer:
+	  type: solo           # kafka or etcdraft, which needs TLS
+	  organizations:
+	    - name: Orderer
+	      nodes: 1
+	organizations:
+	  - name: Org1         # domain org1.example.com, MSP ID Org1MSP
+	    peers: 2
+	    ca: true
+	  - name: Org2
+	    stateDatabase: couchdb
+	channels:
+	  - name: mychannel
+	    organizations: [Org1, Org2]
+	chaincodes:
+	  - name: mycc
+	    path: ./chaincode/mycc
+	    channels: [mychannel]
+	    init: [init, a, "100"] # instantiated or initialized with these arguments`,
+}
+
+// specValidateCmd validates the network spec
+var specValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Validate the network spec and report every problem with its line and column",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		network, err := loadNetworkSpec(specFile)
+		if err != nil {
+			errorExit(err)
+		}
+		color.Green("%s is valid: %s", specFile, describeNetwork(network))
+	},
+}
+
+// specShowCmd prints the network spec with its defaults
+var specShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Print the network spec with the defaults of the fields that are not set",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := showNetworkSpec(specFile, os.Stdout); err != nil {
+			errorExit(err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(specCmd)
+	specCmd.AddCommand(specValidateCmd)
+	specCmd.AddCommand(specShowCmd)
+
+	specCmd.PersistentFlags().StringVarP(&specFile, "file", "f", spec.FileName, "Network spec file")
+}
+
+// loadNetworkSpec reads a network spec, fabric is set to the configured fabric
+// version when the spec does not pin one.
+func loadNetworkSpec(file string) (*spec.Network, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	return spec.LoadWithFabric(file, cfg.FabricVersion)
+}
+
+// describeNetwork summarizes a network e.g network dev: 2 organizations, 3 peers...
+func describeNetwork(network *spec.Network) string {
+	peers := 0
+	for _, org := range network.Organizations {
+		peers += org.Peers
+	}
+
+	return fmt.Sprintf("network %s, fabric %s, %d organizations, %d peers, %d %s orderers, %d channels, %d chaincodes",
+		network.Name,
+		network.Fabric,
+		len(network.Organizations),
+		peers,
+		len(network.OrdererNodes()),
+		network.Orderer.Type,
+		len(network.Channels),
+		len(network.Chaincodes),
+	)
+}
+
+func showNetworkSpec(file string, out io.Writer) error {
+	network, err := loadNetworkSpec(file)
+	if err != nil {
+		return err
+	}
+
+	data, err := spec.Marshal(network)
+	if err != nil {
+		return err
+	}
+
+	_, err = out.Write(data)
+	return err
+}
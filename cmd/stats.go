@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/docker/go-units"
+	"github.com/gangachris/hlf/config"
+	"github.com/gangachris/hlf/docker"
+	"github.com/mattn/go-isatty"
+	"github.com/spf13/cobra"
+)
+
+var (
+	statsNetwork  string
+	statsNoStream bool
+	statsInterval time.Duration
+)
+
+// statsCmd shows the resource usage of the nodes of a network
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show the CPU, memory, network and block I/O usage of the nodes of a network",
+	Long: `Show the resource usage of the peers, orderers, CAs, CouchDBs and chaincode containers
+of a network. On a terminal the table is refreshed in place, otherwise, or when the
+output is json, every sample is written as a JSON line so it can be recorded e.g on CI runners:
+
+	hlf stats -n dev
+	hlf stats -n dev > stats.jsonl`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, err := loadConfig()
+		if err != nil {
+			errorExit(err)
+		}
+
+		network := statsNetwork
+		if network == "" {
+			network = cfg.Network
+		}
+
+		dockerClient, err := newDockerClient()
+		if err != nil {
+			errorExit(err)
+		}
+
+		ctx, cancel := interruptContext()
+		defer cancel()
+
+		tty := isatty.IsTerminal(os.Stdout.Fd()) && cfg.Output != config.OutputJSON
+		if err := stats(ctx, dockerClient, network, tty, statsNoStream, statsInterval, os.Stdout); err != nil {
+			errorExit(err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(statsCmd)
+
+	statsCmd.Flags().StringVarP(&statsNetwork, "network", "n", "", "Name of the hlf network, the configured network by default")
+	statsCmd.Flags().BoolVar(&statsNoStream, "no-stream", false, "Show a single sample and exit")
+	statsCmd.Flags().DurationVar(&statsInterval, "interval", 2*time.Second, "Time between samples")
+}
+
+// clearScreen moves the cursor home and clears the terminal.
+const clearScreen = "\033[H\033[2J"
+
+// stats writes samples of the resource usage of a network until ctx is done, as
+// a table refreshed in place on a terminal and as JSON lines otherwise.
+func stats(ctx context.Context, dockerClient *docker.Client, network string, tty, noStream bool, interval time.Duration, out io.Writer) error {
+	for {
+		samples, err := dockerClient.Stats(ctx, map[string]string{docker.LabelNetwork: network})
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		if tty {
+			if !noStream {
+				fmt.Fprint(out, clearScreen)
+			}
+			if err := writeStatsTable(out, samples); err != nil {
+				return err
+			}
+		} else if err := writeStatsJSON(out, samples); err != nil {
+			return err
+		}
+
+		if noStream {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(interval):
+		}
+	}
+}
+
+func writeStatsTable(out io.Writer, samples []docker.NodeStats) error {
+	if len(samples) == 0 {
+		_, err := fmt.Fprintln(out, "No running nodes, is the network up?")
+		return err
+	}
+
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NODE\tROLE\tCPU %\tMEM USAGE / LIMIT\tMEM %\tNET I/O\tBLOCK I/O\tPIDS")
+	for _, sample := range samples {
+		fmt.Fprintf(w, "%s\t%s\t%.2f%%\t%s / %s\t%.2f%%\t%s / %s\t%s / %s\t%d\n",
+			sample.Container,
+			sample.Role,
+			sample.CPUPercent,
+			units.BytesSize(float64(sample.MemoryUsage)), units.BytesSize(float64(sample.MemoryLimit)),
+			sample.MemoryPercent,
+			units.HumanSize(float64(sample.NetworkRx)), units.HumanSize(float64(sample.NetworkTx)),
+			units.HumanSize(float64(sample.BlockRead)), units.HumanSize(float64(sample.BlockWrite)),
+			sample.PIDs,
+		)
+	}
+	return w.Flush()
+}
+
+func writeStatsJSON(out io.Writer, samples []docker.NodeStats) error {
+	encoder := json.NewEncoder(out)
+	for _, sample := range samples {
+		if err := encoder.Encode(sample); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,263 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/gangachris/hlf/compose"
+	"github.com/gangachris/hlf/docker"
+	"github.com/spf13/cobra"
+)
+
+// uninstallOptions selects what hlf uninstall removes.
+type uninstallOptions struct {
+	binaries bool
+	images   bool
+	samples  bool
+	networks bool
+
+	// yes answers yes to every confirmation prompt.
+	yes bool
+}
+
+var (
+	uninstallFlags uninstallOptions
+	uninstallAll   bool
+)
+
+// uninstallCmd removes what hlf installed
+var uninstallCmd = &cobra.Command{
+	Use:   "uninstall",
+	Short: "Remove the binaries, images, samples and networks hlf installed",
+	Long: `Remove what hlf installed, as recorded by hlf download, and nothing else:
+
+	--binaries   platform binaries extracted in the hlf directory
+	--images     docker images pulled and tagged by hlf download
+	--samples    downloaded samples
+	--networks   containers, docker networks and volumes of hlf networks
+	--all        all of the above
+
+Every step asks for confirmation, use --yes in scripts.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		options := uninstallFlags
+		if uninstallAll {
+			options.binaries, options.images, options.samples, options.networks = true, true, true, true
+		}
+
+		if !options.binaries && !options.images && !options.samples && !options.networks {
+			errorExit(errors.New("error: nothing to uninstall, use --binaries, --images, --samples, --networks or --all"))
+		}
+
+		if err := uninstall(options, os.Stdin, os.Stdout); err != nil {
+			errorExit(err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(uninstallCmd)
+
+	uninstallCmd.Flags().BoolVar(&uninstallFlags.binaries, "binaries", false, "Remove the platform binaries")
+	uninstallCmd.Flags().BoolVar(&uninstallFlags.images, "images", false, "Remove the docker images")
+	uninstallCmd.Flags().BoolVar(&uninstallFlags.samples, "samples", false, "Remove the samples")
+	uninstallCmd.Flags().BoolVar(&uninstallFlags.networks, "networks", false, "Remove the containers, networks and volumes of hlf networks")
+	uninstallCmd.Flags().BoolVar(&uninstallAll, "all", false, "Remove everything hlf installed")
+	uninstallCmd.Flags().BoolVarP(&uninstallFlags.yes, "yes", "y", false, "Do not ask for confirmation")
+}
+
+func uninstall(options uninstallOptions, in io.Reader, out io.Writer) error {
+	manifest, err := loadInstallManifest()
+	if err != nil {
+		return err
+	}
+
+	answers := bufio.NewReader(in)
+	ask := func(question string, items []string) (bool, error) {
+		for _, item := range items {
+			fmt.Fprintf(out, "\t%s\n", item)
+		}
+		if options.yes {
+			return true, nil
+		}
+		return confirm(answers, out, question)
+	}
+
+	// networks go first, their containers use the images
+	if options.networks {
+		if err := uninstallNetworks(ask, out); err != nil {
+			return err
+		}
+	}
+
+	if options.binaries && len(manifest.Binaries) > 0 {
+		ok, err := ask(fmt.Sprintf("Remove %d platform binaries files and directories?", len(manifest.Binaries)), manifest.Binaries)
+		if err != nil {
+			return err
+		}
+		if ok {
+			manifest.Binaries = removePaths(manifest.Binaries, out)
+			if err := manifest.save(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if options.samples && len(manifest.Samples) > 0 {
+		ok, err := ask(fmt.Sprintf("Remove %d samples files and directories?", len(manifest.Samples)), manifest.Samples)
+		if err != nil {
+			return err
+		}
+		if ok {
+			manifest.Samples = removePaths(manifest.Samples, out)
+			if err := manifest.save(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if options.images && len(manifest.Images) > 0 {
+		ok, err := ask(fmt.Sprintf("Remove %d docker images?", len(manifest.Images)), manifest.Images)
+		if err != nil {
+			return err
+		}
+		if ok {
+			left, removeErr := removeImages(manifest.Images)
+			manifest.Images = left
+			if err := manifest.save(); err != nil {
+				return err
+			}
+			if removeErr != nil {
+				return removeErr
+			}
+		}
+	}
+
+	color.New(color.FgGreen).Fprintln(out, "Uninstall complete")
+	return nil
+}
+
+// removePaths removes files and then the directories containing them, directories
+// holding files hlf did not create are kept. It returns the paths left.
+func removePaths(paths []string, out io.Writer) []string {
+	sorted := append([]string{}, paths...)
+	sort.Sort(sort.Reverse(sort.StringSlice(sorted)))
+
+	var left []string
+	for _, path := range sorted {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			color.New(color.FgYellow).Fprintf(out, "kept %s: %s\n", path, err.Error())
+			left = append(left, path)
+		}
+	}
+
+	sort.Strings(left)
+	return left
+}
+
+// removeImages removes image references and returns the ones left.
+func removeImages(refs []string) ([]string, error) {
+	dockerClient, err := newDockerClient()
+	if err != nil {
+		return refs, err
+	}
+
+	ctx := context.Background()
+	for i, ref := range refs {
+		sep := strings.LastIndex(ref, ":")
+		if sep == -1 {
+			return refs[i:], fmt.Errorf("error: invalid image reference %s in the install manifest", ref)
+		}
+
+		image := docker.Image{Repository: ref[:sep], Tag: ref[sep+1:]}
+		if err := dockerClient.RemoveImageTag(ctx, image); err != nil {
+			return refs[i:], err
+		}
+	}
+
+	return nil, nil
+}
+
+// uninstallNetworks removes the containers, chaincodes, docker networks and
+// volumes of every hlf network.
+func uninstallNetworks(ask func(question string, items []string) (bool, error), out io.Writer) error {
+	dockerClient, err := newDockerClient()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	hlfResources := map[string]string{docker.LabelNetwork: ""}
+
+	containers, err := dockerClient.List(ctx, hlfResources)
+	if err != nil {
+		return err
+	}
+
+	networks, err := dockerClient.Networks(ctx, hlfResources)
+	if err != nil {
+		return err
+	}
+
+	volumes, err := dockerClient.Volumes(ctx, hlfResources)
+	if err != nil {
+		return err
+	}
+
+	var items []string
+	peers := map[string][]string{}
+	for _, cont := range containers {
+		name := docker.ContainerName(cont)
+		items = append(items, "container "+name)
+		if node := docker.NodeFromLabels(cont.Labels); node.Role == docker.RolePeer {
+			network := compose.NetworkName(node.Network)
+			peers[network] = append(peers[network], name)
+		}
+	}
+	for _, network := range networks {
+		items = append(items, "network   "+network.Name)
+	}
+	for _, volume := range volumes {
+		items = append(items, "volume    "+volume.Name)
+	}
+
+	if len(items) == 0 {
+		return nil
+	}
+
+	ok, err := ask("Remove the containers, networks and volumes of hlf networks? Ledger data is lost", items)
+	if err != nil || !ok {
+		return err
+	}
+
+	if len(peers) > 0 {
+		if err := cleanChaincodes(ctx, dockerClient, docker.ChaincodeFilter{Peers: peers}, false, true, out); err != nil {
+			return err
+		}
+	}
+
+	if _, err := dockerClient.RemoveContainers(ctx, hlfResources); err != nil {
+		return err
+	}
+
+	for _, network := range networks {
+		if err := dockerClient.RemoveNetwork(ctx, network.Labels[docker.LabelNetwork], network.Name); err != nil {
+			return fmt.Errorf("error removing network %s: %s", network.Name, err.Error())
+		}
+	}
+
+	for _, volume := range volumes {
+		if err := dockerClient.RemoveVolume(ctx, volume.Labels[docker.LabelNetwork], volume.Name); err != nil {
+			return fmt.Errorf("error removing volume %s: %s", volume.Name, err.Error())
+		}
+	}
+
+	return nil
+}
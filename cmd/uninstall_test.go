@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/gangachris/hlf/docker"
+	"github.com/gangachris/hlf/docker/dockertest"
+)
+
+// withHome points HLF_HOME to a temporary directory until restore is called.
+func withHome(t *testing.T) (home string, restore func()) {
+	home, err := ioutil.TempDir("", "hlf-home")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	previous, set := os.LookupEnv("HLF_HOME")
+	os.Setenv("HLF_HOME", home)
+
+	return home, func() {
+		if set {
+			os.Setenv("HLF_HOME", previous)
+		} else {
+			os.Unsetenv("HLF_HOME")
+		}
+		os.RemoveAll(home)
+	}
+}
+
+// withDockerClient makes commands use a client of the fake until restore is called.
+func withDockerClient(fake *dockertest.Fake) (dockerClient *docker.Client, restore func()) {
+	dockerClient = docker.NewWithAPI(fake, docker.RuntimeDocker)
+
+	previous := newDockerClient
+	newDockerClient = func() (*docker.Client, error) { return dockerClient, nil }
+
+	return dockerClient, func() { newDockerClient = previous }
+}
+
+func Test_uninstall(t *testing.T) {
+	home, restoreHome := withHome(t)
+	defer restoreHome()
+
+	fake := dockertest.New()
+	dockerClient, restoreClient := withDockerClient(fake)
+	defer restoreClient()
+	ctx := context.Background()
+
+	// binaries hlf extracted next to a file the user added
+	bin := filepath.Join(home, "bin")
+	if err := os.MkdirAll(bin, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"peer", "configtxgen", "mytool"} {
+		if err := ioutil.WriteFile(filepath.Join(bin, name), nil, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	fake.AddImage(types.ImageSummary{RepoTags: []string{"hyperledger/fabric-peer:x86_64-1.1.0", "hyperledger/fabric-peer:latest"}})
+	fake.AddImage(types.ImageSummary{RepoTags: []string{"hyperledger/fabric-orderer:x86_64-1.1.0", "hyperledger/fabric-orderer:latest"}})
+
+	err := recordInstall(func(m *installManifest) {
+		m.Binaries = appendUnique(m.Binaries, bin, filepath.Join(bin, "peer"), filepath.Join(bin, "configtxgen"))
+		m.Images = appendUnique(m.Images, "hyperledger/fabric-peer:x86_64-1.1.0", "hyperledger/fabric-peer:latest")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	spec := docker.ContainerSpec{
+		Node:  docker.Node{Network: "dev", Role: docker.RolePeer, Name: "peer0"},
+		Image: "hyperledger/fabric-peer:x86_64-1.1.0",
+	}
+	if _, err := dockerClient.CreateContainer(ctx, spec); err != nil {
+		t.Fatal(err)
+	}
+
+	// networks and binaries are removed, images are kept
+	answers := strings.NewReader("y\nyes\nn\n")
+	options := uninstallOptions{binaries: true, images: true, networks: true}
+	if err := uninstall(options, answers, ioutil.Discard); err != nil {
+		t.Fatal(err)
+	}
+
+	if containers, _ := dockerClient.List(ctx, nil); len(containers) != 0 {
+		t.Errorf("containers left = %v", containers)
+	}
+
+	files, err := ioutil.ReadDir(bin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Name() != "mytool" {
+		t.Errorf("binaries left = %v, want [mytool]", files)
+	}
+
+	manifest, err := loadInstallManifest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &installManifest{Binaries: []string{bin}, Images: []string{"hyperledger/fabric-peer:latest", "hyperledger/fabric-peer:x86_64-1.1.0"}}
+	if !reflect.DeepEqual(manifest, want) {
+		t.Errorf("manifest = %+v, want %+v", manifest, want)
+	}
+
+	// only the images hlf pulled are removed
+	if err := uninstall(uninstallOptions{images: true, yes: true}, strings.NewReader(""), ioutil.Discard); err != nil {
+		t.Fatal(err)
+	}
+
+	wantImages := []string{"hyperledger/fabric-orderer:latest", "hyperledger/fabric-orderer:x86_64-1.1.0"}
+	if got := fake.ImageTags(); !reflect.DeepEqual(got, wantImages) {
+		t.Errorf("images left = %v, want %v", got, wantImages)
+	}
+}
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/gangachris/hlf/docker"
+	"github.com/spf13/cobra"
+)
+
+var (
+	waitNetwork string
+	waitTimeout time.Duration
+)
+
+// waitCmd waits for the nodes of a network to be ready
+var waitCmd = &cobra.Command{
+	Use:   "wait [node...]",
+	Short: "Wait for the nodes of a network to be ready",
+	Long: `Wait for peers, orderers, CAs and CouchDB to be ready instead of sleeping in scripts.
+A node is ready when its container is running, its port accepts connections and either
+its operations endpoint (/healthz) is healthy or, on older fabric versions, it has logged
+that it serves requests. The last log lines of nodes that do not become ready are shown.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		network, err := configuredNetwork(waitNetwork)
+		if err != nil {
+			errorExit(err)
+		}
+
+		if err := wait(network, args, waitTimeout); err != nil {
+			errorExit(err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(waitCmd)
+
+	waitCmd.Flags().StringVarP(&waitNetwork, "network", "n", "", "Name of the hlf network, the configured network by default")
+	waitCmd.Flags().DurationVar(&waitTimeout, "timeout", docker.DefaultReadyTimeout, "How long each node is given to become ready")
+}
+
+func wait(network string, nodes []string, timeout time.Duration) error {
+	dockerClient, err := newDockerClient()
+	if err != nil {
+		return err
+	}
+
+	labels := map[string]string{docker.LabelNetwork: network}
+	ctx := context.Background()
+	checks, err := dockerClient.ReadinessChecks(ctx, labels)
+	if err != nil {
+		return err
+	}
+
+	selected := checks[:0]
+	for _, check := range checks {
+		if len(nodes) == 0 || contains(nodes, check.Container) {
+			check.Timeout = timeout
+			selected = append(selected, check)
+		}
+	}
+
+	for _, node := range nodes {
+		found := false
+		for _, check := range selected {
+			found = found || check.Container == node
+		}
+		if !found {
+			return fmt.Errorf("error: node %s not found", node)
+		}
+	}
+
+	if len(selected) == 0 {
+		return fmt.Errorf("error: no containers found for network %s, is it up?", network)
+	}
+
+	color.Blue("Waiting for %d nodes to be ready", len(selected))
+	if err := dockerClient.WaitReady(ctx, selected); err != nil {
+		return err
+	}
+
+	color.Green("All nodes are ready")
+	return nil
+}
@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+
+	"github.com/fatih/color"
+	"github.com/gangachris/hlf/docker"
+	"github.com/spf13/cobra"
+)
+
+const watchLogLines = 10
+
+var (
+	watchNetwork     string
+	watchRestart     string
+	watchMaxRestarts int
+)
+
+// watchCmd reports nodes of a network that crash
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Report peers, orderers and chaincode containers that die",
+	Long: `Watch the containers of a network and report the ones that die or run out of memory,
+with their exit code and last log lines. Containers stopped on purpose (hlf, docker stop,
+a peer stopping a chaincode container) are not reported as crashes.
+
+With --restart, containers that die are restarted:
+
+	never        report only (default)
+	on-failure   restart containers exiting with a non zero code
+	always       restart containers whatever their exit code`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		policy, err := restartPolicy(watchRestart, watchMaxRestarts)
+		if err != nil {
+			errorExit(err)
+		}
+
+		network, err := configuredNetwork(watchNetwork)
+		if err != nil {
+			errorExit(err)
+		}
+
+		dockerClient, err := newDockerClient()
+		if err != nil {
+			errorExit(err)
+		}
+
+		ctx, cancel := interruptContext()
+		defer cancel()
+
+		color.Blue("Watching containers, press ctrl+c to stop")
+		if err := watch(ctx, dockerClient, network, policy, os.Stdout); err != nil {
+			errorExit(err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(watchCmd)
+
+	watchCmd.Flags().StringVarP(&watchNetwork, "network", "n", "", "Name of the hlf network, the configured network by default")
+	watchCmd.Flags().StringVar(&watchRestart, "restart", docker.RestartNever, "Restart policy: never, on-failure or always")
+	watchCmd.Flags().IntVar(&watchMaxRestarts, "max-restarts", 3, "Maximum number of restarts of a container, 0 is unlimited")
+}
+
+func restartPolicy(mode string, maxRestarts int) (docker.RestartPolicy, error) {
+	switch mode {
+	case docker.RestartNever, docker.RestartOnFailure, docker.RestartAlways:
+		return docker.RestartPolicy{Mode: mode, MaxRestarts: maxRestarts}, nil
+	}
+
+	return docker.RestartPolicy{}, fmt.Errorf("unknown restart policy %s, expected one of never, on-failure, always", mode)
+}
+
+// interruptContext returns a context cancelled on ctrl+c.
+func interruptContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	go func() {
+		defer signal.Stop(interrupt)
+		select {
+		case <-interrupt:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	return ctx, cancel
+}
+
+// watchedContainer is what the watcher knows about a container between events.
+type watchedContainer struct {
+	stopping bool
+	oom      bool
+	restarts int
+}
+
+// watch reports the containers of a network and the chaincode containers of its
+// peers that die until ctx is done and restarts them according to the policy.
+func watch(ctx context.Context, dockerClient *docker.Client, network string, policy docker.RestartPolicy, out io.Writer) error {
+	peers, err := networkPeers(ctx, dockerClient, network)
+	if err != nil {
+		return err
+	}
+
+	containers := map[string]*watchedContainer{}
+	return dockerClient.WatchContainers(ctx, map[string]string{docker.LabelNetwork: network}, peers, func(event docker.ContainerEvent) {
+		state, ok := containers[event.Container]
+		if !ok {
+			state = &watchedContainer{}
+			containers[event.Container] = state
+		}
+
+		switch event.Action {
+		case docker.EventKill, docker.EventStop:
+			state.stopping = true
+		case docker.EventOOM:
+			state.oom = true
+		case docker.EventStart:
+			state.stopping = false
+			state.oom = false
+		case docker.EventDestroy:
+			delete(containers, event.Container)
+		case docker.EventRestart:
+			color.New(color.FgYellow).Fprintf(out, "%s was restarted by docker\n", event.Container)
+		case docker.EventDie:
+			if state.stopping {
+				fmt.Fprintf(out, "%s stopped\n", event.Container)
+				return
+			}
+
+			reason := fmt.Sprintf("exit code %d", event.ExitCode)
+			if state.oom {
+				reason = "out of memory"
+			}
+			color.New(color.FgRed).Fprintf(out, "%s died at %s (%s)\n", event.Container, event.Time.Format("15:04:05"), reason)
+
+			for _, line := range dockerClient.LastLogLines(event.Container, watchLogLines) {
+				fmt.Fprintf(out, "\t%s\n", line)
+			}
+
+			if !policy.ShouldRestart(event.ExitCode, state.restarts) {
+				return
+			}
+
+			state.restarts++
+			color.New(color.FgYellow).Fprintf(out, "restarting %s (%d)\n", event.Container, state.restarts)
+			if err := dockerClient.Start(ctx, event.Container); err != nil {
+				color.New(color.FgRed).Fprintf(out, "error restarting %s: %s\n", event.Container, err.Error())
+			}
+		}
+	})
+}
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/gangachris/hlf/docker"
+	"github.com/gangachris/hlf/docker/dockertest"
+)
+
+// syncBuffer is a buffer safe to read while the watcher writes to it.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func Test_watch(t *testing.T) {
+	fake := dockertest.New()
+	fake.AddImage(types.ImageSummary{RepoTags: []string{"hyperledger/fabric-peer"}})
+	fake.Logs["peer0"] = "panic: runtime error\n"
+	dockerClient := docker.NewWithAPI(fake, docker.RuntimeDocker)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	for _, name := range []string{"peer0", "peer1"} {
+		spec := docker.ContainerSpec{
+			Node:  docker.Node{Network: "dev", Role: docker.RolePeer, Name: name},
+			Image: "hyperledger/fabric-peer",
+		}
+		if _, err := dockerClient.CreateContainer(ctx, spec); err != nil {
+			t.Fatal(err)
+		}
+		if err := dockerClient.Start(ctx, name); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// a chaincode container peer0 launched without hlf labels, and a container
+	// that only looks like one
+	for _, name := range []string{"dev-peer0-mycc-1.0", "dev-postgres"} {
+		if _, err := fake.ContainerCreate(ctx, &container.Config{Image: "hyperledger/fabric-peer"}, nil, nil, name); err != nil {
+			t.Fatal(err)
+		}
+		if err := dockerClient.Start(ctx, name); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var out syncBuffer
+	done := make(chan error)
+	go func() {
+		done <- watch(ctx, dockerClient, "dev", docker.RestartPolicy{Mode: docker.RestartOnFailure, MaxRestarts: 1}, &out)
+	}()
+
+	// wait for the watcher to subscribe
+	for len(fake.CallsTo("Events")) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	dockerClient.Stop(ctx, "peer1", time.Second)
+	fake.Exit("peer0", 2)
+	for len(fake.CallsTo("ContainerStart")) < 5 {
+		time.Sleep(time.Millisecond)
+	}
+	fake.Exit("peer0", 2)
+	fake.Exit("dev-postgres", 1)
+	fake.Exit("dev-peer0-mycc-1.0", 1)
+
+	// the second crash of peer0 is not restarted, the chaincode is
+	for len(fake.CallsTo("ContainerStart")) < 6 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+
+	if err := <-done; err != nil {
+		t.Fatalf("watch() error = %v", err)
+	}
+
+	got := out.String()
+	for _, want := range []string{"peer1 stopped", "(exit code 2)", "\tpanic: runtime error", "restarting peer0 (1)", "restarting dev-peer0-mycc-1.0 (1)"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("watch() output does not contain %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "dev-postgres") {
+		t.Errorf("watch() reported a container of no peer:\n%s", got)
+	}
+
+	if starts := len(fake.CallsTo("ContainerStart")); starts != 6 {
+		t.Errorf("watch() restarted containers %d times, want 2", starts-4)
+	}
+}
@@ -0,0 +1,116 @@
+package compose
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/gangachris/hlf/configtxgen"
+	"github.com/gangachris/hlf/msp"
+	"github.com/gangachris/hlf/spec"
+)
+
+// cliCryptoDir is the crypto material in the CLI container.
+const cliCryptoDir = cliWorkingDir + "/crypto"
+
+// ChannelArtifact returns the path of a channel artifact in the CLI container
+// e.g ChannelArtifact(configtxgen.ChannelTxFile("mychannel")).
+func ChannelArtifact(file string) string {
+	return cliWorkingDir + "/" + configtxgen.OutputDir + "/" + file
+}
+
+// ChaincodeDir returns the directory the source of a chaincode is mounted on
+// in the CLI container.
+func ChaincodeDir(name string) string {
+	return chaincodeDir + "/" + name
+}
+
+// ChaincodeImportPath returns the GOPATH import path of a golang chaincode in
+// the CLI container, its ChaincodeDir relative to /opt/gopath/src.
+func ChaincodeImportPath(name string) string {
+	return "chaincode/" + name
+}
+
+// adminDir returns the directory of the admin of a peer organization in the
+// CLI container.
+func adminDir(domain string) string {
+	return fmt.Sprintf("%s/peerOrganizations/%s/users/%s@%s", cliCryptoDir, domain, msp.AdminUser, domain)
+}
+
+// PeerEnv returns the environment of peer commands run in the CLI container
+// as the admin of an organization against one of its peers.
+func PeerEnv(network *spec.Network, org *spec.Organization, peer int) []string {
+	return AdminEnv(network.TLS, org.MSPID, org.Domain, org.PeerHost(peer))
+}
+
+// AdminEnv returns the environment of peer commands run in the CLI container
+// as the admin of the organization of a domain against a peer, by host name.
+func AdminEnv(tls spec.TLS, mspID, domain, peerHost string) []string {
+	env := []string{
+		fmt.Sprintf("CORE_PEER_ADDRESS=%s:%d", peerHost, spec.PeerPort),
+		"CORE_PEER_LOCALMSPID=" + mspID,
+		"CORE_PEER_MSPCONFIGPATH=" + adminDir(domain) + "/msp",
+		"CORE_PEER_TLS_ENABLED=" + strconv.FormatBool(tls.Enabled),
+	}
+	if !tls.Enabled {
+		return env
+	}
+
+	env = append(env, fmt.Sprintf("CORE_PEER_TLS_ROOTCERT_FILE=%s/peerOrganizations/%s/peers/%s/tls/ca.crt", cliCryptoDir, domain, peerHost))
+	if tls.ClientAuth {
+		env = append(env,
+			"CORE_PEER_TLS_CLIENTAUTHREQUIRED=true",
+			"CORE_PEER_TLS_CLIENTCERT_FILE="+adminDir(domain)+"/tls/client.crt",
+			"CORE_PEER_TLS_CLIENTKEY_FILE="+adminDir(domain)+"/tls/client.key",
+		)
+	}
+	return env
+}
+
+// ordererCA returns the TLS CA certificate of an orderer in the CLI container.
+func ordererCA(orderer spec.OrdererNode) string {
+	domain := orderer.Organization.Domain
+	return fmt.Sprintf("%s/ordererOrganizations/%s/orderers/%s/msp/tlscacerts/%s", cliCryptoDir, domain, orderer.Host(), tlsCACert(domain))
+}
+
+// OrdererArgs returns the flags of peer commands sending transactions to the
+// first orderer as the admin of an organization.
+func OrdererArgs(network *spec.Network, org *spec.Organization) []string {
+	orderer := network.OrdererNodes()[0]
+	args := []string{"-o", fmt.Sprintf("%s:%d", orderer.Host(), spec.OrdererPort)}
+	if !network.TLS.Enabled {
+		return args
+	}
+
+	args = append(args, "--tls", "--cafile", ordererCA(orderer))
+	if network.TLS.ClientAuth {
+		args = append(args, "--clientauth", "--certfile", adminDir(org.Domain)+"/tls/client.crt", "--keyfile", adminDir(org.Domain)+"/tls/client.key")
+	}
+	return args
+}
+
+// OrdererAdminArgs returns the flags of osnadmin commands run in the CLI
+// container against the admin endpoint of an orderer, osnadmin authenticates
+// with the TLS certificate of the orderer.
+func OrdererAdminArgs(network *spec.Network, orderer spec.OrdererNode) []string {
+	args := []string{"-o", fmt.Sprintf("%s:%d", orderer.Host(), ordererAdminPort)}
+	if !network.TLS.Enabled {
+		return args
+	}
+
+	tls := fmt.Sprintf("%s/ordererOrganizations/%s/orderers/%s/tls", cliCryptoDir, orderer.Organization.Domain, orderer.Host())
+	return append(args, "--ca-file", ordererCA(orderer), "--client-cert", tls+"/server.crt", "--client-key", tls+"/server.key")
+}
+
+// PeerArgs returns the flags of peer commands sending proposals to the first
+// peer of every organization, e.g to commit a chaincode definition.
+func PeerArgs(network *spec.Network, orgs []*spec.Organization) []string {
+	var args []string
+	for _, org := range orgs {
+		host := org.PeerHost(0)
+		args = append(args, "--peerAddresses", fmt.Sprintf("%s:%d", host, spec.PeerPort))
+		if network.TLS.Enabled {
+			args = append(args, "--tlsRootCertFiles", fmt.Sprintf("%s/peerOrganizations/%s/peers/%s/tls/ca.crt", cliCryptoDir, org.Domain, host))
+		}
+	}
+	return args
+}
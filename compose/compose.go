@@ -0,0 +1,267 @@
+// Package compose describes the containers of a network spec: the orderers,
+// peers, CAs, CouchDB, kafka and zookeeper nodes and a CLI container, with
+// their environment, volumes, ports and labels. The project is rendered as a
+// docker-compose file teams can run and commit, and created through the docker
+// API by hlf network up.
+package compose
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gangachris/hlf/docker"
+	"github.com/gangachris/hlf/semver"
+	"github.com/gangachris/hlf/spec"
+	yaml "gopkg.in/yaml.v2"
+)
+
+const (
+	// FileName is the name of the compose file in the artifacts directory.
+	FileName = "docker-compose.yaml"
+
+	// DefaultDockerSocket is the docker API socket peers mount by default.
+	DefaultDockerSocket = "/var/run/docker.sock"
+
+	// fileVersion is the compose file format, 3.7 names networks and volumes.
+	fileVersion = "3.7"
+)
+
+// Options configure the images and the host of the containers.
+type Options struct {
+	// FabricTag is the tag of the fabric images e.g x86_64-1.1.0 or 2.2.0.
+	FabricTag string
+
+	// CATag is the tag of the fabric-ca image, fabric-ca is versioned on its own.
+	CATag string
+
+	// ThirdPartyTag is the tag of the couchdb, kafka and zookeeper images.
+	ThirdPartyTag string
+
+	// DockerSocket is the docker API socket on the host, peers mount it to
+	// launch chaincode containers. DefaultDockerSocket when empty.
+	DockerSocket string
+}
+
+// Volume is a named volume holding the data of a node.
+type Volume struct {
+	Name string
+	Node docker.Node
+}
+
+// Project is the docker network, volumes and containers of a network spec.
+type Project struct {
+	// Name is the name of the hlf network.
+	Name string
+
+	// Fabric is the fabric version of the network.
+	Fabric string
+
+	// Network is the docker network of the containers.
+	Network string
+
+	Volumes    []Volume
+	Containers []docker.ContainerSpec
+
+	// DependsOn are the containers a container is started after.
+	DependsOn map[string][]string
+
+	// Dir is the artifacts directory, bind mounts of the network directory
+	// are written relative to it in the compose file.
+	Dir string
+
+	// Root is the directory of the network spec.
+	Root string
+}
+
+// NetworkName returns the docker network of an hlf network.
+func NetworkName(network string) string {
+	return "hlf-" + network
+}
+
+// Container returns the container of a node by name.
+func (p *Project) Container(name string) (docker.ContainerSpec, bool) {
+	for _, c := range p.Containers {
+		if c.Node.Name == name {
+			return c, true
+		}
+	}
+	return docker.ContainerSpec{}, false
+}
+
+// atLeast reports whether a fabric version is the minimum version or newer.
+func atLeast(version, minimum string) bool {
+	ok, err := semver.CorrectVersion(minimum, version)
+	return err == nil && ok
+}
+
+// FromSpec returns the project of a network spec. The crypto material must be
+// generated, the CA containers are configured with the keys of the CAs.
+func FromSpec(network *spec.Network, options Options) (*Project, error) {
+	if options.DockerSocket == "" {
+		options.DockerSocket = DefaultDockerSocket
+	}
+
+	b := &builder{
+		network: network,
+		options: options,
+		project: &Project{
+			Name:      network.Name,
+			Fabric:    network.Fabric,
+			Network:   NetworkName(network.Name),
+			DependsOn: map[string][]string{},
+			Dir:       network.ArtifactsDir(),
+			Root:      network.Dir,
+		},
+		ports: &allocator{next: network.PortBase},
+	}
+
+	if network.Orderer.Type == spec.OrdererKafka {
+		b.zookeepers()
+		b.kafkas()
+	}
+	b.orderers()
+	for i := range network.Organizations {
+		if err := b.organization(&network.Organizations[i]); err != nil {
+			return nil, err
+		}
+	}
+	b.cli()
+
+	if b.ports.next-1 > maxPort {
+		return nil, fmt.Errorf("error: the network needs host ports %d to %d, lower portBase in %s", network.PortBase, b.ports.next-1, spec.FileName)
+	}
+	return b.project, nil
+}
+
+// maxPort is the highest host port.
+const maxPort = 65535
+
+// allocator hands out host ports from the port base, in the order of the spec
+// so that the ports of a network do not change between runs.
+type allocator struct {
+	next int
+}
+
+func (a *allocator) allocate() int {
+	port := a.next
+	a.next++
+	return port
+}
+
+// the compose file, in the order of its keys
+type composeFile struct {
+	Version  string                    `yaml:"version"`
+	Networks map[string]composeNetwork `yaml:"networks"`
+	Volumes  yaml.MapSlice             `yaml:"volumes,omitempty"`
+	Services yaml.MapSlice             `yaml:"services"`
+}
+
+type composeNetwork struct {
+	Name   string            `yaml:"name"`
+	Labels map[string]string `yaml:"labels"`
+}
+
+type composeVolume struct {
+	Name   string            `yaml:"name"`
+	Labels map[string]string `yaml:"labels"`
+}
+
+type composeService struct {
+	ContainerName string            `yaml:"container_name"`
+	Hostname      string            `yaml:"hostname"`
+	Image         string            `yaml:"image"`
+	Labels        map[string]string `yaml:"labels"`
+	Environment   []string          `yaml:"environment,omitempty"`
+	WorkingDir    string            `yaml:"working_dir,omitempty"`
+	Command       []string          `yaml:"command,omitempty"`
+	Volumes       []string          `yaml:"volumes,omitempty"`
+	Ports         []string          `yaml:"ports,omitempty"`
+	DependsOn     []string          `yaml:"depends_on,omitempty"`
+}
+
+// Marshal encodes the project as a compose file, host paths under the
+// artifacts directory are relative to it.
+func (p *Project) Marshal() ([]byte, error) {
+	file := composeFile{
+		Version: fileVersion,
+		Networks: map[string]composeNetwork{
+			"default": {Name: p.Network, Labels: map[string]string{docker.LabelNetwork: p.Name}},
+		},
+	}
+
+	for _, volume := range p.Volumes {
+		file.Volumes = append(file.Volumes, yaml.MapItem{
+			Key:   volume.Name,
+			Value: composeVolume{Name: volume.Name, Labels: volume.Node.Labels()},
+		})
+	}
+
+	for _, c := range p.Containers {
+		labels := c.Node.Labels()
+		for key, value := range c.Labels {
+			labels[key] = value
+		}
+
+		service := composeService{
+			ContainerName: c.Node.Name,
+			Hostname:      c.Node.Name,
+			Image:         c.Image,
+			Labels:        labels,
+			Environment:   c.Env,
+			WorkingDir:    c.WorkingDir,
+			Command:       c.Cmd,
+			DependsOn:     p.DependsOn[c.Node.Name],
+		}
+
+		for _, m := range c.Mounts {
+			source := m.Source
+			if m.Bind {
+				source = p.relative(source)
+			}
+			volume := source + ":" + m.Target
+			if m.ReadOnly {
+				volume += ":ro"
+			}
+			service.Volumes = append(service.Volumes, volume)
+		}
+
+		var containerPorts []int
+		for port := range c.Ports {
+			containerPorts = append(containerPorts, port)
+		}
+		sort.Ints(containerPorts)
+		for _, port := range containerPorts {
+			service.Ports = append(service.Ports, strconv.Itoa(c.Ports[port])+":"+strconv.Itoa(port))
+		}
+
+		file.Services = append(file.Services, yaml.MapItem{Key: c.Node.Name, Value: service})
+	}
+
+	data, err := yaml.Marshal(file)
+	if err != nil {
+		return nil, err
+	}
+	header := fmt.Sprintf("# Generated by hlf from the network spec for fabric %s, changes are overwritten.\n", p.Fabric)
+	return append([]byte(header), data...), nil
+}
+
+// relative returns a host path of the network directory relative to the
+// artifacts directory e.g ./crypto-config/... or ../chaincode/mycc, other
+// paths such as the docker socket are kept.
+func (p *Project) relative(path string) string {
+	if inRoot, err := filepath.Rel(p.Root, path); err != nil || strings.HasPrefix(filepath.ToSlash(inRoot), "../") {
+		return path
+	}
+	rel, err := filepath.Rel(p.Dir, path)
+	if err != nil || filepath.IsAbs(rel) {
+		return path
+	}
+	rel = filepath.ToSlash(rel)
+	if !strings.HasPrefix(rel, "../") {
+		rel = "./" + rel
+	}
+	return rel
+}
@@ -0,0 +1,167 @@
+package compose
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gangachris/hlf/docker"
+	"github.com/gangachris/hlf/spec"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// update rewrites the golden files: go test ./compose -update
+var update = flag.Bool("update", false, "update the golden files")
+
+const twoOrgs = `version: 1
+name: dev
+tls:
+  enabled: true
+  clientAuth: true
+organizations:
+  - name: Org1
+    peers: 2
+    ca: true
+  - name: Org2
+    stateDatabase: couchdb
+channels:
+  - name: mychannel
+chaincodes:
+  - name: mycc
+    path: chaincode/mycc
+`
+
+const kafka = `version: 1
+name: kafka
+portBase: 9000
+orderer:
+  type: kafka
+  organizations:
+    - name: Orderer
+      nodes: 2
+  kafka:
+    brokers: 2
+    zookeepers: 1
+organizations:
+  - name: Org1
+`
+
+const etcdRaft = `version: 1
+name: raft
+portBase: 8000
+orderer:
+  type: etcdraft
+  organizations:
+    - name: Orderer
+      nodes: 2
+    - name: Orderer2
+organizations:
+  - name: Org1
+`
+
+func TestFromSpec(t *testing.T) {
+	// images are tagged with the machine hardware name before fabric 1.2
+	tests := []struct {
+		name    string
+		spec    string
+		fabric  string
+		options Options
+	}{
+		{name: "two-orgs-1.4", spec: twoOrgs, fabric: "1.4.4", options: Options{FabricTag: "1.4.4", CATag: "1.4.4", ThirdPartyTag: "0.4.18"}},
+		{name: "two-orgs-1.1", spec: twoOrgs, fabric: "1.1.0", options: Options{FabricTag: "x86_64-1.1.0", CATag: "x86_64-1.1.0", ThirdPartyTag: "x86_64-0.4.6"}},
+		{name: "kafka-2.2", spec: kafka, fabric: "2.2.0", options: Options{FabricTag: "2.2.0", CATag: "1.4.9", ThirdPartyTag: "0.4.18"}},
+		{name: "etcdraft-1.4", spec: etcdRaft, fabric: "1.4.4", options: Options{FabricTag: "1.4.4", CATag: "1.4.4", ThirdPartyTag: "0.4.18"}},
+		{name: "etcdraft-2.3", spec: etcdRaft, fabric: "2.3.0", options: Options{FabricTag: "2.3.0", CATag: "1.5.7", ThirdPartyTag: "0.4.18"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "hlf-compose")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+
+			network, err := spec.Parse(spec.FileName, []byte(tt.spec))
+			if err != nil {
+				t.Fatal(err)
+			}
+			network.Dir = dir
+			network.Fabric = tt.fabric
+
+			// the CA containers are configured with the key cryptogen generated
+			caDir := filepath.Join(network.ArtifactsDir(), "crypto-config", "peerOrganizations", "org1.example.com", "ca")
+			if err := os.MkdirAll(caDir, 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := ioutil.WriteFile(filepath.Join(caDir, "4a1b_sk"), nil, 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			project, err := FromSpec(network, tt.options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := project.Marshal()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			golden := filepath.Join("testdata", tt.name+".golden")
+			if *update {
+				if err := ioutil.WriteFile(golden, got, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := ioutil.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != string(want) {
+				t.Errorf("Marshal() =\n%s\nwant\n%s", got, want)
+			}
+
+			var file struct {
+				Services map[string]struct {
+					Labels map[string]string `yaml:"labels"`
+				} `yaml:"services"`
+			}
+			if err := yaml.Unmarshal(got, &file); err != nil {
+				t.Fatalf("Marshal() is not valid yaml: %v", err)
+			}
+			if len(file.Services) != len(project.Containers) {
+				t.Errorf("services = %d, want %d", len(file.Services), len(project.Containers))
+			}
+
+			// host ports are unique and the hlf labels are set
+			seen := map[int]string{}
+			for _, c := range project.Containers {
+				for _, port := range c.Ports {
+					if other, ok := seen[port]; ok {
+						t.Errorf("host port %d of %s is already used by %s", port, c.Node.Name, other)
+					}
+					seen[port] = c.Node.Name
+				}
+				if labels := file.Services[c.Node.Name].Labels; labels[docker.LabelNetwork] != network.Name || labels[docker.LabelRole] == "" {
+					t.Errorf("%s labels = %v, want the hlf labels", c.Node.Name, labels)
+				}
+			}
+		})
+	}
+}
+
+func TestFromSpec_missingCA(t *testing.T) {
+	network, err := spec.Parse(spec.FileName, []byte(twoOrgs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	network.Dir = os.TempDir()
+	network.Artifacts = "hlf-missing-artifacts"
+
+	_, err = FromSpec(network, Options{})
+	if err == nil || !strings.Contains(err.Error(), "hlf artifacts crypto") {
+		t.Errorf("FromSpec() error = %v, want a hint to generate the crypto material", err)
+	}
+}
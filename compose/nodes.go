@@ -0,0 +1,498 @@
+package compose
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gangachris/hlf/configtx"
+	"github.com/gangachris/hlf/configtxgen"
+	"github.com/gangachris/hlf/cryptogen"
+	"github.com/gangachris/hlf/docker"
+	"github.com/gangachris/hlf/spec"
+)
+
+// ports the nodes listen on in their containers besides the spec ones
+const (
+	peerChaincodePort     = 7052
+	peerOperationsPort    = 9443
+	ordererOperationsPort = 8443
+	ordererAdminPort      = 7053
+	caPort                = 7054
+	couchDBPort           = 5984
+	zookeeperPeerPort     = 2888
+	zookeeperLeaderPort   = 3888
+)
+
+// paths in the containers
+const (
+	peerConfigDir     = "/etc/hyperledger/fabric"
+	ordererConfigDir  = "/var/hyperledger/orderer"
+	ordererClusterDir = ordererConfigDir + "/cluster"
+	ledgerDir         = "/var/hyperledger/production"
+	caConfigDir       = "/etc/hyperledger/fabric-ca-server-config"
+	fabricWorkingDir  = "/opt/gopath/src/github.com/hyperledger/fabric"
+	cliWorkingDir     = fabricWorkingDir + "/peer"
+	chaincodeDir      = "/opt/gopath/src/chaincode"
+	hostDockerSocket  = "/host/var/run/docker.sock"
+)
+
+// CouchDB credentials of the state databases, they are only reachable from
+// the docker network and the host.
+const (
+	CouchDBUser     = "admin"
+	CouchDBPassword = "adminpw"
+)
+
+// CA admin credentials the fabric-ca servers are bootstrapped with.
+const (
+	CAAdmin         = "admin"
+	CAAdminPassword = "adminpw"
+)
+
+// CLIName returns the name of the CLI container of a network.
+func CLIName(network *spec.Network) string {
+	return "cli." + network.Domain
+}
+
+// CouchDBName returns the name of the state database container of a peer.
+func CouchDBName(peerHost string) string {
+	return "couchdb." + peerHost
+}
+
+// CAName returns the name of the CA container of an organization.
+func CAName(org *spec.Organization) string {
+	return "ca." + org.Domain
+}
+
+type builder struct {
+	network *spec.Network
+	options Options
+	project *Project
+	ports   *allocator
+}
+
+func (b *builder) image(name, tag string) string {
+	return fmt.Sprintf("%s/fabric-%s:%s", docker.HyperledgerRepository, name, tag)
+}
+
+func (b *builder) node(org string, role docker.Role, name string) docker.Node {
+	return docker.Node{Network: b.network.Name, Org: org, Role: role, Name: name}
+}
+
+// volume adds the named volume of a node.
+func (b *builder) volume(node docker.Node) string {
+	name := b.network.Name + "-" + node.Name
+	b.project.Volumes = append(b.project.Volumes, Volume{Name: name, Node: node})
+	return name
+}
+
+func (b *builder) add(container docker.ContainerSpec, dependsOn ...string) {
+	container.Network = b.project.Network
+	b.project.Containers = append(b.project.Containers, container)
+	if len(dependsOn) > 0 {
+		b.project.DependsOn[container.Node.Name] = dependsOn
+	}
+}
+
+// crypto returns the host path of crypto material e.g crypto("peerOrganizations", "org1.example.com").
+func (b *builder) crypto(elem ...string) string {
+	return filepath.Join(append([]string{b.network.ArtifactsDir(), cryptogen.OutputDir}, elem...)...)
+}
+
+func bind(source, target string) docker.Mount {
+	return docker.Mount{Source: source, Target: target, Bind: true, ReadOnly: true}
+}
+
+// ports exposes the main port of a node and its operations port, fabric 1.4
+// and newer, on the next host ports.
+func (b *builder) exposePorts(container *docker.ContainerSpec, port, operationsPort int) {
+	container.Ports = map[int]int{port: b.ports.allocate()}
+	container.Labels = map[string]string{docker.LabelPort: strconv.Itoa(container.Ports[port])}
+	if operationsPort != 0 && b.operations() {
+		container.Ports[operationsPort] = b.ports.allocate()
+		container.Labels[docker.LabelOperationsPort] = strconv.Itoa(container.Ports[operationsPort])
+	}
+}
+
+// operations reports whether nodes have an operations service, fabric 1.4 and newer.
+func (b *builder) operations() bool {
+	return atLeast(b.network.Fabric, "1.4")
+}
+
+// logging returns the log level variable of a node, FABRIC_LOGGING_SPEC since
+// fabric 1.4.
+func (b *builder) logging(legacy string) string {
+	if b.operations() {
+		return "FABRIC_LOGGING_SPEC=INFO"
+	}
+	return legacy + "=INFO"
+}
+
+func (b *builder) zookeepers() {
+	count := b.network.Orderer.Kafka.Zookeepers
+	var servers []string
+	for i := 0; i < count; i++ {
+		servers = append(servers, fmt.Sprintf("server.%d=%s:%d:%d", i+1, spec.ZookeeperHost(i), zookeeperPeerPort, zookeeperLeaderPort))
+	}
+
+	for i := 0; i < count; i++ {
+		b.add(docker.ContainerSpec{
+			Node:  b.node("", docker.RoleZookeeper, spec.ZookeeperHost(i)),
+			Image: b.image("zookeeper", b.options.ThirdPartyTag),
+			Env: []string{
+				fmt.Sprintf("ZOO_MY_ID=%d", i+1),
+				"ZOO_SERVERS=" + strings.Join(servers, " "),
+			},
+		})
+	}
+}
+
+func (b *builder) kafkas() {
+	kafka := b.network.Orderer.Kafka
+	var zookeepers, addresses []string
+	for i := 0; i < kafka.Zookeepers; i++ {
+		zookeepers = append(zookeepers, spec.ZookeeperHost(i))
+		addresses = append(addresses, fmt.Sprintf("%s:%d", spec.ZookeeperHost(i), spec.ZookeeperPort))
+	}
+
+	replication := min(3, kafka.Brokers)
+	for i := 0; i < kafka.Brokers; i++ {
+		b.add(docker.ContainerSpec{
+			Node:  b.node("", docker.RoleKafka, spec.KafkaHost(i)),
+			Image: b.image("kafka", b.options.ThirdPartyTag),
+			Env: []string{
+				fmt.Sprintf("KAFKA_BROKER_ID=%d", i),
+				"KAFKA_ZOOKEEPER_CONNECT=" + strings.Join(addresses, ","),
+				"KAFKA_MESSAGE_MAX_BYTES=103809024",
+				"KAFKA_REPLICA_FETCH_MAX_BYTES=103809024",
+				"KAFKA_UNCLEAN_LEADER_ELECTION_ENABLE=false",
+				fmt.Sprintf("KAFKA_DEFAULT_REPLICATION_FACTOR=%d", replication),
+				fmt.Sprintf("KAFKA_MIN_INSYNC_REPLICAS=%d", min(2, replication)),
+				"KAFKA_LOG_RETENTION_MS=-1",
+			},
+		}, zookeepers...)
+	}
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func (b *builder) orderers() {
+	tls := b.network.TLS
+	var kafkas []string
+	for i := 0; i < b.network.Orderer.Kafka.Brokers && b.network.Orderer.Type == spec.OrdererKafka; i++ {
+		kafkas = append(kafkas, spec.KafkaHost(i))
+	}
+
+	// orderers using the channel participation API start without a system
+	// channel, they join channels through their admin endpoint
+	participation := configtx.ChannelParticipation(b.network)
+	for _, node := range b.network.OrdererNodes() {
+		org := node.Organization
+		host := node.Host()
+		dir := b.crypto("ordererOrganizations", org.Domain, "orderers", host)
+		dockerNode := b.node(org.Name, docker.RoleOrderer, host)
+
+		env := []string{
+			b.logging("ORDERER_GENERAL_LOGLEVEL"),
+			"ORDERER_GENERAL_LISTENADDRESS=0.0.0.0",
+			fmt.Sprintf("ORDERER_GENERAL_LISTENPORT=%d", spec.OrdererPort),
+		}
+		genesis := ordererConfigDir + "/orderer.genesis.block"
+		switch {
+		case participation:
+			env = append(env, "ORDERER_GENERAL_BOOTSTRAPMETHOD=none")
+			env = append(env, b.adminEnv()...)
+		case atLeast(b.network.Fabric, "2.0"):
+			env = append(env, "ORDERER_GENERAL_BOOTSTRAPMETHOD=file", "ORDERER_GENERAL_BOOTSTRAPFILE="+genesis)
+		default:
+			env = append(env, "ORDERER_GENERAL_GENESISMETHOD=file", "ORDERER_GENERAL_GENESISFILE="+genesis)
+		}
+		env = append(env,
+			"ORDERER_GENERAL_LOCALMSPID="+org.MSPID,
+			"ORDERER_GENERAL_LOCALMSPDIR="+ordererConfigDir+"/msp",
+			"ORDERER_GENERAL_TLS_ENABLED="+strconv.FormatBool(tls.Enabled),
+			"ORDERER_GENERAL_TLS_PRIVATEKEY="+ordererConfigDir+"/tls/server.key",
+			"ORDERER_GENERAL_TLS_CERTIFICATE="+ordererConfigDir+"/tls/server.crt",
+			"ORDERER_GENERAL_TLS_ROOTCAS=["+ordererConfigDir+"/tls/ca.crt]",
+		)
+		if tls.ClientAuth {
+			env = append(env,
+				"ORDERER_GENERAL_TLS_CLIENTAUTHREQUIRED=true",
+				"ORDERER_GENERAL_TLS_CLIENTROOTCAS=["+ordererConfigDir+"/tls/ca.crt]",
+			)
+		}
+		if b.network.Orderer.Type == spec.OrdererKafka {
+			env = append(env,
+				"ORDERER_KAFKA_RETRY_SHORTINTERVAL=1s",
+				"ORDERER_KAFKA_RETRY_SHORTTOTAL=30s",
+				"ORDERER_KAFKA_VERBOSE=true",
+			)
+		}
+		if b.network.Orderer.Type == spec.OrdererEtcdRaft {
+			env = append(env, b.raftEnv()...)
+		}
+		if b.operations() {
+			env = append(env, fmt.Sprintf("ORDERER_OPERATIONS_LISTENADDRESS=0.0.0.0:%d", ordererOperationsPort))
+		}
+
+		var mounts []docker.Mount
+		if !participation {
+			mounts = append(mounts, bind(filepath.Join(b.network.ArtifactsDir(), configtxgen.OutputDir, configtxgen.GenesisBlockFile), genesis))
+		}
+		mounts = append(mounts,
+			bind(filepath.Join(dir, "msp"), ordererConfigDir+"/msp"),
+			bind(filepath.Join(dir, "tls"), ordererConfigDir+"/tls"),
+			docker.Mount{Source: b.volume(dockerNode), Target: ledgerDir + "/orderer"},
+		)
+		if b.network.Orderer.Type == spec.OrdererEtcdRaft {
+			for _, org := range b.network.Orderer.Organizations {
+				cert := tlsCACert(org.Domain)
+				mounts = append(mounts, bind(b.crypto("ordererOrganizations", org.Domain, "tlsca", cert), ordererClusterDir+"/"+cert))
+			}
+		}
+
+		container := docker.ContainerSpec{
+			Node:       dockerNode,
+			Image:      b.image("orderer", b.options.FabricTag),
+			Env:        env,
+			WorkingDir: fabricWorkingDir,
+			Cmd:        []string{"orderer"},
+			Mounts:     mounts,
+		}
+		b.exposePorts(&container, spec.OrdererPort, ordererOperationsPort)
+		b.add(container, kafkas...)
+	}
+}
+
+// tlsCACert returns the file name of the TLS CA certificate of an organization.
+func tlsCACert(domain string) string {
+	return "tlsca." + domain + "-cert.pem"
+}
+
+// adminEnv returns the environment of the admin endpoint of the channel
+// participation API. osnadmin has to authenticate with a TLS client certificate
+// when TLS is enabled, the orderers trust their TLS CA.
+func (b *builder) adminEnv() []string {
+	env := []string{
+		"ORDERER_CHANNELPARTICIPATION_ENABLED=true",
+		fmt.Sprintf("ORDERER_ADMIN_LISTENADDRESS=0.0.0.0:%d", ordererAdminPort),
+		"ORDERER_ADMIN_TLS_ENABLED=" + strconv.FormatBool(b.network.TLS.Enabled),
+	}
+	if !b.network.TLS.Enabled {
+		return env
+	}
+
+	return append(env,
+		"ORDERER_ADMIN_TLS_PRIVATEKEY="+ordererConfigDir+"/tls/server.key",
+		"ORDERER_ADMIN_TLS_CERTIFICATE="+ordererConfigDir+"/tls/server.crt",
+		"ORDERER_ADMIN_TLS_ROOTCAS=["+ordererConfigDir+"/tls/ca.crt]",
+		"ORDERER_ADMIN_TLS_CLIENTAUTHREQUIRED=true",
+		"ORDERER_ADMIN_TLS_CLIENTROOTCAS=["+ordererConfigDir+"/tls/ca.crt]",
+	)
+}
+
+// raftEnv returns the environment of the etcdraft consenters: the consenters
+// connect to each other with their TLS server certificate and trust the TLS
+// CAs of every orderer organization. The write ahead log and the snapshots are
+// kept in the ledger volume of the node.
+func (b *builder) raftEnv() []string {
+	var rootCAs []string
+	for _, org := range b.network.Orderer.Organizations {
+		rootCAs = append(rootCAs, ordererClusterDir+"/"+tlsCACert(org.Domain))
+	}
+
+	return []string{
+		"ORDERER_GENERAL_CLUSTER_CLIENTCERTIFICATE=" + ordererConfigDir + "/tls/server.crt",
+		"ORDERER_GENERAL_CLUSTER_CLIENTPRIVATEKEY=" + ordererConfigDir + "/tls/server.key",
+		"ORDERER_GENERAL_CLUSTER_ROOTCAS=[" + strings.Join(rootCAs, ",") + "]",
+		"ORDERER_CONSENSUS_WALDIR=" + ledgerDir + "/orderer/etcdraft/wal",
+		"ORDERER_CONSENSUS_SNAPDIR=" + ledgerDir + "/orderer/etcdraft/snapshot",
+	}
+}
+
+func (b *builder) organization(org *spec.Organization) error {
+	if org.CA {
+		if err := b.ca(org); err != nil {
+			return err
+		}
+	}
+
+	for i := 0; i < org.Peers; i++ {
+		b.peer(org, i)
+	}
+	return nil
+}
+
+func (b *builder) ca(org *spec.Organization) error {
+	dir := b.crypto("peerOrganizations", org.Domain, "ca")
+	keys, err := filepath.Glob(filepath.Join(dir, "*_sk"))
+	if err != nil || len(keys) == 0 {
+		return fmt.Errorf("error: the CA key of %s was not found in %s, generate the crypto material first with hlf artifacts crypto", org.Name, dir)
+	}
+
+	certFile := caConfigDir + "/ca." + org.Domain + "-cert.pem"
+	keyFile := caConfigDir + "/" + filepath.Base(keys[0])
+	container := docker.ContainerSpec{
+		Node:  b.node(org.Name, docker.RoleCA, CAName(org)),
+		Image: b.image("ca", b.options.CATag),
+		Env: []string{
+			"FABRIC_CA_HOME=/etc/hyperledger/fabric-ca-server",
+			"FABRIC_CA_SERVER_CA_NAME=" + CAName(org),
+			fmt.Sprintf("FABRIC_CA_SERVER_PORT=%d", caPort),
+			"FABRIC_CA_SERVER_CA_CERTFILE=" + certFile,
+			"FABRIC_CA_SERVER_CA_KEYFILE=" + keyFile,
+			"FABRIC_CA_SERVER_TLS_ENABLED=" + strconv.FormatBool(b.network.TLS.Enabled),
+			"FABRIC_CA_SERVER_TLS_CERTFILE=" + certFile,
+			"FABRIC_CA_SERVER_TLS_KEYFILE=" + keyFile,
+		},
+		Cmd:    []string{"sh", "-c", fmt.Sprintf("fabric-ca-server start -b %s:%s -d", CAAdmin, CAAdminPassword)},
+		Mounts: []docker.Mount{bind(dir, caConfigDir)},
+	}
+	b.exposePorts(&container, caPort, 0)
+	b.add(container)
+	return nil
+}
+
+func (b *builder) peer(org *spec.Organization, i int) {
+	tls := b.network.TLS
+	host := org.PeerHost(i)
+	address := fmt.Sprintf("%s:%d", host, spec.PeerPort)
+	dir := b.crypto("peerOrganizations", org.Domain, "peers", host)
+	dockerNode := b.node(org.Name, docker.RolePeer, host)
+
+	// gossip bootstraps from the next peer of the organization
+	bootstrap := fmt.Sprintf("%s:%d", org.PeerHost((i+1)%org.Peers), spec.PeerPort)
+
+	env := []string{
+		b.logging("CORE_LOGGING_LEVEL"),
+		"CORE_VM_ENDPOINT=unix://" + hostDockerSocket,
+		"CORE_VM_DOCKER_HOSTCONFIG_NETWORKMODE=" + b.project.Network,
+		"CORE_PEER_ID=" + host,
+		"CORE_PEER_ADDRESS=" + address,
+		fmt.Sprintf("CORE_PEER_LISTENADDRESS=0.0.0.0:%d", spec.PeerPort),
+		fmt.Sprintf("CORE_PEER_CHAINCODEADDRESS=%s:%d", host, peerChaincodePort),
+		fmt.Sprintf("CORE_PEER_CHAINCODELISTENADDRESS=0.0.0.0:%d", peerChaincodePort),
+		"CORE_PEER_GOSSIP_BOOTSTRAP=" + bootstrap,
+		"CORE_PEER_GOSSIP_EXTERNALENDPOINT=" + address,
+		"CORE_PEER_GOSSIP_USELEADERELECTION=true",
+		"CORE_PEER_GOSSIP_ORGLEADER=false",
+		"CORE_PEER_LOCALMSPID=" + org.MSPID,
+		"CORE_PEER_MSPCONFIGPATH=" + peerConfigDir + "/msp",
+		"CORE_PEER_TLS_ENABLED=" + strconv.FormatBool(tls.Enabled),
+		"CORE_PEER_TLS_CERT_FILE=" + peerConfigDir + "/tls/server.crt",
+		"CORE_PEER_TLS_KEY_FILE=" + peerConfigDir + "/tls/server.key",
+		"CORE_PEER_TLS_ROOTCERT_FILE=" + peerConfigDir + "/tls/ca.crt",
+	}
+	if tls.ClientAuth {
+		env = append(env,
+			"CORE_PEER_TLS_CLIENTAUTHREQUIRED=true",
+			"CORE_PEER_TLS_CLIENTROOTCAS_FILES="+peerConfigDir+"/tls/ca.crt",
+		)
+	}
+	if b.operations() {
+		env = append(env, fmt.Sprintf("CORE_OPERATIONS_LISTENADDRESS=0.0.0.0:%d", peerOperationsPort))
+	}
+
+	var dependsOn []string
+	if org.StateDatabase == spec.CouchDB {
+		couchDB := CouchDBName(host)
+		env = append(env,
+			"CORE_LEDGER_STATE_STATEDATABASE=CouchDB",
+			fmt.Sprintf("CORE_LEDGER_STATE_COUCHDBCONFIG_COUCHDBADDRESS=%s:%d", couchDB, couchDBPort),
+			"CORE_LEDGER_STATE_COUCHDBCONFIG_USERNAME="+CouchDBUser,
+			"CORE_LEDGER_STATE_COUCHDBCONFIG_PASSWORD="+CouchDBPassword,
+		)
+		dependsOn = append(dependsOn, couchDB)
+	}
+
+	container := docker.ContainerSpec{
+		Node:       dockerNode,
+		Image:      b.image("peer", b.options.FabricTag),
+		Env:        env,
+		WorkingDir: fabricWorkingDir,
+		Cmd:        []string{"peer", "node", "start"},
+		Mounts: []docker.Mount{
+			{Source: b.options.DockerSocket, Target: hostDockerSocket, Bind: true},
+			bind(filepath.Join(dir, "msp"), peerConfigDir+"/msp"),
+			bind(filepath.Join(dir, "tls"), peerConfigDir+"/tls"),
+			{Source: b.volume(dockerNode), Target: ledgerDir},
+		},
+	}
+	b.exposePorts(&container, spec.PeerPort, peerOperationsPort)
+
+	if org.StateDatabase == spec.CouchDB {
+		b.couchDB(org, host)
+	}
+	b.add(container, dependsOn...)
+}
+
+func (b *builder) couchDB(org *spec.Organization, peerHost string) {
+	dockerNode := b.node(org.Name, docker.RoleCouchDB, CouchDBName(peerHost))
+	container := docker.ContainerSpec{
+		Node:  dockerNode,
+		Image: b.image("couchdb", b.options.ThirdPartyTag),
+		Env: []string{
+			"COUCHDB_USER=" + CouchDBUser,
+			"COUCHDB_PASSWORD=" + CouchDBPassword,
+		},
+		Mounts: []docker.Mount{{Source: b.volume(dockerNode), Target: "/opt/couchdb/data"}},
+	}
+	b.exposePorts(&container, couchDBPort, 0)
+	b.add(container)
+}
+
+// cli adds the tools container, set up as the admin of the first organization
+// on its first peer. Peer commands of other organizations override the
+// CORE_PEER variables.
+func (b *builder) cli() {
+	org := &b.network.Organizations[0]
+	orderer := b.network.OrdererNodes()[0]
+
+	env := []string{
+		"GOPATH=/opt/gopath",
+		"CORE_VM_ENDPOINT=unix://" + hostDockerSocket,
+		b.logging("CORE_LOGGING_LEVEL"),
+		"CORE_PEER_ID=" + CLIName(b.network),
+	}
+	env = append(env, PeerEnv(b.network, org, 0)...)
+	env = append(env,
+		fmt.Sprintf("ORDERER_ADDRESS=%s:%d", orderer.Host(), spec.OrdererPort),
+		"ORDERER_CA="+ordererCA(orderer),
+	)
+
+	mounts := []docker.Mount{
+		{Source: b.options.DockerSocket, Target: hostDockerSocket, Bind: true},
+		bind(b.crypto(), cliCryptoDir),
+		bind(filepath.Join(b.network.ArtifactsDir(), configtxgen.OutputDir), cliWorkingDir+"/"+configtxgen.OutputDir),
+	}
+	for _, chaincode := range b.network.Chaincodes {
+		path := chaincode.Path
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(b.network.Dir, path)
+		}
+		mounts = append(mounts, bind(path, ChaincodeDir(chaincode.Name)))
+	}
+
+	var peers []string
+	for _, c := range b.project.Containers {
+		if c.Node.Role == docker.RolePeer {
+			peers = append(peers, c.Node.Name)
+		}
+	}
+
+	b.add(docker.ContainerSpec{
+		Node:       b.node("", docker.RoleCLI, CLIName(b.network)),
+		Image:      b.image("tools", b.options.FabricTag),
+		Env:        env,
+		WorkingDir: cliWorkingDir,
+		Cmd:        []string{"tail", "-f", "/dev/null"},
+		Mounts:     mounts,
+	}, peers...)
+}
@@ -0,0 +1,450 @@
+// Package config defines the hlf configuration. Values are read, from the
+// highest precedence to the lowest, from command line flags, HLF_* environment
+// variables, the config file and defaults.
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gangachris/hlf/docker"
+	"github.com/gangachris/hlf/semver"
+	homedir "github.com/mitchellh/go-homedir"
+	"github.com/spf13/viper"
+)
+
+// configuration keys
+const (
+	KeyFabricVersion   = "fabric-version"
+	KeyFabricCAVersion = "fabric-ca-version"
+	KeyHome            = "home"
+	KeyRegistry        = "registry"
+	KeyMirrors         = "mirrors"
+	KeyParallelism     = "parallelism"
+	KeyOutput          = "output"
+	KeyNetwork         = "network"
+	KeyRuntime         = "runtime"
+)
+
+// output formats
+const (
+	OutputTable = "table"
+	OutputJSON  = "json"
+)
+
+const (
+	// EnvPrefix prefixes the environment variables of keys e.g HLF_FABRIC_VERSION.
+	EnvPrefix = "HLF"
+
+	// DefaultFabricVersion is the fabric version used when none is configured.
+	DefaultFabricVersion = "1.1.0"
+
+	// DefaultHome is the directory hlf keeps its binaries, cache, state and config in.
+	DefaultHome = "~/.hlf-cli"
+
+	// DefaultRegistry is the docker hub organization fabric images are published by.
+	DefaultRegistry = "hyperledger"
+
+	// FileName is the name of the config file in the hlf home directory.
+	FileName = "config.yaml"
+)
+
+// Config is the hlf configuration.
+type Config struct {
+	// FabricVersion is the fabric version images and binaries are downloaded for.
+	FabricVersion string
+
+	// FabricCAVersion is the fabric-ca version, the one released with the fabric
+	// version when empty, see CAVersion.
+	FabricCAVersion string
+
+	// Home is the absolute directory hlf keeps its binaries, cache, state and config in.
+	Home string
+
+	// Registry is the registry and organization images are pulled from e.g ghcr.io/hyperledger.
+	Registry string
+
+	// Mirrors are URLs platform binaries are downloaded from before the default one.
+	Mirrors []string
+
+	// Parallelism is the number of images pulled at the same time.
+	Parallelism int
+
+	// Output is the format commands list things in, table or json.
+	Output string
+
+	// Network is the hlf network commands act on when none is given.
+	Network string
+
+	// Runtime is the container runtime: auto, docker, rootless-docker or podman.
+	Runtime string
+}
+
+// Key describes a configuration key.
+type Key struct {
+	Name        string
+	Description string
+	// Default is the value of the key when it is not set, its type is the
+	// type of the key: string, int or []string.
+	Default interface{}
+
+	// EnvOnly keys are read from their flag or environment variable but not
+	// from the config file, e.g home which locates the config file.
+	EnvOnly bool
+
+	// validate checks a value of the type of the key.
+	validate func(value interface{}) error
+}
+
+// CheckFile returns an error when the key can not be set in the config file.
+func (k Key) CheckFile() error {
+	if k.EnvOnly {
+		return fmt.Errorf("%s can not be set in the config file, use %s or --%s", k.Name, k.Env(), k.Name)
+	}
+	return nil
+}
+
+// Env returns the environment variable setting the key.
+func (k Key) Env() string {
+	return EnvPrefix + "_" + strings.ToUpper(strings.Replace(k.Name, "-", "_", -1))
+}
+
+// Parse converts a value to the type of the key and validates it. Values set on
+// the command line or in environment variables are strings, lists are comma
+// separated, and yaml decodes unquoted versions such as 1.4 to numbers.
+func (k Key) Parse(value interface{}) (interface{}, error) {
+	if value == nil {
+		value = k.Default
+	}
+
+	switch k.Default.(type) {
+	case string:
+		switch v := value.(type) {
+		case int, int64, float64:
+			value = fmt.Sprint(v)
+		}
+	case int:
+		switch v := value.(type) {
+		case string:
+			n, err := strconv.Atoi(strings.TrimSpace(v))
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s %q: expected a number", k.Name, v)
+			}
+			value = n
+		case int64:
+			value = int(v)
+		case float64:
+			if v != float64(int(v)) {
+				return nil, fmt.Errorf("invalid %s %v: expected a whole number", k.Name, v)
+			}
+			value = int(v)
+		}
+	case []string:
+		switch v := value.(type) {
+		case string:
+			list := []string{}
+			for _, item := range strings.Split(v, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					list = append(list, item)
+				}
+			}
+			value = list
+		case []interface{}:
+			list := []string{}
+			for _, item := range v {
+				list = append(list, fmt.Sprint(item))
+			}
+			value = list
+		}
+	}
+
+	if k.validate != nil {
+		if err := k.validate(value); err != nil {
+			return nil, fmt.Errorf("invalid %s %v: %s", k.Name, value, err.Error())
+		}
+	}
+	return value, nil
+}
+
+var (
+	versionPattern = regexp.MustCompile(`^\d+\.\d+(\.\d+)?$`)
+	networkPattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+)
+
+// Keys are the configuration keys, in the order they are listed.
+var Keys = []Key{
+	{
+		Name:        KeyFabricVersion,
+		Description: "fabric version images and binaries are downloaded for",
+		Default:     DefaultFabricVersion,
+		validate: stringValue(func(value string) error {
+			if !versionPattern.MatchString(value) {
+				return fmt.Errorf("expected a version such as 1.1.0")
+			}
+			return nil
+		}),
+	},
+	{
+		Name:        KeyFabricCAVersion,
+		Description: "fabric-ca version, the one released with the fabric version when empty",
+		Default:     "",
+		validate: stringValue(func(value string) error {
+			if value != "" && !versionPattern.MatchString(value) {
+				return fmt.Errorf("expected a version such as 1.4.9")
+			}
+			return nil
+		}),
+	},
+	{
+		Name:        KeyHome,
+		Description: "directory of binaries, cache, state and the config file, only set with HLF_HOME or --home",
+		Default:     DefaultHome,
+		EnvOnly:     true,
+		validate: stringValue(func(value string) error {
+			_, err := ExpandHome(value)
+			return err
+		}),
+	},
+	{
+		Name:        KeyRegistry,
+		Description: "registry and organization images are pulled from e.g ghcr.io/hyperledger",
+		Default:     DefaultRegistry,
+		validate: stringValue(func(value string) error {
+			if value == "" || strings.Contains(value, "://") || strings.HasSuffix(value, "/") {
+				return fmt.Errorf("expected a registry path such as hyperledger or registry.example.com:5000/hyperledger")
+			}
+			return nil
+		}),
+	},
+	{
+		Name:        KeyMirrors,
+		Description: "comma separated URLs platform binaries are downloaded from before the default one",
+		Default:     []string{},
+		validate: func(value interface{}) error {
+			mirrors, ok := value.([]string)
+			if !ok {
+				return fmt.Errorf("expected a list of URLs")
+			}
+			for _, mirror := range mirrors {
+				u, err := url.Parse(mirror)
+				if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+					return fmt.Errorf("%s is not an http or https URL", mirror)
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Name:        KeyParallelism,
+		Description: "number of images pulled at the same time",
+		Default:     1,
+		validate: func(value interface{}) error {
+			parallelism, ok := value.(int)
+			if !ok || parallelism < 1 {
+				return fmt.Errorf("expected a number greater than 0")
+			}
+			return nil
+		},
+	},
+	{
+		Name:        KeyOutput,
+		Description: "format commands list things in: table or json",
+		Default:     OutputTable,
+		validate: stringValue(func(value string) error {
+			if value != OutputTable && value != OutputJSON {
+				return fmt.Errorf("expected table or json")
+			}
+			return nil
+		}),
+	},
+	{
+		Name:        KeyNetwork,
+		Description: "hlf network commands act on when none is given, all networks when empty",
+		Default:     "",
+		validate: stringValue(func(value string) error {
+			if value != "" && !networkPattern.MatchString(value) {
+				return fmt.Errorf("expected letters, digits, '_', '.' or '-'")
+			}
+			return nil
+		}),
+	},
+	{
+		Name:        KeyRuntime,
+		Description: "container runtime: auto, docker, rootless-docker or podman",
+		Default:     "auto",
+		validate: stringValue(func(value string) error {
+			_, err := docker.ParseRuntime(value)
+			return err
+		}),
+	},
+}
+
+// stringValue validates values that must be strings.
+func stringValue(validate func(value string) error) func(value interface{}) error {
+	return func(value interface{}) error {
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("expected a string")
+		}
+		return validate(s)
+	}
+}
+
+// Lookup returns a configuration key by name.
+func Lookup(name string) (Key, bool) {
+	for _, key := range Keys {
+		if key.Name == name {
+			return key, true
+		}
+	}
+	return Key{}, false
+}
+
+// Setup makes v read keys from HLF_* environment variables and fall back to defaults.
+func Setup(v *viper.Viper) {
+	v.SetEnvPrefix(EnvPrefix)
+	v.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
+	v.AutomaticEnv()
+
+	for _, key := range Keys {
+		v.SetDefault(key.Name, key.Default)
+	}
+}
+
+// Load returns the configuration read by v and validates it.
+func Load(v *viper.Viper) (*Config, error) {
+	values := map[string]interface{}{}
+	for _, key := range Keys {
+		value, err := key.Parse(v.Get(key.Name))
+		if err != nil {
+			return nil, err
+		}
+		values[key.Name] = value
+	}
+
+	home, err := ExpandHome(values[KeyHome].(string))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Config{
+		FabricVersion:   values[KeyFabricVersion].(string),
+		FabricCAVersion: values[KeyFabricCAVersion].(string),
+		Home:            home,
+		Registry:        values[KeyRegistry].(string),
+		Mirrors:         values[KeyMirrors].([]string),
+		Parallelism:     values[KeyParallelism].(int),
+		Output:          values[KeyOutput].(string),
+		Network:         values[KeyNetwork].(string),
+		Runtime:         values[KeyRuntime].(string),
+	}, nil
+}
+
+// CAVersion returns the configured fabric-ca version, or the one released with
+// a fabric version. fabric-ca followed the fabric versions up to 1.4, fabric
+// 2.0 to 2.2 shipped with fabric-ca 1.4.9 and 2.3 and newer with 1.5.
+func (c Config) CAVersion(fabricVersion string) string {
+	if c.FabricCAVersion != "" {
+		return c.FabricCAVersion
+	}
+	return DefaultCAVersion(fabricVersion)
+}
+
+// DefaultCAVersion returns the fabric-ca version released with a fabric version.
+func DefaultCAVersion(fabricVersion string) string {
+	switch {
+	case atLeast(fabricVersion, "2.3"):
+		return "1.5.7"
+	case atLeast(fabricVersion, "2.0"):
+		return "1.4.9"
+	}
+	return fabricVersion
+}
+
+// atLeast reports whether a version is the minimum version or newer.
+func atLeast(version, minimum string) bool {
+	ok, err := semver.CorrectVersion(minimum, version)
+	return err == nil && ok
+}
+
+// ExpandHome returns the absolute path of a home directory, ~ is the user
+// home directory and relative paths are relative to the working directory.
+func ExpandHome(home string) (string, error) {
+	if strings.TrimSpace(home) == "" {
+		return "", fmt.Errorf("expected a directory")
+	}
+
+	expanded, err := homedir.Expand(home)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Abs(expanded)
+}
+
+// BinariesDir is the directory the platform binaries archive is extracted to,
+// it holds the bin and config directories of fabric.
+func (c Config) BinariesDir() string {
+	return c.Home
+}
+
+// CacheDir is the directory downloads are kept in, it can be deleted at any time.
+func (c Config) CacheDir() string {
+	return filepath.Join(c.Home, "cache")
+}
+
+// StateDir is the directory hlf keeps the state of networks in.
+func (c Config) StateDir() string {
+	return filepath.Join(c.Home, "state")
+}
+
+// ConfigFile is the config file in the home directory.
+func (c Config) ConfigFile() string {
+	return filepath.Join(c.Home, FileName)
+}
+
+// Source returns where the value of a key comes from: flag, env, file or default.
+func Source(v *viper.Viper, key Key, flagChanged bool) string {
+	switch {
+	case flagChanged:
+		return "flag"
+	case os.Getenv(key.Env()) != "":
+		return "env"
+	case v.InConfig(key.Name) && !key.EnvOnly:
+		return "file"
+	}
+	return "default"
+}
+
+// ValidateFile checks every setting of a config file and returns all the problems found.
+func ValidateFile(settings map[string]interface{}) []error {
+	var names []string
+	for name := range settings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var problems []error
+	for _, name := range names {
+		key, ok := Lookup(name)
+		if !ok {
+			problems = append(problems, fmt.Errorf("unknown key %s", name))
+			continue
+		}
+
+		if err := key.CheckFile(); err != nil {
+			problems = append(problems, err)
+			continue
+		}
+
+		if _, err := key.Parse(settings[name]); err != nil {
+			problems = append(problems, err)
+		}
+	}
+	return problems
+}
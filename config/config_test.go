@@ -0,0 +1,216 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestKey_Parse(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     string
+		value   interface{}
+		want    interface{}
+		wantErr bool
+	}{
+		{
+			name:  "unset value is the default",
+			key:   KeyFabricVersion,
+			value: nil,
+			want:  DefaultFabricVersion,
+		},
+		{
+			name:  "yaml number is a version",
+			key:   KeyFabricVersion,
+			value: 1.4,
+			want:  "1.4",
+		},
+		{
+			name:    "invalid version",
+			key:     KeyFabricVersion,
+			value:   "latest",
+			wantErr: true,
+		},
+		{
+			name:  "string number",
+			key:   KeyParallelism,
+			value: "4",
+			want:  4,
+		},
+		{
+			name:    "parallelism must be positive",
+			key:     KeyParallelism,
+			value:   0,
+			wantErr: true,
+		},
+		{
+			name:  "comma separated mirrors",
+			key:   KeyMirrors,
+			value: "https://a.example.com, https://b.example.com,",
+			want:  []string{"https://a.example.com", "https://b.example.com"},
+		},
+		{
+			name:  "yaml list of mirrors",
+			key:   KeyMirrors,
+			value: []interface{}{"https://a.example.com"},
+			want:  []string{"https://a.example.com"},
+		},
+		{
+			name:    "mirror must be an http URL",
+			key:     KeyMirrors,
+			value:   "ftp://a.example.com",
+			wantErr: true,
+		},
+		{
+			name:    "unknown output",
+			key:     KeyOutput,
+			value:   "yaml",
+			wantErr: true,
+		},
+		{
+			name:    "registry with a scheme",
+			key:     KeyRegistry,
+			value:   "https://ghcr.io/hyperledger",
+			wantErr: true,
+		},
+		{
+			name:    "unknown runtime",
+			key:     KeyRuntime,
+			value:   "containerd",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, ok := Lookup(tt.key)
+			if !ok {
+				t.Fatalf("Lookup(%s) not found", tt.key)
+			}
+
+			got, err := key.Parse(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Key.Parse() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Key.Parse() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoad_precedence(t *testing.T) {
+	v := viper.New()
+	Setup(v)
+	v.Set(KeyRegistry, "registry.example.com/hyperledger")
+	v.SetDefault(KeyParallelism, 2)
+
+	os.Setenv("HLF_OUTPUT", "json")
+	defer os.Unsetenv("HLF_OUTPUT")
+
+	cfg, err := Load(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.FabricVersion != DefaultFabricVersion {
+		t.Errorf("FabricVersion = %s, want %s", cfg.FabricVersion, DefaultFabricVersion)
+	}
+	if cfg.Registry != "registry.example.com/hyperledger" {
+		t.Errorf("Registry = %s, want registry.example.com/hyperledger", cfg.Registry)
+	}
+	if cfg.Parallelism != 2 {
+		t.Errorf("Parallelism = %d, want 2", cfg.Parallelism)
+	}
+	if cfg.Output != OutputJSON {
+		t.Errorf("Output = %s, want %s", cfg.Output, OutputJSON)
+	}
+}
+
+func TestExpandHome(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ExpandHome(filepath.Join("ci", "job-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(wd, "ci", "job-1"); got != want {
+		t.Errorf("ExpandHome() = %s, want %s", got, want)
+	}
+
+	if _, err := ExpandHome(" "); err == nil {
+		t.Errorf("ExpandHome() of a blank home, want an error")
+	}
+}
+
+func TestLoad_home(t *testing.T) {
+	os.Setenv("HLF_HOME", "/opt/hlf")
+	defer os.Unsetenv("HLF_HOME")
+
+	v := viper.New()
+	Setup(v)
+
+	cfg, err := Load(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := []string{cfg.BinariesDir(), cfg.CacheDir(), cfg.StateDir(), cfg.ConfigFile()}
+	want := []string{"/opt/hlf", "/opt/hlf/cache", "/opt/hlf/state", "/opt/hlf/config.yaml"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("directories = %v, want %v", got, want)
+	}
+}
+
+func TestValidateFile(t *testing.T) {
+	settings := map[string]interface{}{
+		KeyParallelism:   "many",
+		KeyFabricVersion: "1.4.2",
+		KeyHome:          "/opt/hlf",
+		"colour":         "red",
+	}
+
+	problems := ValidateFile(settings)
+
+	var got []string
+	for _, problem := range problems {
+		got = append(got, problem.Error())
+	}
+	want := []string{
+		"unknown key colour",
+		"home can not be set in the config file, use HLF_HOME or --home",
+		`invalid parallelism "many": expected a number`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ValidateFile() = %q, want %q", got, want)
+	}
+}
+
+func TestConfig_CAVersion(t *testing.T) {
+	tests := []struct {
+		name          string
+		caVersion     string
+		fabricVersion string
+		want          string
+	}{
+		{name: "fabric 1.x", fabricVersion: "1.4.4", want: "1.4.4"},
+		{name: "fabric 2.0 to 2.2", fabricVersion: "2.2.0", want: "1.4.9"},
+		{name: "fabric 2.3 and newer", fabricVersion: "2.5.4", want: "1.5.7"},
+		{name: "configured", caVersion: "1.5.2", fabricVersion: "2.2.0", want: "1.5.2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{FabricCAVersion: tt.caVersion}
+			if got := cfg.CAVersion(tt.fabricVersion); got != tt.want {
+				t.Errorf("CAVersion(%s) = %s, want %s", tt.fabricVersion, got, tt.want)
+			}
+		})
+	}
+}
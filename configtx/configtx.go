@@ -0,0 +1,418 @@
+// Package configtx renders the configtx.yaml of a network spec, the input of
+// configtxgen for the orderer genesis block and the channel transactions. The
+// schema changed between fabric versions: organization policies replaced the
+// admin principal in 1.2, orderer endpoints moved to the orderer organizations
+// in 1.4.2 and 2.0 added the endorsement policies of the new chaincode lifecycle.
+// The etcdraft orderer type needs 1.4.1, from 2.3 etcdraft networks have no
+// system channel and the channel profiles carry the orderer configuration.
+package configtx
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+
+	"github.com/gangachris/hlf/semver"
+	"github.com/gangachris/hlf/spec"
+)
+
+const (
+	// ConfigFile is the name of configtx.yaml in the artifacts directory.
+	ConfigFile = "configtx.yaml"
+
+	// GenesisProfile is the profile of the orderer system channel genesis block.
+	GenesisProfile = "OrdererGenesis"
+
+	// Consortium is the consortium of the peer organizations channels are created by.
+	Consortium = "SampleConsortium"
+)
+
+// ChannelParticipation reports whether the orderers of a network join channels
+// with the channel participation API of fabric 2.3 and newer, from the genesis
+// block of each channel instead of a system channel. Solo and kafka orderers
+// do not support it.
+func ChannelParticipation(network *spec.Network) bool {
+	return network.Orderer.Type == spec.OrdererEtcdRaft && atLeast(network.Fabric, "2.3")
+}
+
+// ChannelProfile returns the profile of a channel.
+func ChannelProfile(channel string) string {
+	return channel
+}
+
+// Capabilities are the capability levels of a fabric version.
+type Capabilities struct {
+	Channel     string
+	Orderer     string
+	Application string
+}
+
+// CapabilitiesOf returns the highest capability levels a fabric version supports.
+func CapabilitiesOf(fabricVersion string) Capabilities {
+	switch {
+	case atLeast(fabricVersion, "2.5"):
+		return Capabilities{Channel: "V2_0", Orderer: "V2_0", Application: "V2_5"}
+	case atLeast(fabricVersion, "2.0"):
+		return Capabilities{Channel: "V2_0", Orderer: "V2_0", Application: "V2_0"}
+	case atLeast(fabricVersion, "1.4.3"):
+		return Capabilities{Channel: "V1_4_3", Orderer: "V1_4_2", Application: "V1_4_2"}
+	case atLeast(fabricVersion, "1.4.2"):
+		return Capabilities{Channel: "V1_4_2", Orderer: "V1_4_2", Application: "V1_4_2"}
+	case atLeast(fabricVersion, "1.3"):
+		return Capabilities{Channel: "V1_3", Orderer: "V1_1", Application: "V1_3"}
+	case atLeast(fabricVersion, "1.2"):
+		return Capabilities{Channel: "V1_1", Orderer: "V1_1", Application: "V1_2"}
+	}
+	return Capabilities{Channel: "V1_1", Orderer: "V1_1", Application: "V1_1"}
+}
+
+// atLeast reports whether a fabric version is the minimum version or newer.
+func atLeast(version, minimum string) bool {
+	ok, err := semver.CorrectVersion(minimum, version)
+	return err == nil && ok
+}
+
+// organization is an organization as configtx.yaml describes it.
+type organization struct {
+	Name        string
+	MSPID       string
+	MSPDir      string
+	Endpoints   []string
+	AnchorPeers []string
+}
+
+// consenter is a raft consenter, an orderer node and its TLS certificate.
+type consenter struct {
+	Host    string
+	Port    int
+	TLSCert string
+}
+
+// params are the values of the configtx.yaml template.
+type params struct {
+	Fabric       string
+	Capabilities Capabilities
+
+	// Policies are organization, channel, orderer and application policies, 1.2 and newer.
+	Policies bool
+	// OrdererEndpoints are set on orderer organizations, 1.4.2 and newer.
+	OrdererEndpoints bool
+	// Addresses of the orderers are set on the orderer section, before 2.0.
+	Addresses bool
+	// Lifecycle adds the endorsement policies of the chaincode lifecycle, 2.0 and newer.
+	Lifecycle bool
+	// ChannelParticipation drops the system channel profile, channel profiles
+	// have the orderer section instead of a consortium, etcdraft 2.3 and newer.
+	ChannelParticipation bool
+
+	OrdererType      string
+	OrdererAddresses []string
+	BatchTimeout     string
+	MaxMessageCount  int
+	KafkaBrokers     []string
+	Consenters       []consenter
+	EtcdRaft         spec.EtcdRaft
+
+	OrdererOrgs []organization
+	PeerOrgs    []organization
+	Channels    []spec.Channel
+
+	GenesisProfile string
+	Consortium     string
+}
+
+func newParams(network *spec.Network) params {
+	p := params{
+		Fabric:           network.Fabric,
+		Capabilities:     CapabilitiesOf(network.Fabric),
+		Policies:         atLeast(network.Fabric, "1.2"),
+		OrdererEndpoints: atLeast(network.Fabric, "1.4.2"),
+		Addresses:        !atLeast(network.Fabric, "2.0"),
+		Lifecycle:        atLeast(network.Fabric, "2.0"),
+		OrdererType:      network.Orderer.Type,
+		BatchTimeout:     network.Orderer.BatchTimeout,
+		MaxMessageCount:  network.Orderer.MaxMessageCount,
+		Channels:         network.Channels,
+		GenesisProfile:   GenesisProfile,
+		Consortium:       Consortium,
+	}
+	p.ChannelParticipation = ChannelParticipation(network)
+
+	for _, org := range network.Orderer.Organizations {
+		o := organization{
+			Name:   org.Name,
+			MSPID:  org.MSPID,
+			MSPDir: fmt.Sprintf("crypto-config/ordererOrganizations/%s/msp", org.Domain),
+		}
+		for _, node := range network.OrdererNodes() {
+			if node.Organization.Name == org.Name {
+				address := fmt.Sprintf("%s:%d", node.Host(), spec.OrdererPort)
+				o.Endpoints = append(o.Endpoints, address)
+				p.OrdererAddresses = append(p.OrdererAddresses, address)
+			}
+		}
+		p.OrdererOrgs = append(p.OrdererOrgs, o)
+	}
+
+	if network.Orderer.Type == spec.OrdererEtcdRaft {
+		p.EtcdRaft = network.Orderer.EtcdRaft
+		for _, node := range network.OrdererNodes() {
+			p.Consenters = append(p.Consenters, consenter{
+				Host:    node.Host(),
+				Port:    spec.OrdererPort,
+				TLSCert: fmt.Sprintf("crypto-config/ordererOrganizations/%s/orderers/%s/tls/server.crt", node.Organization.Domain, node.Host()),
+			})
+		}
+	}
+
+	if network.Orderer.Type == spec.OrdererKafka {
+		for i := 0; i < network.Orderer.Kafka.Brokers; i++ {
+			p.KafkaBrokers = append(p.KafkaBrokers, fmt.Sprintf("%s:%d", spec.KafkaHost(i), spec.KafkaPort))
+		}
+	}
+
+	for _, org := range network.Organizations {
+		o := organization{
+			Name:   org.Name,
+			MSPID:  org.MSPID,
+			MSPDir: fmt.Sprintf("crypto-config/peerOrganizations/%s/msp", org.Domain),
+		}
+		for _, anchor := range org.AnchorPeers {
+			o.AnchorPeers = append(o.AnchorPeers, org.PeerHost(anchor))
+		}
+		p.PeerOrgs = append(p.PeerOrgs, o)
+	}
+
+	return p
+}
+
+// Render returns the configtx.yaml of a network spec, for the fabric version of
+// the spec. MSP directories and the TLS certificates of the raft consenters are
+// relative to the artifacts directory.
+func Render(network *spec.Network) ([]byte, error) {
+	if network.Orderer.Type == spec.OrdererEtcdRaft && !atLeast(network.Fabric, "1.4.1") {
+		return nil, fmt.Errorf("error: the %s orderer needs fabric 1.4.1 or later, the network uses %s", spec.OrdererEtcdRaft, network.Fabric)
+	}
+
+	var buf bytes.Buffer
+	if err := configtxTemplate.Execute(&buf, newParams(network)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+var configtxTemplate = template.Must(template.New(ConfigFile).Funcs(template.FuncMap{
+	"peerPort":       func() int { return spec.PeerPort },
+	"channelProfile": ChannelProfile,
+}).Parse(`# Generated by hlf from the network spec for fabric {{ .Fabric }}, changes are overwritten.
+---
+Organizations:
+{{- range .OrdererOrgs }}
+    - &{{ .Name }}
+        Name: {{ .Name }}
+        ID: {{ .MSPID }}
+        MSPDir: {{ .MSPDir }}
+{{- if $.Policies }}
+        Policies:
+            Readers:
+                Type: Signature
+                Rule: "OR('{{ .MSPID }}.member')"
+            Writers:
+                Type: Signature
+                Rule: "OR('{{ .MSPID }}.member')"
+            Admins:
+                Type: Signature
+                Rule: "OR('{{ .MSPID }}.admin')"
+{{- else }}
+        AdminPrincipal: Role.ADMIN
+{{- end }}
+{{- if $.OrdererEndpoints }}
+        OrdererEndpoints:
+{{- range .Endpoints }}
+            - {{ . }}
+{{- end }}
+{{- end }}
+{{- end }}
+{{- range .PeerOrgs }}
+    - &{{ .Name }}
+        Name: {{ .Name }}
+        ID: {{ .MSPID }}
+        MSPDir: {{ .MSPDir }}
+{{- if $.Policies }}
+        Policies:
+            Readers:
+                Type: Signature
+                Rule: "OR('{{ .MSPID }}.admin', '{{ .MSPID }}.peer', '{{ .MSPID }}.client')"
+            Writers:
+                Type: Signature
+                Rule: "OR('{{ .MSPID }}.admin', '{{ .MSPID }}.client')"
+            Admins:
+                Type: Signature
+                Rule: "OR('{{ .MSPID }}.admin')"
+{{- if $.Lifecycle }}
+            Endorsement:
+                Type: Signature
+                Rule: "OR('{{ .MSPID }}.peer')"
+{{- end }}
+{{- else }}
+        AdminPrincipal: Role.ADMIN
+{{- end }}
+        AnchorPeers:
+{{- range .AnchorPeers }}
+            - Host: {{ . }}
+              Port: {{ peerPort }}
+{{- end }}
+{{- end }}
+
+Capabilities:
+    Channel: &ChannelCapabilities
+        {{ .Capabilities.Channel }}: true
+    Orderer: &OrdererCapabilities
+        {{ .Capabilities.Orderer }}: true
+    Application: &ApplicationCapabilities
+        {{ .Capabilities.Application }}: true
+
+Application: &ApplicationDefaults
+    Organizations:
+{{- if .Policies }}
+    Policies:
+        Readers:
+            Type: ImplicitMeta
+            Rule: "ANY Readers"
+        Writers:
+            Type: ImplicitMeta
+            Rule: "ANY Writers"
+        Admins:
+            Type: ImplicitMeta
+            Rule: "MAJORITY Admins"
+{{- if .Lifecycle }}
+        LifecycleEndorsement:
+            Type: ImplicitMeta
+            Rule: "MAJORITY Endorsement"
+        Endorsement:
+            Type: ImplicitMeta
+            Rule: "MAJORITY Endorsement"
+{{- end }}
+{{- end }}
+    Capabilities:
+        <<: *ApplicationCapabilities
+
+Orderer: &OrdererDefaults
+    OrdererType: {{ .OrdererType }}
+{{- if .Addresses }}
+    Addresses:
+{{- range .OrdererAddresses }}
+        - {{ . }}
+{{- end }}
+{{- end }}
+    BatchTimeout: {{ .BatchTimeout }}
+    BatchSize:
+        MaxMessageCount: {{ .MaxMessageCount }}
+        AbsoluteMaxBytes: 99 MB
+        PreferredMaxBytes: 512 KB
+{{- if .KafkaBrokers }}
+    Kafka:
+        Brokers:
+{{- range .KafkaBrokers }}
+            - {{ . }}
+{{- end }}
+{{- end }}
+{{- if .Consenters }}
+    EtcdRaft:
+        Consenters:
+{{- range .Consenters }}
+            - Host: {{ .Host }}
+              Port: {{ .Port }}
+              ClientTLSCert: {{ .TLSCert }}
+              ServerTLSCert: {{ .TLSCert }}
+{{- end }}
+        Options:
+            TickInterval: {{ .EtcdRaft.TickInterval }}
+            ElectionTick: {{ .EtcdRaft.ElectionTick }}
+            HeartbeatTick: {{ .EtcdRaft.HeartbeatTick }}
+            MaxInflightBlocks: {{ .EtcdRaft.MaxInflightBlocks }}
+            SnapshotIntervalSize: {{ .EtcdRaft.SnapshotIntervalSize }}
+{{- end }}
+    Organizations:
+{{- if .Policies }}
+    Policies:
+        Readers:
+            Type: ImplicitMeta
+            Rule: "ANY Readers"
+        Writers:
+            Type: ImplicitMeta
+            Rule: "ANY Writers"
+        Admins:
+            Type: ImplicitMeta
+            Rule: "MAJORITY Admins"
+        BlockValidation:
+            Type: ImplicitMeta
+            Rule: "ANY Writers"
+
+Channel: &ChannelDefaults
+    Policies:
+        Readers:
+            Type: ImplicitMeta
+            Rule: "ANY Readers"
+        Writers:
+            Type: ImplicitMeta
+            Rule: "ANY Writers"
+        Admins:
+            Type: ImplicitMeta
+            Rule: "MAJORITY Admins"
+    Capabilities:
+        <<: *ChannelCapabilities
+{{- end }}
+
+Profiles:
+{{- if not .ChannelParticipation }}
+    {{ .GenesisProfile }}:
+{{- if .Policies }}
+        <<: *ChannelDefaults
+{{- else }}
+        Capabilities:
+            <<: *ChannelCapabilities
+{{- end }}
+        Orderer:
+            <<: *OrdererDefaults
+            Organizations:
+{{- range .OrdererOrgs }}
+                - *{{ .Name }}
+{{- end }}
+            Capabilities:
+                <<: *OrdererCapabilities
+        Consortiums:
+            {{ .Consortium }}:
+                Organizations:
+{{- range .PeerOrgs }}
+                    - *{{ .Name }}
+{{- end }}
+{{- end }}
+{{- range .Channels }}
+    {{ channelProfile .Name }}:
+{{- if $.ChannelParticipation }}
+        <<: *ChannelDefaults
+        Orderer:
+            <<: *OrdererDefaults
+            Organizations:
+{{- range $.OrdererOrgs }}
+                - *{{ .Name }}
+{{- end }}
+            Capabilities:
+                <<: *OrdererCapabilities
+{{- else }}
+        Consortium: {{ $.Consortium }}
+{{- if $.Policies }}
+        <<: *ChannelDefaults
+{{- end }}
+{{- end }}
+        Application:
+            <<: *ApplicationDefaults
+            Organizations:
+{{- range .Organizations }}
+                - *{{ . }}
+{{- end }}
+            Capabilities:
+                <<: *ApplicationCapabilities
+{{- end }}
+`))
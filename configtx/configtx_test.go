@@ -0,0 +1,166 @@
+package configtx
+
+import (
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/gangachris/hlf/spec"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// update rewrites the golden files: go test ./configtx -update
+var update = flag.Bool("update", false, "update the golden files")
+
+const twoOrgs = `version: 1
+name: dev
+organizations:
+  - name: Org1
+    peers: 2
+  - name: Org2
+    anchorPeers: [0]
+channels:
+  - name: mychannel
+  - name: private
+    organizations: [Org1]
+`
+
+const kafka = `version: 1
+name: dev
+orderer:
+  type: kafka
+  batchTimeout: 1s
+  maxMessageCount: 50
+  organizations:
+    - name: Orderer
+      nodes: 2
+organizations:
+  - name: Org1
+channels:
+  - name: mychannel
+`
+
+const etcdRaft = `version: 1
+name: dev
+orderer:
+  type: etcdraft
+  etcdRaft:
+    tickInterval: 250ms
+    electionTick: 20
+  organizations:
+    - name: Orderer
+      nodes: 2
+    - name: Orderer2
+organizations:
+  - name: Org1
+channels:
+  - name: mychannel
+`
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name   string
+		spec   string
+		fabric string
+	}{
+		{name: "two-orgs-1.1", spec: twoOrgs, fabric: "1.1.0"},
+		{name: "two-orgs-1.4", spec: twoOrgs, fabric: "1.4.4"},
+		{name: "two-orgs-2.2", spec: twoOrgs, fabric: "2.2.0"},
+		{name: "kafka-1.4", spec: kafka, fabric: "1.4.1"},
+		{name: "etcdraft-2.2", spec: etcdRaft, fabric: "2.2.0"},
+		{name: "etcdraft-2.3", spec: etcdRaft, fabric: "2.3.0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			network, err := spec.Parse(spec.FileName, []byte(tt.spec))
+			if err != nil {
+				t.Fatal(err)
+			}
+			network.Fabric = tt.fabric
+
+			got, err := Render(network)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			golden := filepath.Join("testdata", tt.name+".golden")
+			if *update {
+				if err := ioutil.WriteFile(golden, got, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			want, err := ioutil.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != string(want) {
+				t.Errorf("Render() =\n%s\nwant\n%s", got, want)
+			}
+
+			// the anchors and merge keys resolve to one profile per channel
+			var config struct {
+				Profiles map[string]interface{} `yaml:"Profiles"`
+			}
+			if err := yaml.Unmarshal(got, &config); err != nil {
+				t.Fatalf("Render() is not valid yaml: %v", err)
+			}
+
+			var profiles []string
+			for profile := range config.Profiles {
+				profiles = append(profiles, profile)
+			}
+			sort.Strings(profiles)
+
+			// etcdraft networks of 2.3 and newer have no system channel
+			var wantProfiles []string
+			if !ChannelParticipation(network) {
+				wantProfiles = append(wantProfiles, GenesisProfile)
+			}
+			for _, channel := range network.Channels {
+				wantProfiles = append(wantProfiles, ChannelProfile(channel.Name))
+			}
+			sort.Strings(wantProfiles)
+			if !reflect.DeepEqual(profiles, wantProfiles) {
+				t.Errorf("Render() profiles = %v, want %v", profiles, wantProfiles)
+			}
+		})
+	}
+}
+
+func TestRender_etcdRaftVersion(t *testing.T) {
+	network, err := spec.Parse(spec.FileName, []byte(etcdRaft))
+	if err != nil {
+		t.Fatal(err)
+	}
+	network.Fabric = "1.4.0"
+
+	if _, err := Render(network); err == nil {
+		t.Errorf("Render() error = nil, want etcdraft to need fabric 1.4.1")
+	}
+}
+
+func TestCapabilitiesOf(t *testing.T) {
+	tests := []struct {
+		fabric string
+		want   Capabilities
+	}{
+		{"1.1.0", Capabilities{Channel: "V1_1", Orderer: "V1_1", Application: "V1_1"}},
+		{"1.2", Capabilities{Channel: "V1_1", Orderer: "V1_1", Application: "V1_2"}},
+		{"1.3.0", Capabilities{Channel: "V1_3", Orderer: "V1_1", Application: "V1_3"}},
+		{"1.4.2", Capabilities{Channel: "V1_4_2", Orderer: "V1_4_2", Application: "V1_4_2"}},
+		{"1.4.12", Capabilities{Channel: "V1_4_3", Orderer: "V1_4_2", Application: "V1_4_2"}},
+		{"2.2.0", Capabilities{Channel: "V2_0", Orderer: "V2_0", Application: "V2_0"}},
+		{"2.5.4", Capabilities{Channel: "V2_0", Orderer: "V2_0", Application: "V2_5"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fabric, func(t *testing.T) {
+			if got := CapabilitiesOf(tt.fabric); got != tt.want {
+				t.Errorf("CapabilitiesOf() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
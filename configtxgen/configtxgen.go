@@ -0,0 +1,161 @@
+// Package configtxgen runs the fabric configtxgen tool on the configtx.yaml of
+// a network to produce the orderer genesis block, the channel creation
+// transactions and the anchor peer updates. Networks using the channel
+// participation API of fabric 2.3 get the genesis block of each channel
+// instead of the orderer genesis block and the creation transactions.
+package configtxgen
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	// OutputDir is the directory of the channel artifacts in the artifacts directory.
+	OutputDir = "channel-artifacts"
+
+	// GenesisBlockFile is the name of the orderer genesis block.
+	GenesisBlockFile = "genesis.block"
+
+	// SystemChannel is the ID of the orderer system channel.
+	SystemChannel = "system-channel"
+)
+
+// ChannelTxFile returns the name of the creation transaction of a channel.
+func ChannelTxFile(channel string) string {
+	return channel + ".tx"
+}
+
+// ChannelBlockFile returns the name of the genesis block of a channel.
+func ChannelBlockFile(channel string) string {
+	return channel + ".block"
+}
+
+// AnchorPeersFile returns the name of the anchor peer update of an organization
+// on a channel.
+func AnchorPeersFile(channel, mspID string) string {
+	return fmt.Sprintf("%s-%sanchors.tx", channel, mspID)
+}
+
+// Error is a failed run of configtxgen.
+type Error struct {
+	// Args are the arguments configtxgen was run with.
+	Args []string
+	// Err is the error of the process, usually its exit status.
+	Err error
+	// Message is the fatal or error message configtxgen logged, or its whole
+	// output when it logged none.
+	Message string
+	// Output is what configtxgen wrote to stderr.
+	Output string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("error running configtxgen %s: %s", strings.Join(e.Args, " "), e.Message)
+}
+
+// logPattern matches the log lines of fabric tools e.g
+// 2019-11-20 10:20:16.374 UTC [common.tools.configtxgen] main -> FATA 002 Error on outputBlock: ...
+var logPattern = regexp.MustCompile(`-> (FATA|PANI|ERRO) \w+ (.*)$`)
+
+// newError returns the error of a run, the message is the last fatal or error
+// line of the output.
+func newError(args []string, err error, output string) *Error {
+	output = strings.TrimSpace(output)
+	message := output
+	for _, line := range strings.Split(output, "\n") {
+		if match := logPattern.FindStringSubmatch(line); match != nil {
+			message = match[2]
+		}
+	}
+	if message == "" {
+		message = err.Error()
+	}
+	return &Error{Args: args, Err: err, Message: message, Output: output}
+}
+
+// Tool runs a configtxgen binary.
+type Tool struct {
+	// Path is the path of the configtxgen binary.
+	Path string
+	// ConfigDir is the directory of configtx.yaml, FABRIC_CFG_PATH of configtxgen.
+	// Relative MSP directories of configtx.yaml are relative to it.
+	ConfigDir string
+}
+
+// GenesisBlock writes the genesis block of the orderer system channel.
+func (t Tool) GenesisBlock(ctx context.Context, profile, output string) error {
+	return t.run(ctx, "-profile", profile, "-channelID", SystemChannel, "-outputBlock", output)
+}
+
+// ChannelBlock writes the genesis block of an application channel, to join the
+// orderers with the channel participation API of fabric 2.3 and newer.
+func (t Tool) ChannelBlock(ctx context.Context, profile, channel, output string) error {
+	return t.run(ctx, "-profile", profile, "-channelID", channel, "-outputBlock", output)
+}
+
+// ChannelTx writes the creation transaction of a channel.
+func (t Tool) ChannelTx(ctx context.Context, profile, channel, output string) error {
+	return t.run(ctx, "-profile", profile, "-channelID", channel, "-outputCreateChannelTx", output)
+}
+
+// AnchorPeers writes the anchor peer update of an organization on a channel,
+// org is the name of the organization in configtx.yaml.
+func (t Tool) AnchorPeers(ctx context.Context, profile, channel, org, output string) error {
+	return t.run(ctx, "-profile", profile, "-channelID", channel, "-outputAnchorPeersUpdate", output, "-asOrg", org)
+}
+
+var versionPattern = regexp.MustCompile(`(?m)^\s*Version:\s*v?(\S+)`)
+
+// Version returns the fabric version of the binary.
+func (t Tool) Version(ctx context.Context) (string, error) {
+	out, err := t.output(ctx, "-version")
+	if err != nil {
+		return "", err
+	}
+
+	match := versionPattern.FindStringSubmatch(out)
+	if match == nil {
+		return "", fmt.Errorf("error reading the version of %s: %s", t.Path, strings.TrimSpace(out))
+	}
+	return match[1], nil
+}
+
+func (t Tool) run(ctx context.Context, args ...string) error {
+	_, err := t.output(ctx, args...)
+	return err
+}
+
+func (t Tool) output(ctx context.Context, args ...string) (string, error) {
+	if _, err := os.Stat(t.Path); err != nil {
+		return "", fmt.Errorf("error: configtxgen not found at %s, run hlf download binaries", t.Path)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, t.Path, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.Env = os.Environ()
+	if t.ConfigDir != "" {
+		dir, err := filepath.Abs(t.ConfigDir)
+		if err != nil {
+			return "", err
+		}
+		cmd.Env = append(cmd.Env, "FABRIC_CFG_PATH="+dir)
+	}
+
+	if err := cmd.Run(); err != nil {
+		output := stderr.String()
+		if strings.TrimSpace(output) == "" {
+			output = stdout.String()
+		}
+		return "", newError(args, err, output)
+	}
+	return stdout.String(), nil
+}
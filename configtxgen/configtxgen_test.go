@@ -0,0 +1,113 @@
+package configtxgen
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeConfigtxgen writes a configtxgen script logging FABRIC_CFG_PATH and its
+// arguments to log.
+func fakeConfigtxgen(t *testing.T, dir, script string) Tool {
+	path := filepath.Join(dir, "configtxgen")
+	content := "#!/bin/sh\necho \"$FABRIC_CFG_PATH $@\" >> " + filepath.Join(dir, "log") + "\n" + script + "\n"
+	if err := ioutil.WriteFile(path, []byte(content), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return Tool{Path: path, ConfigDir: dir}
+}
+
+func TestTool(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hlf-configtxgen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ctx := context.Background()
+
+	tool := fakeConfigtxgen(t, dir, `case "$1" in
+-version) printf 'configtxgen:\n Version: 2.2.0\n Commit SHA: 5ea85bc\n';;
+esac
+case "$5" in
+-outputAnchorPeersUpdate)
+	echo "2020-07-09 10:20:16.374 UTC [common.tools.configtxgen] main -> INFO 001 Loading configuration" >&2
+	echo "2020-07-09 10:20:16.375 UTC [common.tools.configtxgen] main -> FATA 002 Error on inspectChannelCreateTx: org 'Org3' not found" >&2
+	exit 1;;
+esac`)
+
+	version, err := tool.Version(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != "2.2.0" {
+		t.Errorf("Version() = %s, want 2.2.0", version)
+	}
+
+	if err := tool.GenesisBlock(ctx, "OrdererGenesis", "genesis.block"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tool.ChannelTx(ctx, "mychannel", "mychannel", "mychannel.tx"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tool.ChannelBlock(ctx, "mychannel", "mychannel", "mychannel.block"); err != nil {
+		t.Fatal(err)
+	}
+
+	err = tool.AnchorPeers(ctx, "mychannel", "mychannel", "Org3", "anchors.tx")
+	configtxgenErr, ok := err.(*Error)
+	if !ok {
+		t.Fatalf("AnchorPeers() error = %v, want an *Error", err)
+	}
+	if want := "Error on inspectChannelCreateTx: org 'Org3' not found"; configtxgenErr.Message != want {
+		t.Errorf("Error.Message = %q, want %q", configtxgenErr.Message, want)
+	}
+	if !strings.Contains(configtxgenErr.Output, "Loading configuration") {
+		t.Errorf("Error.Output = %q, want the stderr of configtxgen", configtxgenErr.Output)
+	}
+
+	log, err := ioutil.ReadFile(filepath.Join(dir, "log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := dir + " -version\n" +
+		dir + " -profile OrdererGenesis -channelID system-channel -outputBlock genesis.block\n" +
+		dir + " -profile mychannel -channelID mychannel -outputCreateChannelTx mychannel.tx\n" +
+		dir + " -profile mychannel -channelID mychannel -outputBlock mychannel.block\n" +
+		dir + " -profile mychannel -channelID mychannel -outputAnchorPeersUpdate anchors.tx -asOrg Org3\n"
+	if string(log) != want {
+		t.Errorf("arguments =\n%s\nwant\n%s", log, want)
+	}
+
+	missing := Tool{Path: filepath.Join(dir, "missing")}
+	if err := missing.GenesisBlock(ctx, "OrdererGenesis", "genesis.block"); err == nil || !strings.Contains(err.Error(), "hlf download binaries") {
+		t.Errorf("GenesisBlock() error = %v, want a hint to download binaries", err)
+	}
+}
+
+func Test_newError(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   string
+	}{
+		{
+			name:   "fatal",
+			output: "[common.tools.configtxgen] main -> WARN 001 Deprecated\n[common.tools.configtxgen] main -> FATA 002 Error on outputBlock: config requires unknown capabilities",
+			want:   "Error on outputBlock: config requires unknown capabilities",
+		},
+		{name: "plain", output: "flag provided but not defined: -outputBlocks\n", want: "flag provided but not defined: -outputBlocks"},
+		{name: "empty", output: "", want: "exit status 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := newError([]string{"-outputBlock"}, errors.New("exit status 1"), tt.output)
+			if err.Message != tt.want {
+				t.Errorf("newError().Message = %q, want %q", err.Message, tt.want)
+			}
+		})
+	}
+}
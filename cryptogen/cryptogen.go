@@ -0,0 +1,182 @@
+// Package cryptogen writes the crypto-config.yaml of a network spec and runs
+// the fabric cryptogen tool on it to produce the MSP and TLS material of the
+// organizations, nodes and users.
+package cryptogen
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"github.com/gangachris/hlf/spec"
+	yaml "gopkg.in/yaml.v2"
+)
+
+const (
+	// ConfigFile is the name of the cryptogen config in the artifacts directory.
+	ConfigFile = "crypto-config.yaml"
+
+	// OutputDir is the directory cryptogen writes to in the artifacts directory.
+	OutputDir = "crypto-config"
+)
+
+// Config is a crypto-config.yaml.
+type Config struct {
+	OrdererOrgs []OrgSpec `yaml:"OrdererOrgs"`
+	PeerOrgs    []OrgSpec `yaml:"PeerOrgs"`
+}
+
+// OrgSpec is an organization of a crypto-config.yaml.
+type OrgSpec struct {
+	Name          string        `yaml:"Name"`
+	Domain        string        `yaml:"Domain"`
+	EnableNodeOUs bool          `yaml:"EnableNodeOUs"`
+	CA            *CASpec       `yaml:"CA,omitempty"`
+	Specs         []NodeSpec    `yaml:"Specs,omitempty"`
+	Template      *NodeTemplate `yaml:"Template,omitempty"`
+	Users         *UsersSpec    `yaml:"Users,omitempty"`
+}
+
+// CASpec are the subject fields of the certificates of an organization.
+type CASpec struct {
+	Country            string `yaml:"Country,omitempty"`
+	Province           string `yaml:"Province,omitempty"`
+	Locality           string `yaml:"Locality,omitempty"`
+	OrganizationalUnit string `yaml:"OrganizationalUnit,omitempty"`
+}
+
+// NodeSpec is a node with an explicit host name.
+type NodeSpec struct {
+	Hostname string   `yaml:"Hostname"`
+	SANS     []string `yaml:"SANS,omitempty"`
+}
+
+// NodeTemplate generates Count nodes named peer0, peer1...
+type NodeTemplate struct {
+	Count int      `yaml:"Count"`
+	SANS  []string `yaml:"SANS,omitempty"`
+}
+
+// UsersSpec is the number of users besides the admin.
+type UsersSpec struct {
+	Count int `yaml:"Count"`
+}
+
+// sans are the subject alternative names of every node certificate besides its
+// fully qualified name: the host name, which is also the name of its container
+// on the network, and localhost to reach the ports exposed on the host.
+var sans = []string{"{{.Hostname}}", "localhost", "127.0.0.1"}
+
+// FromSpec returns the crypto-config of a network spec, node host names are the
+// ones of the spec e.g orderer.example.com and peer0.org1.example.com.
+func FromSpec(network *spec.Network) Config {
+	nodeSANs := append(append([]string{}, sans...), network.Crypto.SANs...)
+
+	var ca *CASpec
+	if subject := network.Crypto.Subject; subject != (spec.Subject{}) {
+		ca = &CASpec{
+			Country:            subject.Country,
+			Province:           subject.Province,
+			Locality:           subject.Locality,
+			OrganizationalUnit: subject.OrganizationalUnit,
+		}
+	}
+
+	var config Config
+	for _, org := range network.Orderer.Organizations {
+		orgSpec := OrgSpec{
+			Name:          org.Name,
+			Domain:        org.Domain,
+			EnableNodeOUs: true,
+			CA:            ca,
+		}
+		for _, node := range network.OrdererNodes() {
+			if node.Organization.Name == org.Name {
+				orgSpec.Specs = append(orgSpec.Specs, NodeSpec{Hostname: node.Name(), SANS: nodeSANs})
+			}
+		}
+		config.OrdererOrgs = append(config.OrdererOrgs, orgSpec)
+	}
+
+	for _, org := range network.Organizations {
+		config.PeerOrgs = append(config.PeerOrgs, OrgSpec{
+			Name:          org.Name,
+			Domain:        org.Domain,
+			EnableNodeOUs: true,
+			CA:            ca,
+			Template:      &NodeTemplate{Count: org.Peers, SANS: nodeSANs},
+			Users:         &UsersSpec{Count: org.Users},
+		})
+	}
+	return config
+}
+
+// Marshal encodes the config to yaml.
+func (c Config) Marshal() ([]byte, error) {
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte("# Generated by hlf from the network spec, changes are overwritten.\n"), data...), nil
+}
+
+// Tool runs a cryptogen binary.
+type Tool struct {
+	// Path is the path of the cryptogen binary.
+	Path string
+}
+
+// Generate writes the crypto material of a config to an output directory.
+func (t Tool) Generate(ctx context.Context, configFile, output string) error {
+	return t.run(ctx, "generate", "--config="+configFile, "--output="+output)
+}
+
+// Extend adds the crypto material of the organizations and nodes added to a
+// config to an output directory, the existing material is kept.
+func (t Tool) Extend(ctx context.Context, configFile, output string) error {
+	return t.run(ctx, "extend", "--config="+configFile, "--input="+output)
+}
+
+var versionPattern = regexp.MustCompile(`(?m)^\s*Version:\s*v?(\S+)`)
+
+// Version returns the fabric version of the binary.
+func (t Tool) Version(ctx context.Context) (string, error) {
+	out, err := t.output(ctx, "version")
+	if err != nil {
+		return "", err
+	}
+
+	match := versionPattern.FindStringSubmatch(out)
+	if match == nil {
+		return "", fmt.Errorf("error reading the version of %s: %s", t.Path, strings.TrimSpace(out))
+	}
+	return match[1], nil
+}
+
+func (t Tool) run(ctx context.Context, args ...string) error {
+	_, err := t.output(ctx, args...)
+	return err
+}
+
+func (t Tool) output(ctx context.Context, args ...string) (string, error) {
+	if _, err := os.Stat(t.Path); err != nil {
+		return "", fmt.Errorf("error: cryptogen not found at %s, run hlf download binaries", t.Path)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, t.Path, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		message := strings.TrimSpace(stderr.String())
+		if message == "" {
+			message = strings.TrimSpace(stdout.String())
+		}
+		return "", fmt.Errorf("error running cryptogen %s: %s: %s", args[0], err.Error(), message)
+	}
+	return stdout.String(), nil
+}
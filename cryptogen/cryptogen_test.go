@@ -0,0 +1,136 @@
+package cryptogen
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gangachris/hlf/spec"
+)
+
+func TestFromSpec(t *testing.T) {
+	network, err := spec.Parse(spec.FileName, []byte(`version: 1
+name: dev
+orderer:
+  type: kafka
+  organizations:
+    - name: Orderer
+      nodes: 2
+organizations:
+  - name: Org1
+    peers: 2
+    users: 3
+  - name: Org2
+    domain: partner.example.org
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := FromSpec(network).Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `# Generated by hlf from the network spec, changes are overwritten.
+OrdererOrgs:
+- Name: Orderer
+  Domain: example.com
+  EnableNodeOUs: true
+  Specs:
+  - Hostname: orderer0
+    SANS:
+    - '{{.Hostname}}'
+    - localhost
+    - 127.0.0.1
+  - Hostname: orderer1
+    SANS:
+    - '{{.Hostname}}'
+    - localhost
+    - 127.0.0.1
+PeerOrgs:
+- Name: Org1
+  Domain: org1.example.com
+  EnableNodeOUs: true
+  Template:
+    Count: 2
+    SANS:
+    - '{{.Hostname}}'
+    - localhost
+    - 127.0.0.1
+  Users:
+    Count: 3
+- Name: Org2
+  Domain: partner.example.org
+  EnableNodeOUs: true
+  Template:
+    Count: 1
+    SANS:
+    - '{{.Hostname}}'
+    - localhost
+    - 127.0.0.1
+  Users:
+    Count: 0
+`
+	if string(data) != want {
+		t.Errorf("Marshal() =\n%s\nwant\n%s", data, want)
+	}
+}
+
+// fakeCryptogen writes a cryptogen script logging its arguments to log.
+func fakeCryptogen(t *testing.T, dir, script string) Tool {
+	path := filepath.Join(dir, "cryptogen")
+	content := "#!/bin/sh\necho \"$@\" >> " + filepath.Join(dir, "log") + "\n" + script + "\n"
+	if err := ioutil.WriteFile(path, []byte(content), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return Tool{Path: path}
+}
+
+func TestTool(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hlf-cryptogen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ctx := context.Background()
+
+	tool := fakeCryptogen(t, dir, `case "$1" in
+version) printf 'cryptogen:\n Version: 1.4.4\n Commit SHA: 7917a40\n';;
+extend) echo "extend is broken" >&2; exit 1;;
+esac`)
+
+	version, err := tool.Version(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != "1.4.4" {
+		t.Errorf("Version() = %s, want 1.4.4", version)
+	}
+
+	if err := tool.Generate(ctx, "crypto-config.yaml", "crypto-config"); err != nil {
+		t.Fatal(err)
+	}
+
+	err = tool.Extend(ctx, "crypto-config.yaml", "crypto-config")
+	if err == nil || !strings.Contains(err.Error(), "extend is broken") {
+		t.Errorf("Extend() error = %v, want the stderr of cryptogen", err)
+	}
+
+	log, err := ioutil.ReadFile(filepath.Join(dir, "log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "version\ngenerate --config=crypto-config.yaml --output=crypto-config\nextend --config=crypto-config.yaml --input=crypto-config\n"
+	if string(log) != want {
+		t.Errorf("arguments =\n%s\nwant\n%s", log, want)
+	}
+
+	missing := Tool{Path: filepath.Join(dir, "missing")}
+	if err := missing.Generate(ctx, "crypto-config.yaml", "crypto-config"); err == nil || !strings.Contains(err.Error(), "hlf download binaries") {
+		t.Errorf("Generate() error = %v, want a hint to download binaries", err)
+	}
+}
@@ -0,0 +1,201 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+)
+
+// chaincodePrefix starts the names of the chaincode containers and images peers
+// create, dev-<peer>-<chaincode>-<version> with the default network id of fabric.
+const chaincodePrefix = "dev-"
+
+// chaincodePeer returns the peer among peers that created a chaincode container
+// or image, named dev-<peer>-<chaincode>-<version>. Image names are lower cased
+// by fabric and end with a hash.
+func chaincodePeer(name string, peers []string) (string, bool) {
+	name = strings.ToLower(name)
+	for _, peer := range peers {
+		prefix := chaincodePrefix + strings.ToLower(peer) + "-"
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+
+		// <chaincode>-<version>, both of them are set
+		rest := strings.TrimPrefix(name, prefix)
+		if i := strings.Index(rest, "-"); i > 0 && i < len(rest)-1 {
+			return peer, true
+		}
+	}
+	return "", false
+}
+
+// chaincodeNetworkPeers returns the peers among peers of the docker networks a
+// chaincode container runs in. Peers run their chaincode containers in their
+// own docker network.
+func chaincodeNetworkPeers(cont types.Container, peers map[string][]string) []string {
+	var networkPeers []string
+	for network, names := range peers {
+		attached := cont.HostConfig.NetworkMode == network
+		if cont.NetworkSettings != nil {
+			if _, ok := cont.NetworkSettings.Networks[network]; ok {
+				attached = true
+			}
+		}
+		if attached {
+			networkPeers = append(networkPeers, names...)
+		}
+	}
+	return networkPeers
+}
+
+// ChaincodeArtifact is a chaincode container or image.
+type ChaincodeArtifact struct {
+	ID   string
+	Name string
+
+	// Size is the size of the writable layer of containers and of images.
+	Size int64
+
+	// Running is set on running containers, they are only removed with force.
+	Running bool
+
+	// Image is the image ID of containers.
+	Image string
+}
+
+// ChaincodeArtifacts are the containers and images peers create for chaincodes.
+type ChaincodeArtifacts struct {
+	Containers []ChaincodeArtifact
+	Images     []ChaincodeArtifact
+}
+
+// Empty reports whether no artifacts were found.
+func (a ChaincodeArtifacts) Empty() bool {
+	return len(a.Containers) == 0 && len(a.Images) == 0
+}
+
+// Size is the disk space used by the artifacts.
+func (a ChaincodeArtifacts) Size() int64 {
+	var size int64
+	for _, artifact := range append(a.Containers, a.Images...) {
+		size += artifact.Size
+	}
+	return size
+}
+
+// ChaincodeFilter selects chaincode artifacts.
+type ChaincodeFilter struct {
+	// Peers are the peers whose chaincodes are selected, by the docker network
+	// of their hlf network. Peers with the same name in other hlf networks run
+	// their chaincode containers in other docker networks, those are not
+	// selected, nor are the images they use.
+	Peers map[string][]string
+}
+
+// FindChaincodes returns the chaincode containers and images selected by the
+// filter, they are identified by fabric's naming convention.
+func (c Client) FindChaincodes(ctx context.Context, filter ChaincodeFilter) (*ChaincodeArtifacts, error) {
+	containers, err := c.api.ContainerList(ctx, types.ContainerListOptions{All: true, Size: true})
+	if err != nil {
+		return nil, err
+	}
+
+	var peers []string
+	for _, names := range filter.Peers {
+		peers = append(peers, names...)
+	}
+
+	// images are selected unless a container that is not selected uses them
+	artifacts := &ChaincodeArtifacts{}
+	imagesInUse := map[string]bool{}
+	for _, cont := range containers {
+		name := ContainerName(cont)
+		if _, ok := chaincodePeer(name, chaincodeNetworkPeers(cont, filter.Peers)); !ok {
+			imagesInUse[cont.ImageID] = true
+			imagesInUse[cont.Image] = true
+			continue
+		}
+
+		artifacts.Containers = append(artifacts.Containers, ChaincodeArtifact{
+			ID:      cont.ID,
+			Name:    name,
+			Size:    cont.SizeRw,
+			Running: cont.State == "running",
+			Image:   cont.ImageID,
+		})
+	}
+
+	images, err := c.api.ImageList(ctx, types.ImageListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, image := range images {
+		if len(image.RepoTags) == 0 || imagesInUse[image.ID] {
+			continue
+		}
+
+		name := image.RepoTags[0]
+		if _, ok := chaincodePeer(name, peers); ok && !imagesInUse[name] {
+			artifacts.Images = append(artifacts.Images, ChaincodeArtifact{ID: image.ID, Name: name, Size: image.Size})
+		}
+	}
+
+	sort.Slice(artifacts.Containers, func(i, j int) bool { return artifacts.Containers[i].Name < artifacts.Containers[j].Name })
+	sort.Slice(artifacts.Images, func(i, j int) bool { return artifacts.Images[i].Name < artifacts.Images[j].Name })
+
+	return artifacts, nil
+}
+
+// RemoveChaincodes removes chaincode containers, then their images. Running
+// containers and their images are kept unless force is set.
+func (c Client) RemoveChaincodes(ctx context.Context, artifacts *ChaincodeArtifacts, force bool) error {
+	kept := map[string]bool{}
+	for _, cont := range artifacts.Containers {
+		if cont.Running && !force {
+			kept[cont.Image] = true
+			continue
+		}
+		if err := c.Remove(ctx, cont.ID, force); err != nil && !IsNotFound(err) {
+			return fmt.Errorf("error removing chaincode container %s: %s", cont.Name, err.Error())
+		}
+	}
+
+	for _, image := range artifacts.Images {
+		if kept[image.ID] {
+			continue
+		}
+		_, err := c.api.ImageRemove(ctx, image.ID, types.ImageRemoveOptions{Force: force, PruneChildren: true})
+		if err != nil && !IsNotFound(err) {
+			return fmt.Errorf("error removing chaincode image %s: %s", image.Name, err.Error())
+		}
+	}
+
+	return nil
+}
+
+// chaincodeContainers returns the chaincode containers created by the peers,
+// by the docker network of their hlf network.
+func (c Client) chaincodeContainers(ctx context.Context, peers map[string][]string) ([]types.Container, error) {
+	if len(peers) == 0 {
+		return nil, nil
+	}
+
+	containers, err := c.List(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var chaincodes []types.Container
+	for _, cont := range containers {
+		if _, ok := chaincodePeer(ContainerName(cont), chaincodeNetworkPeers(cont, peers)); ok {
+			chaincodes = append(chaincodes, cont)
+		}
+	}
+
+	return chaincodes, nil
+}
@@ -0,0 +1,144 @@
+package docker_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/gangachris/hlf/docker"
+	"github.com/gangachris/hlf/docker/dockertest"
+)
+
+func TestClient_FindChaincodes(t *testing.T) {
+	fake := dockertest.New()
+	client := startPeer(t, fake, "peer0.org1.example.com")
+	ctx := context.Background()
+
+	chaincodes := []struct {
+		container string
+		network   string
+		running   bool
+		image     types.ImageSummary
+	}{
+		{
+			container: "dev-peer0.org1.example.com-mycc-1.0",
+			network:   "hlf_dev",
+			running:   true,
+			image:     types.ImageSummary{RepoTags: []string{"dev-peer0.org1.example.com-mycc-1.0-1a2b:latest"}, Size: 100},
+		},
+		{
+			container: "dev-peer1.org1.example.com-mycc-1.0",
+			network:   "hlf_dev",
+			image:     types.ImageSummary{RepoTags: []string{"dev-peer1.org1.example.com-mycc-1.0-3c4d:latest"}, Size: 200},
+		},
+		{
+			// a peer with the same name in another network
+			container: "dev-peer0.org1.example.com-other-2.0",
+			network:   "hlf_test",
+			image:     types.ImageSummary{RepoTags: []string{"dev-peer0.org1.example.com-other-2.0-5e6f:latest"}, Size: 300},
+		},
+		{
+			// not a chaincode of a peer
+			container: "dev-postgres",
+			network:   "hlf_dev",
+			image:     types.ImageSummary{RepoTags: []string{"dev-postgres:latest"}, Size: 400},
+		},
+	}
+	for _, chaincode := range chaincodes {
+		fake.AddImage(chaincode.image)
+		config := &container.Config{Image: chaincode.image.RepoTags[0]}
+		hostConfig := &container.HostConfig{NetworkMode: container.NetworkMode(chaincode.network)}
+		if _, err := fake.ContainerCreate(ctx, config, hostConfig, nil, chaincode.container); err != nil {
+			t.Fatal(err)
+		}
+		if chaincode.running {
+			if err := client.Start(ctx, chaincode.container); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	tests := []struct {
+		name           string
+		filter         docker.ChaincodeFilter
+		wantContainers []string
+		wantImages     []string
+		wantSize       int64
+	}{
+		{
+			name:           "chaincodes of the peers of a network",
+			filter:         docker.ChaincodeFilter{Peers: map[string][]string{"hlf_dev": {"peer0.org1.example.com", "peer1.org1.example.com"}}},
+			wantContainers: []string{"dev-peer0.org1.example.com-mycc-1.0", "dev-peer1.org1.example.com-mycc-1.0"},
+			wantImages:     []string{"dev-peer0.org1.example.com-mycc-1.0-1a2b:latest", "dev-peer1.org1.example.com-mycc-1.0-3c4d:latest"},
+			wantSize:       300,
+		},
+		{
+			name:           "chaincodes of a peer with the same name in another network",
+			filter:         docker.ChaincodeFilter{Peers: map[string][]string{"hlf_test": {"peer0.org1.example.com"}}},
+			wantContainers: []string{"dev-peer0.org1.example.com-other-2.0"},
+			wantImages:     []string{"dev-peer0.org1.example.com-other-2.0-5e6f:latest"},
+			wantSize:       300,
+		},
+		{
+			name:   "no peers",
+			filter: docker.ChaincodeFilter{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			artifacts, err := client.FindChaincodes(ctx, tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var containers, images []string
+			for _, cont := range artifacts.Containers {
+				containers = append(containers, cont.Name)
+			}
+			for _, image := range artifacts.Images {
+				images = append(images, image.Name)
+			}
+
+			if !reflect.DeepEqual(containers, tt.wantContainers) {
+				t.Errorf("containers = %v, want %v", containers, tt.wantContainers)
+			}
+			if !reflect.DeepEqual(images, tt.wantImages) {
+				t.Errorf("images = %v, want %v", images, tt.wantImages)
+			}
+			if artifacts.Size() != tt.wantSize {
+				t.Errorf("Size() = %d, want %d", artifacts.Size(), tt.wantSize)
+			}
+		})
+	}
+
+	// running chaincodes and their images are only removed with force
+	filter := docker.ChaincodeFilter{Peers: map[string][]string{"hlf_dev": {"peer0.org1.example.com", "peer1.org1.example.com"}}}
+	for _, force := range []bool{false, true} {
+		artifacts, err := client.FindChaincodes(ctx, filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := client.RemoveChaincodes(ctx, artifacts, force); err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = client.Inspect(ctx, "dev-peer0.org1.example.com-mycc-1.0")
+		if force != docker.IsNotFound(err) {
+			t.Errorf("RemoveChaincodes(force %v) running chaincode: %v", force, err)
+		}
+	}
+
+	want := []string{
+		"dev-peer0.org1.example.com-other-2.0-5e6f:latest",
+		"dev-postgres:latest",
+		"hyperledger/fabric-peer:1.1.0",
+	}
+	if got := fake.ImageTags(); !reflect.DeepEqual(got, want) {
+		t.Errorf("images left = %v, want %v", got, want)
+	}
+	if _, err := client.Inspect(ctx, "peer0.org1.example.com"); err != nil {
+		t.Errorf("peer was removed: %s", err.Error())
+	}
+}
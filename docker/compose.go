@@ -0,0 +1,80 @@
+package docker
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/gangachris/hlf/semver"
+)
+
+const (
+	// MinimumDockerComposeVersion is the minimum required docker-compose version for hyperledger fabric to work
+	MinimumDockerComposeVersion = "1.14.0"
+)
+
+// errComposeNotFound is returned when neither compose v2 nor v1 are available.
+var errComposeNotFound = errors.New("error: please make sure docker compose (v2 plugin) or docker-compose is installed")
+
+// Compose represents the docker compose implementation available on the host.
+// Compose v2 ships as a docker CLI plugin (docker compose) and is preferred
+// over the standalone v1 binary (docker-compose).
+type Compose struct {
+	// Plugin is true when compose is the v2 docker CLI plugin.
+	Plugin bool
+
+	// Version is the version reported by compose, without the v prefix.
+	Version string
+}
+
+// DetectCompose looks for compose v2 first and falls back to the
+// standalone docker-compose binary.
+func DetectCompose() (*Compose, error) {
+	if version, err := composeVersion("docker", "compose", "version", "--short"); err == nil {
+		return &Compose{Plugin: true, Version: version}, nil
+	}
+
+	version, err := composeVersion("docker-compose", "version", "--short")
+	if err != nil {
+		return nil, errComposeNotFound
+	}
+
+	return &Compose{Version: version}, nil
+}
+
+// String returns the command used to run compose e.g docker compose.
+func (c Compose) String() string {
+	if c.Plugin {
+		return "docker compose"
+	}
+	return "docker-compose"
+}
+
+// checkVersion makes sure the compose version meets the minimum required version.
+func (c Compose) checkVersion() error {
+	requiredDockerComposeVersion, err := semver.CorrectVersion(MinimumDockerComposeVersion, c.Version)
+	if err != nil {
+		return fmt.Errorf("error checking %s version: %s", c, err.Error())
+	}
+
+	if !requiredDockerComposeVersion {
+		return fmt.Errorf("error: %s version %s or higher is required, %s is installed", c, MinimumDockerComposeVersion, c.Version)
+	}
+
+	return nil
+}
+
+func composeVersion(name string, args ...string) (string, error) {
+	out, err := exec.Command(name, args...).Output()
+	if err != nil {
+		return "", err
+	}
+
+	version := strings.TrimPrefix(strings.TrimSpace(string(out)), "v")
+	if version == "" {
+		return "", fmt.Errorf("error: %s returned an empty version", name)
+	}
+
+	return version, nil
+}
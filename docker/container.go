@@ -0,0 +1,328 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/go-connections/nat"
+)
+
+const (
+	// LabelNetwork is the label holding the name of the hlf network a resource belongs to.
+	LabelNetwork = "hlf.network"
+
+	// LabelOrg is the label holding the organization of a node.
+	LabelOrg = "hlf.org"
+
+	// LabelRole is the label holding the role of a node e.g peer.
+	LabelRole = "hlf.role"
+
+	// LabelNode is the label holding the name of a node e.g peer0.org1.example.com.
+	LabelNode = "hlf.node"
+)
+
+// Role is the role of a fabric node.
+type Role string
+
+const (
+	// RolePeer is a fabric peer.
+	RolePeer Role = "peer"
+
+	// RoleOrderer is a fabric orderer.
+	RoleOrderer Role = "orderer"
+
+	// RoleCA is a fabric certificate authority.
+	RoleCA Role = "ca"
+
+	// RoleCouchDB is the couchdb state database of a peer.
+	RoleCouchDB Role = "couchdb"
+
+	// RoleCLI is the tools container used to run peer commands.
+	RoleCLI Role = "cli"
+
+	// RoleChaincode is a chaincode container launched by a peer.
+	RoleChaincode Role = "chaincode"
+
+	// RoleZookeeper is a zookeeper node of a kafka ordering service.
+	RoleZookeeper Role = "zookeeper"
+
+	// RoleKafka is a kafka broker of a kafka ordering service.
+	RoleKafka Role = "kafka"
+)
+
+// Node identifies a fabric node in an hlf network.
+type Node struct {
+	Network string
+	Org     string
+	Role    Role
+	Name    string
+}
+
+// Labels returns the hlf labels of the node.
+func (n Node) Labels() map[string]string {
+	labels := map[string]string{
+		LabelNetwork: n.Network,
+		LabelRole:    string(n.Role),
+		LabelNode:    n.Name,
+	}
+
+	if n.Org != "" {
+		labels[LabelOrg] = n.Org
+	}
+
+	return labels
+}
+
+// NodeFromLabels returns the node a resource was created for.
+func NodeFromLabels(labels map[string]string) Node {
+	return Node{
+		Network: labels[LabelNetwork],
+		Org:     labels[LabelOrg],
+		Role:    Role(labels[LabelRole]),
+		Name:    labels[LabelNode],
+	}
+}
+
+// Mount is a volume or a host directory mounted in a container.
+type Mount struct {
+	// Source is the volume name, or the host path for bind mounts.
+	Source string
+
+	// Target is the path in the container.
+	Target string
+
+	// Bind is true when Source is a host path.
+	Bind bool
+
+	ReadOnly bool
+}
+
+// ContainerSpec describes a container for a fabric node.
+type ContainerSpec struct {
+	Node Node
+
+	Image      string
+	Cmd        []string
+	Env        []string
+	WorkingDir string
+	Mounts     []Mount
+
+	// Ports maps container ports to host ports.
+	Ports map[int]int
+
+	// Network is the docker network the container is attached to.
+	Network string
+
+	// Aliases are additional names of the container on the network,
+	// the node name is always resolvable.
+	Aliases []string
+
+	// Labels are added to the hlf labels of the node.
+	Labels map[string]string
+
+	// RestartPolicy is the docker restart policy e.g unless-stopped.
+	RestartPolicy string
+}
+
+// CreateContainer creates the container of a node, named after the node.
+func (c Client) CreateContainer(ctx context.Context, spec ContainerSpec) (string, error) {
+	labels := spec.Node.Labels()
+	for key, value := range spec.Labels {
+		labels[key] = value
+	}
+
+	config := &container.Config{
+		Hostname:     spec.Node.Name,
+		Image:        spec.Image,
+		Cmd:          spec.Cmd,
+		Env:          spec.Env,
+		WorkingDir:   spec.WorkingDir,
+		Labels:       labels,
+		ExposedPorts: nat.PortSet{},
+	}
+
+	hostConfig := &container.HostConfig{
+		Binds:         c.binds(spec.Mounts),
+		PortBindings:  nat.PortMap{},
+		RestartPolicy: container.RestartPolicy{Name: spec.RestartPolicy},
+	}
+
+	for containerPort, hostPort := range spec.Ports {
+		port, err := nat.NewPort("tcp", strconv.Itoa(containerPort))
+		if err != nil {
+			return "", err
+		}
+		config.ExposedPorts[port] = struct{}{}
+		hostConfig.PortBindings[port] = []nat.PortBinding{{HostPort: strconv.Itoa(hostPort)}}
+	}
+
+	var networkingConfig *network.NetworkingConfig
+	if spec.Network != "" {
+		hostConfig.NetworkMode = container.NetworkMode(spec.Network)
+
+		endpoint := &network.EndpointSettings{}
+		if c.Runtime.SupportsNetworkAliases() {
+			endpoint.Aliases = append([]string{spec.Node.Name}, spec.Aliases...)
+		}
+
+		networkingConfig = &network.NetworkingConfig{
+			EndpointsConfig: map[string]*network.EndpointSettings{spec.Network: endpoint},
+		}
+	}
+
+	created, err := c.api.ContainerCreate(ctx, config, hostConfig, networkingConfig, spec.Node.Name)
+	if err != nil {
+		return "", fmt.Errorf("error creating container %s: %s", spec.Node.Name, err.Error())
+	}
+
+	return created.ID, nil
+}
+
+// RunContainer creates the container of a node unless it exists and starts it
+// unless it is running, it reports whether the container was created. An
+// existing container of the same hlf network is reused as is, any other
+// container with the name is an error.
+func (c Client) RunContainer(ctx context.Context, spec ContainerSpec) (bool, error) {
+	existing, err := c.api.ContainerInspect(ctx, spec.Node.Name)
+	if err != nil && !IsNotFound(err) {
+		return false, err
+	}
+
+	created := err != nil
+	if created {
+		if _, _, err := c.api.ImageInspectWithRaw(ctx, spec.Image); IsNotFound(err) {
+			return false, fmt.Errorf("error: image %s not found, run hlf download images", spec.Image)
+		}
+		if _, err := c.CreateContainer(ctx, spec); err != nil {
+			return false, err
+		}
+	} else {
+		if err := ownedBy("container", spec.Node.Name, existing.Config.Labels, spec.Node.Network); err != nil {
+			return false, err
+		}
+		if existing.State != nil && existing.State.Running {
+			return false, nil
+		}
+	}
+
+	if err := c.Start(ctx, spec.Node.Name); err != nil {
+		return created, fmt.Errorf("error starting container %s: %s", spec.Node.Name, err.Error())
+	}
+	return created, nil
+}
+
+// binds returns the docker binds of the mounts e.g /host/msp:/etc/hyperledger/msp:ro,z
+func (c Client) binds(mounts []Mount) []string {
+	var binds []string
+	for _, m := range mounts {
+		var options []string
+		if m.ReadOnly {
+			options = append(options, "ro")
+		}
+
+		if m.Bind && c.Runtime.BindOptions() != "" {
+			options = append(options, c.Runtime.BindOptions())
+		}
+
+		bind := m.Source + ":" + m.Target
+		if len(options) > 0 {
+			bind += ":" + strings.Join(options, ",")
+		}
+		binds = append(binds, bind)
+	}
+	return binds
+}
+
+// Start starts a container.
+func (c Client) Start(ctx context.Context, name string) error {
+	return c.api.ContainerStart(ctx, name, types.ContainerStartOptions{})
+}
+
+// Stop stops a container, killing it if it is still running after timeout.
+func (c Client) Stop(ctx context.Context, name string, timeout time.Duration) error {
+	return c.api.ContainerStop(ctx, name, &timeout)
+}
+
+// Remove removes a container and its anonymous volumes. Running containers
+// are only removed when force is true.
+func (c Client) Remove(ctx context.Context, name string, force bool) error {
+	return c.api.ContainerRemove(ctx, name, types.ContainerRemoveOptions{RemoveVolumes: true, Force: force})
+}
+
+// Wait blocks until a container exits and returns its exit code.
+func (c Client) Wait(ctx context.Context, name string) (int64, error) {
+	return c.api.ContainerWait(ctx, name)
+}
+
+// Inspect returns the state and configuration of a container.
+func (c Client) Inspect(ctx context.Context, name string) (types.ContainerJSON, error) {
+	return c.api.ContainerInspect(ctx, name)
+}
+
+// List returns the containers, running or not, with all the given labels
+// sorted by name e.g List(ctx, map[string]string{LabelNetwork: "dev"}).
+func (c Client) List(ctx context.Context, labels map[string]string) ([]types.Container, error) {
+	containers, err := c.api.ContainerList(ctx, types.ContainerListOptions{
+		All:     true,
+		Filters: LabelFilters(labels),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(containers, func(i, j int) bool {
+		return ContainerName(containers[i]) < ContainerName(containers[j])
+	})
+
+	return containers, nil
+}
+
+// RemoveContainers force removes the containers with all the given labels and
+// returns their names, it is used to tear down networks.
+func (c Client) RemoveContainers(ctx context.Context, labels map[string]string) ([]string, error) {
+	containers, err := c.List(ctx, labels)
+	if err != nil {
+		return nil, err
+	}
+
+	var removed []string
+	for _, cont := range containers {
+		name := ContainerName(cont)
+		if err := c.Remove(ctx, cont.ID, true); err != nil && !IsNotFound(err) {
+			return removed, fmt.Errorf("error removing container %s: %s", name, err.Error())
+		}
+		removed = append(removed, name)
+	}
+
+	return removed, nil
+}
+
+// LabelFilters returns filters matching resources with all the given labels,
+// an empty value matches any value of the label.
+func LabelFilters(labels map[string]string) filters.Args {
+	args := filters.NewArgs()
+	for key, value := range labels {
+		if value == "" {
+			args.Add("label", key)
+			continue
+		}
+		args.Add("label", key+"="+value)
+	}
+	return args
+}
+
+// ContainerName returns the name of a listed container without the leading slash.
+func ContainerName(cont types.Container) string {
+	if len(cont.Names) == 0 {
+		return cont.ID
+	}
+	return strings.TrimPrefix(cont.Names[0], "/")
+}
@@ -0,0 +1,166 @@
+package docker_test
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/gangachris/hlf/docker"
+	"github.com/gangachris/hlf/docker/dockertest"
+)
+
+func peerSpec(network, name string) docker.ContainerSpec {
+	return docker.ContainerSpec{
+		Node: docker.Node{
+			Network: network,
+			Org:     "Org1",
+			Role:    docker.RolePeer,
+			Name:    name,
+		},
+		Image: "hyperledger/fabric-peer:1.1.0",
+		Mounts: []docker.Mount{
+			{Source: "/tmp/crypto/peer0/msp", Target: "/etc/hyperledger/fabric/msp", Bind: true, ReadOnly: true},
+			{Source: name, Target: "/var/hyperledger/production"},
+		},
+		Ports:   map[int]int{7051: 7051},
+		Network: network,
+	}
+}
+
+func TestClient_CreateContainer(t *testing.T) {
+	tests := []struct {
+		name        string
+		runtime     docker.Runtime
+		wantBinds   []string
+		wantAliases []string
+	}{
+		{
+			name:    "docker",
+			runtime: docker.RuntimeDocker,
+			wantBinds: []string{
+				"/tmp/crypto/peer0/msp:/etc/hyperledger/fabric/msp:ro",
+				"peer0.org1.example.com:/var/hyperledger/production",
+			},
+			wantAliases: []string{"peer0.org1.example.com"},
+		},
+		{
+			name:    "podman relabels binds and skips aliases",
+			runtime: docker.RuntimePodman,
+			wantBinds: []string{
+				"/tmp/crypto/peer0/msp:/etc/hyperledger/fabric/msp:ro,z",
+				"peer0.org1.example.com:/var/hyperledger/production",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := dockertest.New()
+			fake.AddImage(types.ImageSummary{RepoTags: []string{"hyperledger/fabric-peer:1.1.0"}})
+
+			client := docker.NewWithAPI(fake, tt.runtime)
+			ctx := context.Background()
+			if _, err := client.CreateContainer(ctx, peerSpec("dev", "peer0.org1.example.com")); err != nil {
+				t.Fatalf("CreateContainer() error = %v", err)
+			}
+
+			inspect, err := client.Inspect(ctx, "peer0.org1.example.com")
+			if err != nil {
+				t.Fatalf("Inspect() error = %v", err)
+			}
+
+			wantLabels := map[string]string{
+				docker.LabelNetwork: "dev",
+				docker.LabelOrg:     "Org1",
+				docker.LabelRole:    "peer",
+				docker.LabelNode:    "peer0.org1.example.com",
+			}
+			if !reflect.DeepEqual(inspect.Config.Labels, wantLabels) {
+				t.Errorf("CreateContainer() labels = %v, want %v", inspect.Config.Labels, wantLabels)
+			}
+			if !reflect.DeepEqual(inspect.HostConfig.Binds, tt.wantBinds) {
+				t.Errorf("CreateContainer() binds = %v, want %v", inspect.HostConfig.Binds, tt.wantBinds)
+			}
+			if got := inspect.NetworkSettings.Networks["dev"].Aliases; !reflect.DeepEqual(got, tt.wantAliases) {
+				t.Errorf("CreateContainer() aliases = %v, want %v", got, tt.wantAliases)
+			}
+		})
+	}
+}
+
+func TestClient_RemoveContainers(t *testing.T) {
+	fake := dockertest.New()
+	fake.AddImage(types.ImageSummary{RepoTags: []string{"hyperledger/fabric-peer:1.1.0"}})
+
+	client := docker.NewWithAPI(fake, docker.RuntimeDocker)
+	ctx := context.Background()
+	for _, spec := range []docker.ContainerSpec{
+		peerSpec("dev", "peer1.org1.example.com"),
+		peerSpec("dev", "peer0.org1.example.com"),
+		peerSpec("test", "peer0.org1.test.com"),
+	} {
+		if _, err := client.CreateContainer(ctx, spec); err != nil {
+			t.Fatal(err)
+		}
+		if err := client.Start(ctx, spec.Node.Name); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	removed, err := client.RemoveContainers(ctx, map[string]string{docker.LabelNetwork: "dev"})
+	if err != nil {
+		t.Fatalf("RemoveContainers() error = %v", err)
+	}
+
+	want := []string{"peer0.org1.example.com", "peer1.org1.example.com"}
+	if !reflect.DeepEqual(removed, want) {
+		t.Errorf("RemoveContainers() = %v, want %v", removed, want)
+	}
+
+	remaining, err := client.List(ctx, map[string]string{docker.LabelRole: ""})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(remaining) != 1 || docker.ContainerName(remaining[0]) != "peer0.org1.test.com" {
+		t.Errorf("RemoveContainers() removed containers of another network: %v", remaining)
+	}
+}
+
+func TestClient_RunContainer(t *testing.T) {
+	fake := dockertest.New()
+	fake.AddImage(types.ImageSummary{RepoTags: []string{"hyperledger/fabric-peer:1.1.0"}})
+
+	client := docker.NewWithAPI(fake, docker.RuntimeDocker)
+	ctx := context.Background()
+	spec := peerSpec("dev", "peer0.org1.example.com")
+
+	created, err := client.RunContainer(ctx, spec)
+	if err != nil || !created {
+		t.Fatalf("RunContainer() = %v, %v, want a new container", created, err)
+	}
+
+	// an exited container is started again, a running one is left alone
+	if err := fake.Exit("peer0.org1.example.com", 1); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		created, err = client.RunContainer(ctx, spec)
+		if err != nil || created {
+			t.Fatalf("RunContainer() = %v, %v, want the existing container", created, err)
+		}
+	}
+	if got := len(fake.CallsTo("ContainerStart")); got != 2 {
+		t.Errorf("ContainerStart called %d times, want 2", got)
+	}
+
+	if _, err := client.RunContainer(ctx, peerSpec("test", "peer0.org1.example.com")); err == nil {
+		t.Error("RunContainer() reused the container of another network")
+	}
+
+	missing := peerSpec("dev", "peer1.org1.example.com")
+	missing.Image = "hyperledger/fabric-peer:2.2.0"
+	if _, err := client.RunContainer(ctx, missing); err == nil || !strings.Contains(err.Error(), "hlf download images") {
+		t.Errorf("RunContainer() error = %v, want a hint to download the images", err)
+	}
+}
@@ -2,10 +2,11 @@ package docker
 
 import (
 	"context"
-	"errors"
 	"fmt"
+	"os"
 	"os/exec"
 	"strings"
+	"sync"
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/client"
@@ -17,31 +18,74 @@ const (
 	// MinimumDockerVersion is the minimum required docker version for hyperledger fabric to work
 	MinimumDockerVersion = "17.06.2-ce"
 
-	// MinimumDockerComposeVersion is the minimum required docker-compose version for hyperledger fabric to work
-	MinimumDockerComposeVersion = "1.14.0"
+	// MinimumPodmanVersion is the first podman release whose docker compatible API works with compose
+	MinimumPodmanVersion = "3.0.0"
 )
 
 // Client represents a docker client.
 type Client struct {
-	client *client.Client
+	api API
+
+	// Runtime is the container engine the client talks to.
+	Runtime Runtime
 }
 
-// New creates an instance of our docker client.
-func New() (*Client, error) {
-	cli, err := client.NewEnvClient()
+// New creates an instance of our docker client for the given endpoint.
+func New(endpoint *Endpoint) (*Client, error) {
+	// DOCKER_HOST may come with TLS settings (DOCKER_CERT_PATH, DOCKER_TLS_VERIFY)
+	// which only the env client knows how to handle.
+	if os.Getenv("DOCKER_HOST") != "" {
+		cli, err := client.NewEnvClient()
+		if err != nil {
+			return nil, err
+		}
+		return NewWithAPI(cli, endpoint.Runtime), nil
+	}
+
+	cli, err := client.NewClient(endpoint.Host, client.DefaultVersion, nil, nil)
 	if err != nil {
 		return nil, err
 	}
 
+	return NewWithAPI(cli, endpoint.Runtime), nil
+}
+
+// NewWithAPI creates an instance of our docker client backed by the given API,
+// tests use it with dockertest.Fake.
+func NewWithAPI(api API, runtime Runtime) *Client {
 	c := Client{
-		client: cli,
+		api:     api,
+		Runtime: runtime,
+	}
+
+	return &c
+}
+
+// Installed checks if Docker (or podman) and compose are installed and returns the
+// compose implementation that should be used to drive compose files.
+func Installed(endpoint *Endpoint) (*Compose, error) {
+	if endpoint.Runtime == RuntimePodman {
+		if err := podmanInstalled(); err != nil {
+			return nil, err
+		}
+	} else if err := dockerInstalled(endpoint); err != nil {
+		return nil, err
+	}
+
+	// check if compose is installed, preferring the v2 plugin
+	compose, err := DetectCompose()
+	if err != nil {
+		return nil, err
 	}
 
-	return &c, nil
+	if err := compose.checkVersion(); err != nil {
+		return nil, err
+	}
+
+	return compose, nil
 }
 
-// Installed checks if Docker is installed
-func Installed() error {
+func dockerInstalled(endpoint *Endpoint) error {
 	// check if docker is installed
 	dockerCMD := exec.Command("docker")
 	if err := dockerCMD.Run(); err != nil {
@@ -49,22 +93,20 @@ func Installed() error {
 	}
 
 	// check if docker daemon is running
-	dockerPsCMD := exec.Command("docker", "ps")
+	dockerPsCMD := endpointCommand(endpoint, "docker", "ps")
 	if err := dockerPsCMD.Run(); err != nil {
 		// Docker error string whenever you run docker ps
-		dockerErrorString := "cannot connect to the Docker daemon at unix:///var/run/docker.sock. Is the docker daemon running?"
-		return errors.New(dockerErrorString)
+		return fmt.Errorf("cannot connect to the Docker daemon at %s. Is the docker daemon running?", endpoint.Host)
 	}
 
 	// check docker version
-	dockerVersionCmdOutput, err := exec.Command("docker", "version", "--format", "{{.Server.Version}}").Output()
+	dockerVersionCmdOutput, err := endpointCommand(endpoint, "docker", "version", "--format", "{{.Server.Version}}").Output()
 	if err != nil {
 		return fmt.Errorf("error checking docker version: %s", err.Error())
 	}
 
-	dockerSemverCeString := strings.TrimSpace(string(dockerVersionCmdOutput))
-	// get semver // for now we get rid of -ce
-	dockerSemver := dockerSemverCeString[:len(dockerSemverCeString)-3]
+	// semver ignores suffixes such as -ce
+	dockerSemver := strings.TrimSpace(string(dockerVersionCmdOutput))
 	minimumSemver := MinimumDockerVersion[:len(MinimumDockerVersion)-3]
 
 	requiredDockerVersion, err := semver.CorrectVersion(minimumSemver, dockerSemver)
@@ -76,50 +118,112 @@ func Installed() error {
 		return fmt.Errorf("error: docker version %s-ce or higher is required", minimumSemver)
 	}
 
-	// check if docker-compose is installed
-	dockerComposeCMDOutput, err := exec.Command("docker-compose", "version", "--short").Output()
+	return nil
+}
+
+func podmanInstalled() error {
+	podmanVersionCmdOutput, err := exec.Command("podman", "version", "--format", "{{.Client.Version}}").Output()
 	if err != nil {
-		return fmt.Errorf("error: please make sure docker-compose is installed: %s", err.Error())
+		return fmt.Errorf("error running podman, please make sure podman is installed: %s", err.Error())
 	}
 
-	dockerComposeSemverString := strings.TrimSpace(string(dockerComposeCMDOutput))
-
-	// check docker-compose version
-	requiredDockerComposeVersion, err := semver.CorrectVersion(MinimumDockerComposeVersion, dockerComposeSemverString)
+	requiredPodmanVersion, err := semver.CorrectVersion(MinimumPodmanVersion, string(podmanVersionCmdOutput))
 	if err != nil {
-		return fmt.Errorf("error checking docker-compose version: %s", err.Error())
+		return fmt.Errorf("error checking podman version: %s", err.Error())
 	}
 
-	if !requiredDockerComposeVersion {
-		return fmt.Errorf("error: docker-compose version %s or higher is required", MinimumDockerComposeVersion)
+	if !requiredPodmanVersion {
+		return fmt.Errorf("error: podman version %s or higher is required", MinimumPodmanVersion)
 	}
 
 	return nil
 }
 
+// endpointCommand returns a command that talks to the given endpoint.
+func endpointCommand(endpoint *Endpoint, name string, args ...string) *exec.Cmd {
+	cmd := exec.Command(name, args...)
+	cmd.Env = append(os.Environ(), "DOCKER_HOST="+endpoint.Host)
+	return cmd
+}
+
+// PullOptions configures how images are downloaded.
+type PullOptions struct {
+	// Registry is the registry and organization images are pulled from,
+	// docker hub's hyperledger organization when empty.
+	Registry string
+
+	// Parallelism is the number of images pulled at the same time, one when 0.
+	Parallelism int
+}
+
 // DownloadDockerImages downloads docker images given a list of images and the tag.
-func (c Client) DownloadDockerImages(images []string, tag string) error {
+// No image is pulled after a pull fails, pulls in progress are completed.
+func (c Client) DownloadDockerImages(images []string, tag string, options PullOptions) error {
+	parallelism := options.Parallelism
+	if parallelism < 1 {
+		parallelism = 1
+	}
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, parallelism)
+	errs := make(chan error, len(images))
 	for _, image := range images {
-		color.Blue("Pulling %s", image)
-		if err := c.PullAndTagHyperledgerImage(image, tag); err != nil {
-			return err
+		slots <- struct{}{}
+		if len(errs) > 0 {
+			break
 		}
-		color.Green("Successfully pulled and tagged %s", image)
+
+		wg.Add(1)
+		go func(image string) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			color.Blue("Pulling %s", image)
+			if err := c.PullAndTagHyperledgerImage(image, tag, options.Registry); err != nil {
+				errs <- err
+				return
+			}
+			color.Green("Successfully pulled and tagged %s", image)
+		}(image)
 	}
-	return nil
+	wg.Wait()
+	close(errs)
+
+	return <-errs
 }
 
-// PullAndTagHyperledgerImage pulls a docker hyperledger image given the name and the tag.
-// and tags it with 'latest' tag
-func (c Client) PullAndTagHyperledgerImage(imageName, tag string) error {
-	imageString := fmt.Sprintf("hyperledger/fabric-%s:%s", imageName, tag)
+// PullAndTagHyperledgerImage pulls a docker hyperledger image given the name and the tag
+// from a registry, hyperledger's when empty, and tags it hyperledger/fabric-<name>:<tag>
+// and hyperledger/fabric-<name>:latest.
+func (c Client) PullAndTagHyperledgerImage(imageName, tag, registry string) error {
+	if registry == "" {
+		registry = HyperledgerRepository
+	}
 
-	_, err := c.client.ImagePull(context.Background(), imageString, types.ImagePullOptions{})
+	ctx := context.Background()
+	source := fmt.Sprintf("%s/fabric-%s:%s", registry, imageName, tag)
+	stream, err := c.api.ImagePull(ctx, source, types.ImagePullOptions{})
 	if err != nil {
 		return err
 	}
+	defer stream.Close()
+
+	if err := readJSONMessages(stream); err != nil {
+		return fmt.Errorf("error pulling %s: %s", source, err.Error())
+	}
+
+	imageString := fmt.Sprintf("%s/fabric-%s:%s", HyperledgerRepository, imageName, tag)
+	if source != imageString {
+		// images from mirrors are known by their hyperledger names only
+		if err := c.api.ImageTag(ctx, source, imageString); err != nil {
+			return err
+		}
+		if _, err := c.api.ImageRemove(ctx, source, types.ImageRemoveOptions{}); err != nil {
+			return err
+		}
+	}
 
-	image := fmt.Sprintf("hyperledger/fabric-%s", imageName)
+	image := fmt.Sprintf("%s/fabric-%s", HyperledgerRepository, imageName)
 
-	return c.client.ImageTag(context.Background(), imageString, image)
+	return c.api.ImageTag(ctx, imageString, image)
 }
@@ -0,0 +1,74 @@
+package docker_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/gangachris/hlf/docker"
+	"github.com/gangachris/hlf/docker/dockertest"
+)
+
+func TestClient_Exec(t *testing.T) {
+	fake := dockertest.New()
+	fake.AddImage(types.ImageSummary{RepoTags: []string{"hyperledger/fabric-tools"}})
+	fake.Exec = func(container string, cmd []string) (string, string, int) {
+		if container != "cli" || !reflect.DeepEqual(cmd, []string{"peer", "channel", "list"}) {
+			t.Errorf("unexpected exec %s %v", container, cmd)
+		}
+		return "Channels peers has joined:\nmychannel\n", "warning\n", 0
+	}
+
+	ctx := context.Background()
+	if _, err := fake.ContainerCreate(ctx, &container.Config{Image: "hyperledger/fabric-tools"}, nil, nil, "cli"); err != nil {
+		t.Fatal(err)
+	}
+	if err := fake.ContainerStart(ctx, "cli", types.ContainerStartOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	client := docker.NewWithAPI(fake, docker.RuntimeDocker)
+	got, err := client.Exec(ctx, "cli", []string{"peer", "channel", "list"})
+	if err != nil {
+		t.Fatalf("Exec() error = %v", err)
+	}
+
+	want := &docker.ExecResult{Stdout: "Channels peers has joined:\nmychannel\n", Stderr: "warning\n"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Exec() = %+v, want %+v", got, want)
+	}
+}
+
+func TestClient_PullAndTagHyperledgerImage(t *testing.T) {
+	fake := dockertest.New()
+	fake.PullStreamErrors["hyperledger/fabric-peer:x86_64-9.9.9"] = "manifest unknown"
+
+	client := docker.NewWithAPI(fake, docker.RuntimeDocker)
+	if err := client.PullAndTagHyperledgerImage("peer", "x86_64-9.9.9", ""); err == nil {
+		t.Error("PullAndTagHyperledgerImage() expected the error reported in the pull stream")
+	}
+
+	if calls := fake.CallsTo("ImageTag"); len(calls) != 0 {
+		t.Errorf("PullAndTagHyperledgerImage() tagged an image that failed to pull: %v", calls)
+	}
+}
+
+func TestClient_PullAndTagHyperledgerImage_registry(t *testing.T) {
+	fake := dockertest.New()
+
+	client := docker.NewWithAPI(fake, docker.RuntimeDocker)
+	if err := client.PullAndTagHyperledgerImage("peer", "x86_64-1.1.0", "registry.example.com:5000/hyperledger"); err != nil {
+		t.Fatal(err)
+	}
+
+	if calls := fake.CallsTo("ImagePull"); len(calls) != 1 || calls[0].Args[0] != "registry.example.com:5000/hyperledger/fabric-peer:x86_64-1.1.0" {
+		t.Errorf("PullAndTagHyperledgerImage() pulls = %v", calls)
+	}
+
+	want := []string{"hyperledger/fabric-peer:latest", "hyperledger/fabric-peer:x86_64-1.1.0"}
+	if got := fake.ImageTags(); !reflect.DeepEqual(got, want) {
+		t.Errorf("PullAndTagHyperledgerImage() images = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,1208 @@
+// Package dockertest provides an in-memory fake of the docker engine API so
+// the docker package and commands can be tested without a daemon.
+package dockertest
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/network"
+	volumetypes "github.com/docker/docker/api/types/volume"
+	"github.com/gangachris/hlf/docker"
+)
+
+// Call is a recorded call to the fake.
+type Call struct {
+	Method string
+	Args   []interface{}
+}
+
+// ExecHandler simulates a command executed in a container.
+type ExecHandler func(container string, cmd []string) (stdout, stderr string, exitCode int)
+
+// Fake is an in-memory docker engine. The zero value is not usable, use New.
+type Fake struct {
+	mu sync.Mutex
+
+	// Calls records every API call in order.
+	Calls []Call
+
+	// Errors makes the named method fail e.g Errors["ContainerStart"] = errors.New("boom").
+	Errors map[string]error
+
+	// PullStreamErrors makes the pull stream of an image reference end with
+	// the given message, the way the daemon reports failed pulls.
+	PullStreamErrors map[string]string
+
+	// Logs holds the log output of containers by name.
+	Logs map[string]string
+
+	// Stats holds the resource usage samples of containers by name, a zero
+	// sample is returned for containers without one.
+	Stats map[string]types.StatsJSON
+
+	// Exec handles commands executed in containers, commands succeed without output when nil.
+	Exec ExecHandler
+
+	images     []*types.ImageSummary
+	containers []*fakeContainer
+	networks   []*types.NetworkResource
+	volumes    []*types.Volume
+	execs      map[string]*fakeExec
+	nextID     int
+
+	subscribers []*subscriber
+}
+
+// subscriber receives the events matching its filters.
+type subscriber struct {
+	filters  filters.Args
+	messages chan events.Message
+}
+
+type fakeContainer struct {
+	json    types.ContainerJSON
+	created int64
+	exited  chan struct{}
+}
+
+type fakeExec struct {
+	container string
+	cmd       []string
+	exitCode  int
+}
+
+var _ docker.API = (*Fake)(nil)
+
+// New returns an empty fake docker engine.
+func New() *Fake {
+	return &Fake{
+		Errors:           map[string]error{},
+		PullStreamErrors: map[string]string{},
+		Logs:             map[string]string{},
+		Stats:            map[string]types.StatsJSON{},
+		execs:            map[string]*fakeExec{},
+	}
+}
+
+// notFoundError satisfies the daemon's not found errors checked with docker.IsNotFound.
+type notFoundError struct {
+	kind, name string
+}
+
+func (e notFoundError) Error() string {
+	return fmt.Sprintf("Error: No such %s: %s", e.kind, e.name)
+}
+
+func (e notFoundError) NotFound() bool {
+	return true
+}
+
+// CallsTo returns the recorded calls of the given method.
+func (f *Fake) CallsTo(method string) []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var calls []Call
+	for _, call := range f.Calls {
+		if call.Method == method {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+// AddImage adds an image to the fake, the ID is generated when empty.
+func (f *Fake) AddImage(image types.ImageSummary) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if image.ID == "" {
+		image.ID = f.newID("sha256:")
+	}
+	for i, tag := range image.RepoTags {
+		image.RepoTags[i] = normalizeRef(tag)
+	}
+	f.images = append(f.images, &image)
+	return image.ID
+}
+
+// ImageTags returns every image tag present in the fake, sorted.
+func (f *Fake) ImageTags() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var tags []string
+	for _, image := range f.images {
+		tags = append(tags, image.RepoTags...)
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// Exit simulates a container exiting with the given code.
+func (f *Fake) Exit(name string, exitCode int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	c := f.container(name)
+	if c == nil {
+		return notFoundError{"container", name}
+	}
+	f.stop(c, exitCode)
+	return nil
+}
+
+// OOM simulates a container killed because it ran out of memory.
+func (f *Fake) OOM(name string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	c := f.container(name)
+	if c == nil {
+		return notFoundError{"container", name}
+	}
+	f.publish(c, "oom", nil)
+	c.json.State.OOMKilled = true
+	f.stop(c, 137)
+	return nil
+}
+
+func (f *Fake) record(method string, args ...interface{}) error {
+	f.Calls = append(f.Calls, Call{Method: method, Args: args})
+	return f.Errors[method]
+}
+
+func (f *Fake) newID(prefix string) string {
+	f.nextID++
+	return fmt.Sprintf("%s%064x", prefix, f.nextID)
+}
+
+// normalizeRef adds the latest tag to image references without a tag.
+func normalizeRef(ref string) string {
+	if strings.Contains(ref, "@") || strings.Contains(ref[strings.LastIndex(ref, "/")+1:], ":") {
+		return ref
+	}
+	return ref + ":latest"
+}
+
+func (f *Fake) image(ref string) *types.ImageSummary {
+	id := strings.TrimPrefix(ref, "sha256:")
+	for _, image := range f.images {
+		if id != "" && strings.HasPrefix(strings.TrimPrefix(image.ID, "sha256:"), id) {
+			return image
+		}
+		for _, tag := range image.RepoTags {
+			if tag == normalizeRef(ref) {
+				return image
+			}
+		}
+	}
+	return nil
+}
+
+func (f *Fake) removeTag(tag string) {
+	for _, image := range f.images {
+		for i, t := range image.RepoTags {
+			if t == tag {
+				image.RepoTags = append(image.RepoTags[:i], image.RepoTags[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+func (f *Fake) removeImage(id string) {
+	for i, image := range f.images {
+		if image.ID == id {
+			f.images = append(f.images[:i], f.images[i+1:]...)
+			return
+		}
+	}
+}
+
+// ImagePull simulates pulling an image, streaming progress messages. Present
+// images are up to date.
+func (f *Fake) ImagePull(ctx context.Context, ref string, options types.ImagePullOptions) (io.ReadCloser, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.record("ImagePull", ref, options); err != nil {
+		return nil, err
+	}
+
+	ref = normalizeRef(ref)
+	repository := ref[:strings.LastIndex(ref, ":")]
+	tag := ref[strings.LastIndex(ref, ":")+1:]
+
+	var stream bytes.Buffer
+	encoder := json.NewEncoder(&stream)
+	encoder.Encode(map[string]string{"status": "Pulling from " + repository, "id": tag})
+
+	if message, ok := f.PullStreamErrors[ref]; ok {
+		encoder.Encode(map[string]interface{}{
+			"errorDetail": map[string]string{"message": message},
+			"error":       message,
+		})
+		return ioutil.NopCloser(&stream), nil
+	}
+
+	// images are never updated upstream, pulling a present image keeps it
+	if f.image(ref) != nil {
+		encoder.Encode(map[string]string{"status": "Status: Image is up to date for " + ref})
+		return ioutil.NopCloser(&stream), nil
+	}
+
+	encoder.Encode(map[string]string{"status": "Pull complete", "id": tag})
+	encoder.Encode(map[string]string{"status": "Status: Downloaded newer image for " + ref})
+
+	f.images = append(f.images, &types.ImageSummary{
+		ID:       f.newID("sha256:"),
+		RepoTags: []string{ref},
+		Created:  time.Now().Unix(),
+	})
+
+	return ioutil.NopCloser(&stream), nil
+}
+
+// ImageTag tags an image present in the fake.
+func (f *Fake) ImageTag(ctx context.Context, image, ref string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.record("ImageTag", image, ref); err != nil {
+		return err
+	}
+
+	source := f.image(image)
+	if source == nil {
+		return notFoundError{"image", image}
+	}
+
+	ref = normalizeRef(ref)
+	f.removeTag(ref)
+	source.RepoTags = append(source.RepoTags, ref)
+	return nil
+}
+
+// ImageList lists images matching the reference, label and dangling filters.
+func (f *Fake) ImageList(ctx context.Context, options types.ImageListOptions) ([]types.ImageSummary, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.record("ImageList", options); err != nil {
+		return nil, err
+	}
+
+	var images []types.ImageSummary
+	for _, image := range f.images {
+		if !options.Filters.MatchKVList("label", image.Labels) {
+			continue
+		}
+
+		if options.Filters.Include("dangling") {
+			dangling := len(image.RepoTags) == 0
+			if !options.Filters.ExactMatch("dangling", strconv.FormatBool(dangling)) {
+				continue
+			}
+		}
+
+		if options.Filters.Include("reference") && !matchReference(options.Filters.Get("reference"), image.RepoTags) {
+			continue
+		}
+
+		images = append(images, *image)
+	}
+	return images, nil
+}
+
+func matchReference(patterns, tags []string) bool {
+	for _, pattern := range patterns {
+		for _, tag := range tags {
+			repository := tag[:strings.LastIndex(tag, ":")]
+			if ok, _ := path.Match(pattern, tag); ok {
+				return true
+			}
+			if ok, _ := path.Match(pattern, repository); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ImageRemove untags an image and deletes it once it has no tags left.
+func (f *Fake) ImageRemove(ctx context.Context, ref string, options types.ImageRemoveOptions) ([]types.ImageDelete, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.record("ImageRemove", ref, options); err != nil {
+		return nil, err
+	}
+
+	image := f.image(ref)
+	if image == nil {
+		return nil, notFoundError{"image", ref}
+	}
+
+	if !options.Force {
+		for _, c := range f.containers {
+			if f.image(c.json.Image) == image {
+				return nil, fmt.Errorf("conflict: unable to remove repository reference %q - container %s is using its referenced image", ref, c.json.ID[:12])
+			}
+		}
+	}
+
+	var deleted []types.ImageDelete
+	tag := normalizeRef(ref)
+	for _, t := range image.RepoTags {
+		if t == tag {
+			f.removeTag(tag)
+			deleted = append(deleted, types.ImageDelete{Untagged: tag})
+			break
+		}
+	}
+
+	if len(deleted) == 0 {
+		// removing by ID removes every tag
+		if len(image.RepoTags) > 1 && !options.Force {
+			return nil, fmt.Errorf("conflict: unable to delete %s (must be forced) - image is referenced in multiple repositories", ref)
+		}
+		for _, t := range image.RepoTags {
+			deleted = append(deleted, types.ImageDelete{Untagged: t})
+		}
+		image.RepoTags = nil
+	}
+
+	if len(image.RepoTags) == 0 {
+		f.removeImage(image.ID)
+		deleted = append(deleted, types.ImageDelete{Deleted: image.ID})
+	}
+
+	return deleted, nil
+}
+
+// ImageInspectWithRaw returns details of an image present in the fake.
+func (f *Fake) ImageInspectWithRaw(ctx context.Context, ref string) (types.ImageInspect, []byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.record("ImageInspectWithRaw", ref); err != nil {
+		return types.ImageInspect{}, nil, err
+	}
+
+	image := f.image(ref)
+	if image == nil {
+		return types.ImageInspect{}, nil, notFoundError{"image", ref}
+	}
+
+	inspect := types.ImageInspect{
+		ID:          image.ID,
+		RepoTags:    image.RepoTags,
+		RepoDigests: image.RepoDigests,
+		Created:     time.Unix(image.Created, 0).UTC().Format(time.RFC3339Nano),
+		Size:        image.Size,
+		VirtualSize: image.VirtualSize,
+		Config:      &container.Config{Labels: image.Labels},
+	}
+	raw, err := json.Marshal(inspect)
+	return inspect, raw, err
+}
+
+func (f *Fake) container(name string) *fakeContainer {
+	name = strings.TrimPrefix(name, "/")
+	for _, c := range f.containers {
+		if strings.TrimPrefix(c.json.Name, "/") == name || (name != "" && strings.HasPrefix(c.json.ID, name)) {
+			return c
+		}
+	}
+	return nil
+}
+
+func (f *Fake) stop(c *fakeContainer, exitCode int) {
+	if !c.json.State.Running {
+		return
+	}
+	c.json.State.Running = false
+	c.json.State.Status = "exited"
+	c.json.State.ExitCode = exitCode
+	c.json.State.FinishedAt = time.Now().UTC().Format(time.RFC3339Nano)
+	close(c.exited)
+	f.publish(c, "die", map[string]string{"exitCode": strconv.Itoa(exitCode)})
+}
+
+// publish sends a container event to the subscribers whose filters match.
+func (f *Fake) publish(c *fakeContainer, action string, attributes map[string]string) {
+	actor := events.Actor{
+		ID: c.json.ID,
+		Attributes: map[string]string{
+			"name":  strings.TrimPrefix(c.json.Name, "/"),
+			"image": c.json.Image,
+		},
+	}
+	for key, value := range c.json.Config.Labels {
+		actor.Attributes[key] = value
+	}
+	for key, value := range attributes {
+		actor.Attributes[key] = value
+	}
+
+	now := time.Now()
+	message := events.Message{
+		Status:   action,
+		ID:       c.json.ID,
+		From:     c.json.Image,
+		Type:     events.ContainerEventType,
+		Action:   action,
+		Actor:    actor,
+		Time:     now.Unix(),
+		TimeNano: now.UnixNano(),
+	}
+
+	for _, s := range f.subscribers {
+		if !s.filters.ExactMatch("type", message.Type) || !s.filters.ExactMatch("event", action) {
+			continue
+		}
+		if !s.filters.MatchKVList("label", c.json.Config.Labels) {
+			continue
+		}
+		if s.filters.Include("container") && !s.filters.ExactMatch("container", actor.Attributes["name"]) && !s.filters.ExactMatch("container", c.json.ID) {
+			continue
+		}
+		s.messages <- message
+	}
+}
+
+// Events streams the container events matching the type, event, label and
+// container filters until ctx is done.
+func (f *Fake) Events(ctx context.Context, options types.EventsOptions) (<-chan events.Message, <-chan error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	errs := make(chan error, 1)
+	if err := f.record("Events", options); err != nil {
+		errs <- err
+		return make(chan events.Message), errs
+	}
+
+	s := &subscriber{filters: options.Filters, messages: make(chan events.Message, 256)}
+	f.subscribers = append(f.subscribers, s)
+
+	go func() {
+		<-ctx.Done()
+
+		f.mu.Lock()
+		for i := range f.subscribers {
+			if f.subscribers[i] == s {
+				f.subscribers = append(f.subscribers[:i], f.subscribers[i+1:]...)
+				break
+			}
+		}
+		f.mu.Unlock()
+
+		errs <- ctx.Err()
+	}()
+
+	return s.messages, errs
+}
+
+// ContainerCreate creates a container from an image present in the fake.
+func (f *Fake) ContainerCreate(ctx context.Context, config *container.Config, hostConfig *container.HostConfig, networkingConfig *network.NetworkingConfig, containerName string) (container.ContainerCreateCreatedBody, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.record("ContainerCreate", config, hostConfig, networkingConfig, containerName); err != nil {
+		return container.ContainerCreateCreatedBody{}, err
+	}
+
+	if f.image(config.Image) == nil {
+		return container.ContainerCreateCreatedBody{}, notFoundError{"image", config.Image}
+	}
+
+	if containerName != "" && f.container(containerName) != nil {
+		return container.ContainerCreateCreatedBody{}, fmt.Errorf("Conflict. The container name %q is already in use", "/"+containerName)
+	}
+
+	if hostConfig == nil {
+		hostConfig = &container.HostConfig{}
+	}
+
+	id := f.newID("")
+	if containerName == "" {
+		containerName = id[:12]
+	}
+
+	networks := map[string]*network.EndpointSettings{}
+	if networkingConfig != nil {
+		for name, endpoint := range networkingConfig.EndpointsConfig {
+			settings := *endpoint
+			if n := f.network(name); n != nil {
+				settings.NetworkID = n.ID
+			}
+			networks[name] = &settings
+		}
+	}
+	if mode := string(hostConfig.NetworkMode); mode != "" && networks[mode] == nil {
+		networks[mode] = &network.EndpointSettings{}
+	}
+
+	var mounts []types.MountPoint
+	for _, m := range hostConfig.Mounts {
+		mounts = append(mounts, types.MountPoint{Type: m.Type, Name: m.Source, Source: m.Source, Destination: m.Target, RW: !m.ReadOnly})
+	}
+	for _, bind := range hostConfig.Binds {
+		parts := strings.SplitN(bind, ":", 3)
+		if len(parts) < 2 {
+			continue
+		}
+		readOnly := len(parts) == 3 && strings.Contains(parts[2], "ro")
+		if strings.HasPrefix(parts[0], "/") {
+			mounts = append(mounts, types.MountPoint{Type: mount.TypeBind, Source: parts[0], Destination: parts[1], RW: !readOnly})
+			continue
+		}
+		mounts = append(mounts, types.MountPoint{Type: mount.TypeVolume, Name: parts[0], Destination: parts[1], RW: !readOnly})
+	}
+
+	f.containers = append(f.containers, &fakeContainer{
+		json: types.ContainerJSON{
+			ContainerJSONBase: &types.ContainerJSONBase{
+				ID:         id,
+				Name:       "/" + containerName,
+				Image:      config.Image,
+				Created:    time.Now().UTC().Format(time.RFC3339Nano),
+				State:      &types.ContainerState{Status: "created"},
+				HostConfig: hostConfig,
+			},
+			Config:          config,
+			Mounts:          mounts,
+			NetworkSettings: &types.NetworkSettings{Networks: networks},
+		},
+		created: time.Now().Unix(),
+		exited:  make(chan struct{}),
+	})
+
+	return container.ContainerCreateCreatedBody{ID: id}, nil
+}
+
+// ContainerStart starts a created or exited container.
+func (f *Fake) ContainerStart(ctx context.Context, name string, options types.ContainerStartOptions) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.record("ContainerStart", name, options); err != nil {
+		return err
+	}
+
+	c := f.container(name)
+	if c == nil {
+		return notFoundError{"container", name}
+	}
+
+	if c.json.State.Running {
+		return nil
+	}
+
+	if c.json.State.Status == "exited" {
+		c.json.RestartCount++
+		c.exited = make(chan struct{})
+	}
+
+	c.json.State.Running = true
+	c.json.State.Status = "running"
+	c.json.State.ExitCode = 0
+	c.json.State.OOMKilled = false
+	c.json.State.StartedAt = time.Now().UTC().Format(time.RFC3339Nano)
+	f.publish(c, "start", nil)
+	return nil
+}
+
+// ContainerStop stops a running container, it exits with code 0 as if it
+// handled SIGTERM.
+func (f *Fake) ContainerStop(ctx context.Context, name string, timeout *time.Duration) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.record("ContainerStop", name, timeout); err != nil {
+		return err
+	}
+
+	c := f.container(name)
+	if c == nil {
+		return notFoundError{"container", name}
+	}
+
+	if c.json.State.Running {
+		f.publish(c, "kill", map[string]string{"signal": "15"})
+		f.stop(c, 0)
+		f.publish(c, "stop", nil)
+	}
+	return nil
+}
+
+// ContainerRemove removes a container, running containers need options.Force.
+func (f *Fake) ContainerRemove(ctx context.Context, name string, options types.ContainerRemoveOptions) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.record("ContainerRemove", name, options); err != nil {
+		return err
+	}
+
+	c := f.container(name)
+	if c == nil {
+		return notFoundError{"container", name}
+	}
+
+	if c.json.State.Running && !options.Force {
+		return fmt.Errorf("Conflict, You cannot remove a running container %s. Stop the container before attempting removal or use -f", c.json.ID)
+	}
+
+	if c.json.State.Running {
+		f.publish(c, "kill", map[string]string{"signal": "9"})
+		f.stop(c, 137)
+	}
+	for i := range f.containers {
+		if f.containers[i] == c {
+			f.containers = append(f.containers[:i], f.containers[i+1:]...)
+			break
+		}
+	}
+	f.publish(c, "destroy", nil)
+	return nil
+}
+
+// ContainerWait blocks until the container exits and returns its exit code.
+func (f *Fake) ContainerWait(ctx context.Context, name string) (int64, error) {
+	f.mu.Lock()
+	if err := f.record("ContainerWait", name); err != nil {
+		f.mu.Unlock()
+		return -1, err
+	}
+
+	c := f.container(name)
+	if c == nil {
+		f.mu.Unlock()
+		return -1, notFoundError{"container", name}
+	}
+	exited := c.exited
+	running := c.json.State.Running
+	f.mu.Unlock()
+
+	if running {
+		select {
+		case <-exited:
+		case <-ctx.Done():
+			return -1, ctx.Err()
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return int64(c.json.State.ExitCode), nil
+}
+
+// ContainerInspect returns the state and configuration of a container.
+func (f *Fake) ContainerInspect(ctx context.Context, name string) (types.ContainerJSON, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.record("ContainerInspect", name); err != nil {
+		return types.ContainerJSON{}, err
+	}
+
+	c := f.container(name)
+	if c == nil {
+		return types.ContainerJSON{}, notFoundError{"container", name}
+	}
+
+	base := *c.json.ContainerJSONBase
+	state := *c.json.State
+	base.State = &state
+	inspect := c.json
+	inspect.ContainerJSONBase = &base
+	return inspect, nil
+}
+
+// ContainerList lists containers matching the label, name, status and network filters.
+func (f *Fake) ContainerList(ctx context.Context, options types.ContainerListOptions) ([]types.Container, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.record("ContainerList", options); err != nil {
+		return nil, err
+	}
+
+	var containers []types.Container
+	for _, c := range f.containers {
+		if !options.All && !c.json.State.Running && !options.Filters.Include("status") {
+			continue
+		}
+
+		name := strings.TrimPrefix(c.json.Name, "/")
+		if !options.Filters.MatchKVList("label", c.json.Config.Labels) {
+			continue
+		}
+		if options.Filters.Include("name") && !options.Filters.Match("name", name) {
+			continue
+		}
+		if options.Filters.Include("status") && !options.Filters.ExactMatch("status", c.json.State.Status) {
+			continue
+		}
+		if options.Filters.Include("network") && !matchNetwork(options.Filters, c.json.NetworkSettings.Networks) {
+			continue
+		}
+
+		var ports []types.Port
+		for port, bindings := range c.json.HostConfig.PortBindings {
+			for _, binding := range bindings {
+				public, _ := strconv.Atoi(binding.HostPort)
+				ports = append(ports, types.Port{
+					IP:          binding.HostIP,
+					PrivatePort: uint16(port.Int()),
+					PublicPort:  uint16(public),
+					Type:        port.Proto(),
+				})
+			}
+		}
+		sort.Slice(ports, func(i, j int) bool { return ports[i].PrivatePort < ports[j].PrivatePort })
+
+		summary := types.Container{
+			ID:              c.json.ID,
+			Names:           []string{c.json.Name},
+			Image:           c.json.Image,
+			Created:         c.created,
+			Ports:           ports,
+			Labels:          c.json.Config.Labels,
+			State:           c.json.State.Status,
+			Status:          c.json.State.Status,
+			Mounts:          c.json.Mounts,
+			NetworkSettings: &types.SummaryNetworkSettings{Networks: c.json.NetworkSettings.Networks},
+		}
+		if img := f.image(c.json.Image); img != nil {
+			summary.ImageID = img.ID
+		}
+		summary.HostConfig.NetworkMode = string(c.json.HostConfig.NetworkMode)
+		containers = append(containers, summary)
+	}
+	return containers, nil
+}
+
+func matchNetwork(args filters.Args, networks map[string]*network.EndpointSettings) bool {
+	for name, endpoint := range networks {
+		if args.ExactMatch("network", name) || args.ExactMatch("network", endpoint.NetworkID) {
+			return true
+		}
+	}
+	return false
+}
+
+// ContainerLogs returns the logs set in Logs as a multiplexed stdout stream.
+func (f *Fake) ContainerLogs(ctx context.Context, name string, options types.ContainerLogsOptions) (io.ReadCloser, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.record("ContainerLogs", name, options); err != nil {
+		return nil, err
+	}
+
+	c := f.container(name)
+	if c == nil {
+		return nil, notFoundError{"container", name}
+	}
+
+	logs := f.Logs[strings.TrimPrefix(c.json.Name, "/")]
+	if options.Tail != "" && options.Tail != "all" {
+		if tail, err := strconv.Atoi(options.Tail); err == nil {
+			lines := strings.SplitAfter(logs, "\n")
+			if lines[len(lines)-1] == "" {
+				lines = lines[:len(lines)-1]
+			}
+			if tail < len(lines) {
+				lines = lines[len(lines)-tail:]
+			}
+			logs = strings.Join(lines, "")
+		}
+	}
+
+	if c.json.Config.Tty {
+		return ioutil.NopCloser(strings.NewReader(logs)), nil
+	}
+
+	var stream bytes.Buffer
+	for _, line := range strings.SplitAfter(logs, "\n") {
+		if line != "" {
+			stream.Write(docker.MuxFrame(false, []byte(line)))
+		}
+	}
+	return ioutil.NopCloser(&stream), nil
+}
+
+// ContainerStats returns the sample set in Stats, once even when streaming.
+func (f *Fake) ContainerStats(ctx context.Context, name string, stream bool) (types.ContainerStats, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.record("ContainerStats", name, stream); err != nil {
+		return types.ContainerStats{}, err
+	}
+
+	c := f.container(name)
+	if c == nil {
+		return types.ContainerStats{}, notFoundError{"container", name}
+	}
+
+	stats := f.Stats[strings.TrimPrefix(c.json.Name, "/")]
+	stats.Name = c.json.Name
+	stats.ID = c.json.ID
+
+	body, err := json.Marshal(stats)
+	if err != nil {
+		return types.ContainerStats{}, err
+	}
+	return types.ContainerStats{Body: ioutil.NopCloser(bytes.NewReader(body)), OSType: "linux"}, nil
+}
+
+func (f *Fake) network(name string) *types.NetworkResource {
+	for _, n := range f.networks {
+		if n.Name == name || (name != "" && strings.HasPrefix(n.ID, name)) {
+			return n
+		}
+	}
+	return nil
+}
+
+// networkContainers returns the endpoints of the containers attached to a network.
+func (f *Fake) networkContainers(n *types.NetworkResource) map[string]types.EndpointResource {
+	endpoints := map[string]types.EndpointResource{}
+	for _, c := range f.containers {
+		for name := range c.json.NetworkSettings.Networks {
+			if name == n.Name || name == n.ID {
+				endpoints[c.json.ID] = types.EndpointResource{Name: strings.TrimPrefix(c.json.Name, "/")}
+			}
+		}
+	}
+	return endpoints
+}
+
+// NetworkCreate creates a network, names must be unique.
+func (f *Fake) NetworkCreate(ctx context.Context, name string, options types.NetworkCreate) (types.NetworkCreateResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.record("NetworkCreate", name, options); err != nil {
+		return types.NetworkCreateResponse{}, err
+	}
+
+	if f.network(name) != nil {
+		return types.NetworkCreateResponse{}, fmt.Errorf("network with name %s already exists", name)
+	}
+
+	driver := options.Driver
+	if driver == "" {
+		driver = "bridge"
+	}
+
+	n := &types.NetworkResource{
+		Name:     name,
+		ID:       f.newID(""),
+		Created:  time.Now(),
+		Scope:    "local",
+		Driver:   driver,
+		Internal: options.Internal,
+		Options:  options.Options,
+		Labels:   options.Labels,
+	}
+	f.networks = append(f.networks, n)
+	return types.NetworkCreateResponse{ID: n.ID}, nil
+}
+
+// NetworkInspect returns a network and the containers attached to it.
+func (f *Fake) NetworkInspect(ctx context.Context, name string) (types.NetworkResource, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.record("NetworkInspect", name); err != nil {
+		return types.NetworkResource{}, err
+	}
+
+	n := f.network(name)
+	if n == nil {
+		return types.NetworkResource{}, notFoundError{"network", name}
+	}
+
+	inspect := *n
+	inspect.Containers = f.networkContainers(n)
+	return inspect, nil
+}
+
+// NetworkList lists networks matching the name and label filters.
+func (f *Fake) NetworkList(ctx context.Context, options types.NetworkListOptions) ([]types.NetworkResource, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.record("NetworkList", options); err != nil {
+		return nil, err
+	}
+
+	var networks []types.NetworkResource
+	for _, n := range f.networks {
+		if !options.Filters.MatchKVList("label", n.Labels) {
+			continue
+		}
+		if options.Filters.Include("name") && !options.Filters.Match("name", n.Name) {
+			continue
+		}
+		networks = append(networks, *n)
+	}
+	return networks, nil
+}
+
+// NetworkRemove removes a network without containers attached.
+func (f *Fake) NetworkRemove(ctx context.Context, name string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.record("NetworkRemove", name); err != nil {
+		return err
+	}
+
+	n := f.network(name)
+	if n == nil {
+		return notFoundError{"network", name}
+	}
+
+	if len(f.networkContainers(n)) > 0 {
+		return fmt.Errorf("error while removing network: network %s id %s has active endpoints", n.Name, n.ID)
+	}
+
+	for i := range f.networks {
+		if f.networks[i] == n {
+			f.networks = append(f.networks[:i], f.networks[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func (f *Fake) volume(name string) *types.Volume {
+	for _, v := range f.volumes {
+		if v.Name == name {
+			return v
+		}
+	}
+	return nil
+}
+
+// volumeInUse reports whether a container mounts the volume.
+func (f *Fake) volumeInUse(name string) bool {
+	for _, c := range f.containers {
+		for _, m := range c.json.Mounts {
+			if m.Name == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// VolumeCreate creates a volume, creating an existing volume returns it.
+func (f *Fake) VolumeCreate(ctx context.Context, options volumetypes.VolumesCreateBody) (types.Volume, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.record("VolumeCreate", options); err != nil {
+		return types.Volume{}, err
+	}
+
+	if v := f.volume(options.Name); v != nil {
+		return *v, nil
+	}
+
+	name := options.Name
+	if name == "" {
+		name = f.newID("")
+	}
+
+	driver := options.Driver
+	if driver == "" {
+		driver = "local"
+	}
+
+	v := &types.Volume{
+		Name:       name,
+		Driver:     driver,
+		Labels:     options.Labels,
+		Options:    options.DriverOpts,
+		Mountpoint: "/var/lib/docker/volumes/" + name + "/_data",
+		Scope:      "local",
+	}
+	f.volumes = append(f.volumes, v)
+	return *v, nil
+}
+
+// VolumeInspect returns a volume.
+func (f *Fake) VolumeInspect(ctx context.Context, name string) (types.Volume, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.record("VolumeInspect", name); err != nil {
+		return types.Volume{}, err
+	}
+
+	v := f.volume(name)
+	if v == nil {
+		return types.Volume{}, notFoundError{"volume", name}
+	}
+	return *v, nil
+}
+
+// VolumeList lists volumes matching the name and label filters.
+func (f *Fake) VolumeList(ctx context.Context, filter filters.Args) (volumetypes.VolumesListOKBody, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.record("VolumeList", filter); err != nil {
+		return volumetypes.VolumesListOKBody{}, err
+	}
+
+	var body volumetypes.VolumesListOKBody
+	for _, v := range f.volumes {
+		if !filter.MatchKVList("label", v.Labels) {
+			continue
+		}
+		if filter.Include("name") && !filter.Match("name", v.Name) {
+			continue
+		}
+		volume := *v
+		body.Volumes = append(body.Volumes, &volume)
+	}
+	return body, nil
+}
+
+// VolumeRemove removes a volume not mounted by any container.
+func (f *Fake) VolumeRemove(ctx context.Context, name string, force bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.record("VolumeRemove", name, force); err != nil {
+		return err
+	}
+
+	v := f.volume(name)
+	if v == nil {
+		return notFoundError{"volume", name}
+	}
+
+	if f.volumeInUse(name) && !force {
+		return fmt.Errorf("Error response from daemon: unable to remove volume: remove %s: volume is in use", name)
+	}
+
+	for i := range f.volumes {
+		if f.volumes[i] == v {
+			f.volumes = append(f.volumes[:i], f.volumes[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// ContainerExecCreate creates an exec instance in a running container.
+func (f *Fake) ContainerExecCreate(ctx context.Context, name string, config types.ExecConfig) (types.IDResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.record("ContainerExecCreate", name, config); err != nil {
+		return types.IDResponse{}, err
+	}
+
+	c := f.container(name)
+	if c == nil {
+		return types.IDResponse{}, notFoundError{"container", name}
+	}
+
+	if !c.json.State.Running {
+		return types.IDResponse{}, fmt.Errorf("Container %s is not running", c.json.ID)
+	}
+
+	id := f.newID("")
+	f.execs[id] = &fakeExec{container: strings.TrimPrefix(c.json.Name, "/"), cmd: config.Cmd}
+	return types.IDResponse{ID: id}, nil
+}
+
+// ContainerExecAttach runs the exec instance with Exec and returns its multiplexed output.
+func (f *Fake) ContainerExecAttach(ctx context.Context, execID string, config types.ExecConfig) (types.HijackedResponse, error) {
+	f.mu.Lock()
+	if err := f.record("ContainerExecAttach", execID, config); err != nil {
+		f.mu.Unlock()
+		return types.HijackedResponse{}, err
+	}
+
+	exec, ok := f.execs[execID]
+	handler := f.Exec
+	f.mu.Unlock()
+
+	if !ok {
+		return types.HijackedResponse{}, notFoundError{"exec instance", execID}
+	}
+
+	var stdout, stderr string
+	if handler != nil {
+		var exitCode int
+		stdout, stderr, exitCode = handler(exec.container, exec.cmd)
+
+		f.mu.Lock()
+		exec.exitCode = exitCode
+		f.mu.Unlock()
+	}
+
+	var stream bytes.Buffer
+	if stdout != "" {
+		stream.Write(docker.MuxFrame(false, []byte(stdout)))
+	}
+	if stderr != "" {
+		stream.Write(docker.MuxFrame(true, []byte(stderr)))
+	}
+
+	conn, remote := net.Pipe()
+	remote.Close()
+
+	return types.HijackedResponse{Conn: conn, Reader: bufio.NewReader(&stream)}, nil
+}
+
+// ContainerExecInspect returns the exit code of an exec instance.
+func (f *Fake) ContainerExecInspect(ctx context.Context, execID string) (types.ContainerExecInspect, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.record("ContainerExecInspect", execID); err != nil {
+		return types.ContainerExecInspect{}, err
+	}
+
+	exec, ok := f.execs[execID]
+	if !ok {
+		return types.ContainerExecInspect{}, notFoundError{"exec instance", execID}
+	}
+
+	return types.ContainerExecInspect{
+		ExecID:      execID,
+		ContainerID: exec.container,
+		ExitCode:    exec.exitCode,
+	}, nil
+}
@@ -0,0 +1,125 @@
+package docker
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/filters"
+)
+
+// container lifecycle events reported by the watcher
+const (
+	EventStart   = "start"
+	EventDie     = "die"
+	EventOOM     = "oom"
+	EventRestart = "restart"
+	EventKill    = "kill"
+	EventStop    = "stop"
+	EventDestroy = "destroy"
+)
+
+// ContainerEvent is a lifecycle event of an hlf container.
+type ContainerEvent struct {
+	Container string
+	Node      Node
+	Action    string
+	Time      time.Time
+
+	// ExitCode is set for die events.
+	ExitCode int
+}
+
+// WatchContainers calls handle for the lifecycle events of the containers with
+// all the given labels, and of the chaincode containers the given peers launch,
+// until ctx is done. Peers do not label chaincode containers, they are told by
+// their names. Handlers run one at a time in the order events happen.
+func (c Client) WatchContainers(ctx context.Context, labels map[string]string, peers []string, handle func(ContainerEvent)) error {
+	// chaincode containers are selected by name, they can not be filtered by label
+	args := filters.NewArgs()
+	if len(peers) == 0 {
+		args = LabelFilters(labels)
+	}
+	args.Add("type", events.ContainerEventType)
+	for _, action := range []string{EventStart, EventDie, EventOOM, EventRestart, EventKill, EventStop, EventDestroy} {
+		args.Add("event", action)
+	}
+
+	messages, errs := c.api.Events(ctx, types.EventsOptions{Filters: args})
+	for {
+		select {
+		case message := <-messages:
+			event := ContainerEvent{
+				Container: message.Actor.Attributes["name"],
+				Node:      NodeFromLabels(message.Actor.Attributes),
+				Action:    message.Action,
+				Time:      time.Unix(0, message.TimeNano),
+			}
+
+			if !hasLabels(message.Actor.Attributes, labels) {
+				if _, ok := chaincodePeer(event.Container, peers); !ok {
+					continue
+				}
+				event.Node = Node{Network: labels[LabelNetwork], Role: RoleChaincode, Name: event.Container}
+			}
+
+			if exitCode, ok := message.Actor.Attributes["exitCode"]; ok {
+				event.ExitCode, _ = strconv.Atoi(exitCode)
+			}
+
+			handle(event)
+		case err := <-errs:
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// hasLabels reports whether attributes hold all the labels, an empty value
+// matches any value.
+func hasLabels(attributes, labels map[string]string) bool {
+	for key, value := range labels {
+		actual, ok := attributes[key]
+		if !ok || value != "" && actual != value {
+			return false
+		}
+	}
+	return true
+}
+
+// RestartPolicy decides whether hlf restarts containers that die.
+type RestartPolicy struct {
+	// Mode is one of never, on-failure or always.
+	Mode string
+
+	// MaxRestarts is the number of times a container is restarted, 0 is unlimited.
+	MaxRestarts int
+}
+
+// restart policy modes
+const (
+	RestartNever     = "never"
+	RestartOnFailure = "on-failure"
+	RestartAlways    = "always"
+)
+
+// ShouldRestart reports whether a container that died with the exit code and
+// has been restarted restarts times should be restarted.
+func (p RestartPolicy) ShouldRestart(exitCode, restarts int) bool {
+	if p.MaxRestarts > 0 && restarts >= p.MaxRestarts {
+		return false
+	}
+
+	switch p.Mode {
+	case RestartAlways:
+		return true
+	case RestartOnFailure:
+		return exitCode != 0
+	}
+
+	return false
+}
@@ -0,0 +1,52 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/docker/docker/api/types"
+)
+
+// ExecResult is the output of a command executed in a container.
+type ExecResult struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int
+}
+
+// Exec runs a command in a running container and waits for it to finish.
+func (c Client) Exec(ctx context.Context, containerName string, cmd []string, env ...string) (*ExecResult, error) {
+	config := types.ExecConfig{
+		AttachStdout: true,
+		AttachStderr: true,
+		Env:          env,
+		Cmd:          cmd,
+	}
+
+	execID, err := c.api.ContainerExecCreate(ctx, containerName, config)
+	if err != nil {
+		return nil, err
+	}
+
+	attach, err := c.api.ContainerExecAttach(ctx, execID.ID, config)
+	if err != nil {
+		return nil, err
+	}
+	defer attach.Close()
+
+	var stdout, stderr bytes.Buffer
+	if err := Demux(&stdout, &stderr, attach.Reader); err != nil {
+		return nil, err
+	}
+
+	inspect, err := c.api.ContainerExecInspect(ctx, execID.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ExecResult{
+		Stdout:   stdout.String(),
+		Stderr:   stderr.String(),
+		ExitCode: inspect.ExitCode,
+	}, nil
+}
@@ -0,0 +1,129 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/docker/docker/api/types/mount"
+)
+
+// Orphans are hlf resources left behind by crashed or interrupted runs.
+type Orphans struct {
+	// Containers are stopped hlf containers of networks that are gone.
+	Containers []string
+
+	// Networks are hlf networks of networks that are gone, without running containers.
+	Networks []string
+
+	// Volumes are hlf volumes of networks that are gone, not mounted by running containers.
+	Volumes []string
+}
+
+// Empty reports whether no orphans were found.
+func (o Orphans) Empty() bool {
+	return len(o.Containers) == 0 && len(o.Networks) == 0 && len(o.Volumes) == 0
+}
+
+// FindOrphans returns the resources of the hlf networks that are gone: networks
+// that are not known, or none of whose containers exist unless they are starting
+// i.e their containers are being created. Known networks are those whose spec or
+// state still exists. The stopped containers of a known
+// network are not orphans, it was stopped on purpose or by a restart of the
+// host or of the docker daemon.
+func (c Client) FindOrphans(ctx context.Context, known, starting map[string]bool) (*Orphans, error) {
+	hlfResources := map[string]string{LabelNetwork: ""}
+
+	containers, err := c.List(ctx, hlfResources)
+	if err != nil {
+		return nil, err
+	}
+
+	withContainers := map[string]bool{}
+	for _, cont := range containers {
+		withContainers[cont.Labels[LabelNetwork]] = true
+	}
+	gone := func(network string) bool {
+		return !known[network] || (!withContainers[network] && !starting[network])
+	}
+
+	orphans := &Orphans{}
+	networksInUse := map[string]bool{}
+	volumesInUse := map[string]bool{}
+	for _, cont := range containers {
+		if cont.State != "running" {
+			if gone(cont.Labels[LabelNetwork]) {
+				orphans.Containers = append(orphans.Containers, ContainerName(cont))
+			}
+			continue
+		}
+
+		if cont.NetworkSettings != nil {
+			for name, endpoint := range cont.NetworkSettings.Networks {
+				networksInUse[name] = true
+				networksInUse[endpoint.NetworkID] = true
+			}
+		}
+
+		for _, m := range cont.Mounts {
+			if m.Type == mount.TypeVolume {
+				volumesInUse[m.Name] = true
+			}
+		}
+	}
+
+	networks, err := c.Networks(ctx, hlfResources)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, network := range networks {
+		if !networksInUse[network.Name] && !networksInUse[network.ID] && gone(network.Labels[LabelNetwork]) {
+			orphans.Networks = append(orphans.Networks, network.Name)
+		}
+	}
+
+	volumes, err := c.Volumes(ctx, hlfResources)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, volume := range volumes {
+		if !volumesInUse[volume.Name] && gone(volume.Labels[LabelNetwork]) {
+			orphans.Volumes = append(orphans.Volumes, volume.Name)
+		}
+	}
+
+	sort.Strings(orphans.Networks)
+	sort.Strings(orphans.Volumes)
+
+	return orphans, nil
+}
+
+// RemoveOrphans removes orphaned containers and networks, and volumes when
+// volumes is true since they hold ledger data a network may be restarted with.
+func (c Client) RemoveOrphans(ctx context.Context, orphans *Orphans, volumes bool) error {
+	for _, name := range orphans.Containers {
+		if err := c.Remove(ctx, name, true); err != nil && !IsNotFound(err) {
+			return fmt.Errorf("error removing container %s: %s", name, err.Error())
+		}
+	}
+
+	for _, name := range orphans.Networks {
+		if err := c.api.NetworkRemove(ctx, name); err != nil && !IsNotFound(err) {
+			return fmt.Errorf("error removing network %s: %s", name, err.Error())
+		}
+	}
+
+	if !volumes {
+		return nil
+	}
+
+	for _, name := range orphans.Volumes {
+		if err := c.api.VolumeRemove(ctx, name, false); err != nil && !IsNotFound(err) {
+			return fmt.Errorf("error removing volume %s: %s", name, err.Error())
+		}
+	}
+
+	return nil
+}
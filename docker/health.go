@@ -0,0 +1,331 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types"
+)
+
+const (
+	// LabelPort is the label holding the host port of the main service of a node.
+	LabelPort = "hlf.port"
+
+	// LabelOperationsPort is the label holding the host port of the operations
+	// service (/healthz), available since fabric 1.4.
+	LabelOperationsPort = "hlf.operations.port"
+
+	// DefaultReadyTimeout is how long a node is given to become ready.
+	DefaultReadyTimeout = 60 * time.Second
+
+	defaultPollInterval = 500 * time.Millisecond
+
+	// notReadyLogLines is the number of log lines reported for nodes that fail to become ready.
+	notReadyLogLines = 20
+)
+
+// ReadyLogLines are the log lines nodes print once they serve requests, used
+// when a node has no operations endpoint.
+var ReadyLogLines = map[Role]string{
+	RolePeer:      "Started peer with ID",
+	RoleOrderer:   "Beginning to serve requests",
+	RoleCA:        "Listening on http",
+	RoleCouchDB:   "Apache CouchDB has started",
+	RoleKafka:     "started (kafka.server.KafkaServer)",
+	RoleZookeeper: "binding to port",
+}
+
+// ReadinessCheck describes how to tell a container is ready. Probes run in
+// order: the container is running, TCPAddr accepts connections, HealthURL
+// returns 200 and, when there is no HealthURL, LogLine has been logged.
+type ReadinessCheck struct {
+	Container string
+
+	// TCPAddr is the address the node listens on e.g localhost:7051.
+	TCPAddr string
+
+	// HealthURL is the operations health endpoint e.g http://localhost:9443/healthz.
+	HealthURL string
+
+	// LogLine is logged by the node once it is ready.
+	LogLine string
+
+	// Timeout defaults to DefaultReadyTimeout.
+	Timeout time.Duration
+
+	// PollInterval defaults to 500ms.
+	PollInterval time.Duration
+}
+
+// NotReadyError reports a container that did not become ready.
+type NotReadyError struct {
+	Container string
+	Reason    string
+
+	// Logs are the last log lines of the container.
+	Logs []string
+}
+
+func (e *NotReadyError) Error() string {
+	message := fmt.Sprintf("error: %s did not become ready: %s", e.Container, e.Reason)
+	if len(e.Logs) > 0 {
+		message += "\nlast log lines:\n\t" + strings.Join(e.Logs, "\n\t")
+	}
+	return message
+}
+
+// ReadinessChecks returns the readiness checks of the containers with all the
+// given labels, built from the role and port labels set when they were created.
+func (c Client) ReadinessChecks(ctx context.Context, labels map[string]string) ([]ReadinessCheck, error) {
+	containers, err := c.List(ctx, labels)
+	if err != nil {
+		return nil, err
+	}
+
+	var checks []ReadinessCheck
+	for _, cont := range containers {
+		check := ReadinessCheck{
+			Container: ContainerName(cont),
+			LogLine:   ReadyLogLines[Role(cont.Labels[LabelRole])],
+		}
+
+		if port := cont.Labels[LabelPort]; port != "" {
+			check.TCPAddr = net.JoinHostPort("localhost", port)
+		}
+
+		if port := cont.Labels[LabelOperationsPort]; port != "" {
+			check.HealthURL = "http://" + net.JoinHostPort("localhost", port) + "/healthz"
+		}
+
+		checks = append(checks, check)
+	}
+
+	return checks, nil
+}
+
+// WaitReady waits for every check concurrently and returns an error
+// listing each container that did not become ready.
+func (c Client) WaitReady(ctx context.Context, checks []ReadinessCheck) error {
+	var wg sync.WaitGroup
+	errs := make([]error, len(checks))
+	for i := range checks {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = c.waitReady(ctx, checks[i])
+		}(i)
+	}
+	wg.Wait()
+
+	var failed []error
+	for _, err := range errs {
+		if err != nil {
+			failed = append(failed, err)
+		}
+	}
+
+	switch len(failed) {
+	case 0:
+		return nil
+	case 1:
+		return failed[0]
+	}
+
+	var messages []string
+	for _, err := range failed {
+		messages = append(messages, err.Error())
+	}
+	return fmt.Errorf("%d nodes did not become ready\n%s", len(failed), strings.Join(messages, "\n"))
+}
+
+func (c Client) waitReady(ctx context.Context, check ReadinessCheck) error {
+	if check.Timeout == 0 {
+		check.Timeout = DefaultReadyTimeout
+	}
+
+	if check.PollInterval == 0 {
+		check.PollInterval = defaultPollInterval
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, check.Timeout)
+	defer cancel()
+
+	probes := []probe{
+		{"container is not running", func(ctx context.Context) (bool, error) { return c.running(ctx, check.Container) }},
+	}
+
+	if check.TCPAddr != "" {
+		probes = append(probes, probe{"nothing listening on " + check.TCPAddr, func(ctx context.Context) (bool, error) {
+			return tcpOpen(check.TCPAddr), nil
+		}})
+	}
+
+	if check.HealthURL != "" {
+		probes = append(probes, probe{check.HealthURL + " is not healthy", func(ctx context.Context) (bool, error) {
+			return healthy(ctx, check.HealthURL), nil
+		}})
+	} else if check.LogLine != "" {
+		probes = append(probes, probe{fmt.Sprintf("%q was not logged", check.LogLine), func(ctx context.Context) (bool, error) {
+			return c.logged(ctx, check.Container, check.LogLine)
+		}})
+	}
+
+	for _, p := range probes {
+		if err := poll(ctx, check.PollInterval, p.ready); err != nil {
+			reason := p.reason
+			if err != context.DeadlineExceeded {
+				reason = err.Error()
+			} else {
+				reason += fmt.Sprintf(" after %s", check.Timeout)
+			}
+			return &NotReadyError{
+				Container: check.Container,
+				Reason:    reason,
+				Logs:      c.LastLogLines(check.Container, notReadyLogLines),
+			}
+		}
+	}
+
+	return nil
+}
+
+// probe checks one layer of readiness, reason explains a failure.
+type probe struct {
+	reason string
+	ready  func(context.Context) (bool, error)
+}
+
+// poll runs probe every interval until it succeeds, fails or ctx is done.
+func poll(ctx context.Context, interval time.Duration, probe func(context.Context) (bool, error)) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		ok, err := probe(ctx)
+		if err != nil {
+			return err
+		}
+
+		if ok {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// running reports whether the container is running, a container that
+// exited is an error since it will not become ready.
+func (c Client) running(ctx context.Context, name string) (bool, error) {
+	inspect, err := c.api.ContainerInspect(ctx, name)
+	if err != nil {
+		return false, err
+	}
+
+	state := inspect.State
+	if state.Running {
+		return true, nil
+	}
+
+	if state.Status == "exited" || state.Status == "dead" {
+		if state.OOMKilled {
+			return false, fmt.Errorf("container was killed, out of memory")
+		}
+		return false, fmt.Errorf("container exited with code %d", state.ExitCode)
+	}
+
+	return false, nil
+}
+
+func tcpOpen(addr string) bool {
+	conn, err := net.DialTimeout("tcp", addr, time.Second)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+func healthy(ctx context.Context, url string) bool {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return false
+	}
+
+	res, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return false
+	}
+	defer res.Body.Close()
+
+	return res.StatusCode == http.StatusOK
+}
+
+func (c Client) logged(ctx context.Context, name, line string) (bool, error) {
+	logs, err := c.logs(ctx, name, "all")
+	if err != nil {
+		return false, err
+	}
+
+	return bytes.Contains(logs, []byte(line)), nil
+}
+
+// logs returns the combined stdout and stderr of a container.
+func (c Client) logs(ctx context.Context, name, tail string) ([]byte, error) {
+	inspect, err := c.api.ContainerInspect(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	stream, err := c.api.ContainerLogs(ctx, name, types.ContainerLogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Tail:       tail,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close()
+
+	if inspect.Config != nil && inspect.Config.Tty {
+		return ioutil.ReadAll(stream)
+	}
+
+	var out bytes.Buffer
+	if err := Demux(&out, &out, stream); err != nil {
+		return nil, err
+	}
+
+	return out.Bytes(), nil
+}
+
+// LastLogLines returns the last n log lines of a container, errors are ignored
+// since they are only used to report failures.
+func (c Client) LastLogLines(name string, n int) []string {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	logs, err := c.logs(ctx, name, strconv.Itoa(n))
+	if err != nil {
+		return nil
+	}
+
+	trimmed := strings.TrimRight(string(logs), "\n")
+	if trimmed == "" {
+		return nil
+	}
+
+	return strings.Split(trimmed, "\n")
+}
@@ -0,0 +1,121 @@
+package docker_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/gangachris/hlf/docker"
+	"github.com/gangachris/hlf/docker/dockertest"
+)
+
+func startPeer(t *testing.T, fake *dockertest.Fake, name string) *docker.Client {
+	if len(fake.ImageTags()) == 0 {
+		fake.AddImage(types.ImageSummary{RepoTags: []string{"hyperledger/fabric-peer:1.1.0"}})
+	}
+
+	client := docker.NewWithAPI(fake, docker.RuntimeDocker)
+	ctx := context.Background()
+	if _, err := client.CreateContainer(ctx, peerSpec("dev", name)); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Start(ctx, name); err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestClient_WaitReady(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	healthz := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status":"OK"}`))
+	}))
+	defer healthz.Close()
+
+	tests := []struct {
+		name     string
+		logs     string
+		exitCode int
+		check    docker.ReadinessCheck
+		wantErr  bool
+		wantLogs []string
+	}{
+		{
+			name: "ready once the log line is printed",
+			logs: "starting\nStarted peer with ID=[name:\"peer0\"]\n",
+			check: docker.ReadinessCheck{
+				TCPAddr: listener.Addr().String(),
+				LogLine: docker.ReadyLogLines[docker.RolePeer],
+			},
+		},
+		{
+			name: "ready once healthz is OK, log line is ignored",
+			check: docker.ReadinessCheck{
+				HealthURL: healthz.URL + "/healthz",
+				LogLine:   docker.ReadyLogLines[docker.RolePeer],
+			},
+		},
+		{
+			name: "log line never printed",
+			logs: "starting\n",
+			check: docker.ReadinessCheck{
+				LogLine: docker.ReadyLogLines[docker.RolePeer],
+			},
+			wantErr:  true,
+			wantLogs: []string{"starting"},
+		},
+		{
+			name:     "exited container fails immediately",
+			logs:     "panic: cannot open ledger\n",
+			exitCode: 2,
+			check: docker.ReadinessCheck{
+				Timeout: time.Hour,
+			},
+			wantErr:  true,
+			wantLogs: []string{"panic: cannot open ledger"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := dockertest.New()
+			fake.Logs["peer0"] = tt.logs
+			client := startPeer(t, fake, "peer0")
+			if tt.exitCode != 0 {
+				fake.Exit("peer0", tt.exitCode)
+			}
+
+			check := tt.check
+			check.Container = "peer0"
+			check.PollInterval = 10 * time.Millisecond
+			if check.Timeout == 0 {
+				check.Timeout = 100 * time.Millisecond
+			}
+
+			err := client.WaitReady(context.Background(), []docker.ReadinessCheck{check})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("WaitReady() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil {
+				return
+			}
+
+			notReady, ok := err.(*docker.NotReadyError)
+			if !ok {
+				t.Fatalf("WaitReady() error = %T, want *docker.NotReadyError", err)
+			}
+			if !reflect.DeepEqual(notReady.Logs, tt.wantLogs) {
+				t.Errorf("WaitReady() logs = %v, want %v", notReady.Logs, tt.wantLogs)
+			}
+		})
+	}
+}
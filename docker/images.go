@@ -0,0 +1,122 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+)
+
+// HyperledgerRepository is the docker hub organization fabric images are published by.
+const HyperledgerRepository = "hyperledger"
+
+// Image is a tag of a hyperledger image present locally.
+type Image struct {
+	ID         string    `json:"id"`
+	Repository string    `json:"repository"`
+	Tag        string    `json:"tag"`
+	Digest     string    `json:"digest"`
+	Size       int64     `json:"size"`
+	Created    time.Time `json:"created"`
+
+	// InUse is set when a container, hlf or not, uses the image.
+	InUse bool `json:"in_use"`
+
+	// Networks are the hlf networks with containers using the image.
+	Networks []string `json:"networks"`
+}
+
+// Ref returns the repository:tag reference of the image.
+func (i Image) Ref() string {
+	return i.Repository + ":" + i.Tag
+}
+
+// HyperledgerImages returns every tag of the hyperledger images present locally,
+// sorted by repository and tag.
+func (c Client) HyperledgerImages(ctx context.Context) ([]Image, error) {
+	args := filters.NewArgs()
+	args.Add("reference", HyperledgerRepository+"/*")
+	summaries, err := c.api.ImageList(ctx, types.ImageListOptions{Filters: args})
+	if err != nil {
+		return nil, err
+	}
+
+	containers, err := c.api.ContainerList(ctx, types.ContainerListOptions{All: true})
+	if err != nil {
+		return nil, err
+	}
+
+	inUse := map[string]bool{}
+	networks := map[string][]string{}
+	for _, cont := range containers {
+		inUse[cont.ImageID] = true
+
+		network, ok := cont.Labels[LabelNetwork]
+		if ok && !contains(networks[cont.ImageID], network) {
+			networks[cont.ImageID] = append(networks[cont.ImageID], network)
+		}
+	}
+
+	var images []Image
+	for _, summary := range summaries {
+		for _, ref := range summary.RepoTags {
+			i := strings.LastIndex(ref, ":")
+			if i == -1 || !strings.HasPrefix(ref, HyperledgerRepository+"/") {
+				continue
+			}
+
+			image := Image{
+				ID:         summary.ID,
+				Repository: ref[:i],
+				Tag:        ref[i+1:],
+				Digest:     repoDigest(summary.RepoDigests, ref[:i]),
+				Size:       summary.Size,
+				Created:    time.Unix(summary.Created, 0),
+				InUse:      inUse[summary.ID],
+				Networks:   networks[summary.ID],
+			}
+			sort.Strings(image.Networks)
+			images = append(images, image)
+		}
+	}
+
+	sort.Slice(images, func(i, j int) bool {
+		if images[i].Repository != images[j].Repository {
+			return images[i].Repository < images[j].Repository
+		}
+		return images[i].Tag < images[j].Tag
+	})
+
+	return images, nil
+}
+
+// repoDigest returns the digest an image was pulled with from a repository.
+func repoDigest(digests []string, repository string) string {
+	for _, digest := range digests {
+		if strings.HasPrefix(digest, repository+"@") {
+			return strings.TrimPrefix(digest, repository+"@")
+		}
+	}
+	return ""
+}
+
+// RemoveImageTag untags an image, the image is deleted with its last tag.
+func (c Client) RemoveImageTag(ctx context.Context, image Image) error {
+	if _, err := c.api.ImageRemove(ctx, image.Ref(), types.ImageRemoveOptions{PruneChildren: true}); err != nil && !IsNotFound(err) {
+		return fmt.Errorf("error removing image %s: %s", image.Ref(), err.Error())
+	}
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
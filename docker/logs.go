@@ -0,0 +1,128 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+
+	"github.com/docker/docker/api/types"
+)
+
+// LogLine is a line logged by a container.
+type LogLine struct {
+	Container string
+	Stderr    bool
+	Text      string
+}
+
+// LogOptions selects the logs to stream.
+type LogOptions struct {
+	// Follow keeps streaming new lines until the context is done.
+	Follow bool
+
+	// Since only shows logs since a timestamp or a duration e.g 10m.
+	Since string
+
+	// Tail is the number of lines to show from the end of the logs, all when empty.
+	Tail string
+}
+
+// StreamLogs sends the logs of the containers to lines, one line at a time, until
+// the logs end or, when following, until ctx is done. Lines of a container are
+// in order, lines of different containers are interleaved as they arrive.
+func (c Client) StreamLogs(ctx context.Context, containers []string, options LogOptions, lines chan<- LogLine) error {
+	var wg sync.WaitGroup
+	errs := make(chan error, len(containers))
+	for _, name := range containers {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			if err := c.streamLogs(ctx, name, options, lines); err != nil && ctx.Err() == nil {
+				errs <- err
+			}
+		}(name)
+	}
+	wg.Wait()
+	close(errs)
+
+	return <-errs
+}
+
+func (c Client) streamLogs(ctx context.Context, name string, options LogOptions, lines chan<- LogLine) error {
+	inspect, err := c.api.ContainerInspect(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	tail := options.Tail
+	if tail == "" {
+		tail = "all"
+	}
+
+	stream, err := c.api.ContainerLogs(ctx, name, types.ContainerLogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Follow:     options.Follow,
+		Since:      options.Since,
+		Tail:       tail,
+	})
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	stdout := &lineWriter{ctx: ctx, container: name, lines: lines}
+	stderr := &lineWriter{ctx: ctx, container: name, stderr: true, lines: lines}
+	defer stdout.flush()
+	defer stderr.flush()
+
+	// containers with a TTY write a raw stream
+	if inspect.Config != nil && inspect.Config.Tty {
+		_, err := io.Copy(stdout, stream)
+		return err
+	}
+
+	return Demux(stdout, stderr, stream)
+}
+
+// lineWriter sends every complete line written to it.
+type lineWriter struct {
+	ctx       context.Context
+	container string
+	stderr    bool
+	lines     chan<- LogLine
+	buf       []byte
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i == -1 {
+			return len(p), nil
+		}
+
+		if err := w.send(string(bytes.TrimRight(w.buf[:i], "\r"))); err != nil {
+			return 0, err
+		}
+		w.buf = w.buf[i+1:]
+	}
+}
+
+// flush sends the last line when it does not end with a new line.
+func (w *lineWriter) flush() {
+	if len(w.buf) > 0 {
+		w.send(string(w.buf))
+		w.buf = nil
+	}
+}
+
+func (w *lineWriter) send(text string) error {
+	select {
+	case w.lines <- LogLine{Container: w.container, Stderr: w.stderr, Text: text}:
+		return nil
+	case <-w.ctx.Done():
+		return w.ctx.Err()
+	}
+}
@@ -0,0 +1,45 @@
+package docker_test
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/gangachris/hlf/docker"
+	"github.com/gangachris/hlf/docker/dockertest"
+)
+
+func TestClient_StreamLogs(t *testing.T) {
+	fake := dockertest.New()
+	fake.Logs["peer0"] = "starting\nStarted peer\n"
+	fake.Logs["peer1"] = "starting\nno trailing new line"
+	client := startPeer(t, fake, "peer0")
+	startPeer(t, fake, "peer1")
+
+	lines := make(chan docker.LogLine)
+	errs := make(chan error, 1)
+	go func() {
+		errs <- client.StreamLogs(context.Background(), []string{"peer0", "peer1"}, docker.LogOptions{Tail: "2"}, lines)
+		close(lines)
+	}()
+
+	var got []string
+	for line := range lines {
+		got = append(got, line.Container+": "+line.Text)
+	}
+	if err := <-errs; err != nil {
+		t.Fatalf("StreamLogs() error = %v", err)
+	}
+
+	sort.Strings(got)
+	want := []string{
+		"peer0: Started peer",
+		"peer0: starting",
+		"peer1: no trailing new line",
+		"peer1: starting",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("StreamLogs() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,78 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/docker/docker/api/types"
+)
+
+// ownedBy makes sure a resource carries the label of the hlf network, resources
+// created outside of hlf are never modified or removed.
+func ownedBy(kind, name string, labels map[string]string, hlfNetwork string) error {
+	owner, ok := labels[LabelNetwork]
+	if !ok {
+		return fmt.Errorf("error: %s %s already exists and was not created by hlf, remove or rename it first", kind, name)
+	}
+
+	if owner != hlfNetwork {
+		return fmt.Errorf("error: %s %s belongs to hlf network %s", kind, name, owner)
+	}
+
+	return nil
+}
+
+// CreateNetwork creates a bridge network for an hlf network. An existing network
+// of the same hlf network is reused, any other network with the name is an error.
+func (c Client) CreateNetwork(ctx context.Context, hlfNetwork, name string) (string, error) {
+	existing, err := c.api.NetworkInspect(ctx, name)
+	if err == nil {
+		if err := ownedBy("network", name, existing.Labels, hlfNetwork); err != nil {
+			return "", err
+		}
+		return existing.ID, nil
+	}
+
+	if !IsNotFound(err) {
+		return "", err
+	}
+
+	created, err := c.api.NetworkCreate(ctx, name, types.NetworkCreate{
+		CheckDuplicate: true,
+		Driver:         "bridge",
+		Labels:         map[string]string{LabelNetwork: hlfNetwork},
+	})
+	if err != nil {
+		return "", fmt.Errorf("error creating network %s: %s", name, err.Error())
+	}
+
+	return created.ID, nil
+}
+
+// InspectNetwork returns a network and the containers attached to it.
+func (c Client) InspectNetwork(ctx context.Context, name string) (types.NetworkResource, error) {
+	return c.api.NetworkInspect(ctx, name)
+}
+
+// Networks returns the networks with all the given labels.
+func (c Client) Networks(ctx context.Context, labels map[string]string) ([]types.NetworkResource, error) {
+	return c.api.NetworkList(ctx, types.NetworkListOptions{Filters: LabelFilters(labels)})
+}
+
+// RemoveNetwork removes a network of an hlf network, removing a missing network is not an error.
+func (c Client) RemoveNetwork(ctx context.Context, hlfNetwork, name string) error {
+	existing, err := c.api.NetworkInspect(ctx, name)
+	if IsNotFound(err) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if err := ownedBy("network", name, existing.Labels, hlfNetwork); err != nil {
+		return err
+	}
+
+	return c.api.NetworkRemove(ctx, existing.ID)
+}
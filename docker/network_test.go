@@ -0,0 +1,98 @@
+package docker_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/gangachris/hlf/docker"
+	"github.com/gangachris/hlf/docker/dockertest"
+)
+
+func TestClient_CreateNetwork(t *testing.T) {
+	ctx := context.Background()
+	fake := dockertest.New()
+	fake.NetworkCreate(ctx, "unmanaged", types.NetworkCreate{})
+
+	client := docker.NewWithAPI(fake, docker.RuntimeDocker)
+
+	id, err := client.CreateNetwork(ctx, "dev", "hlf_dev")
+	if err != nil {
+		t.Fatalf("CreateNetwork() error = %v", err)
+	}
+
+	again, err := client.CreateNetwork(ctx, "dev", "hlf_dev")
+	if err != nil || again != id {
+		t.Errorf("CreateNetwork() should reuse the network of the same hlf network, got %s, %v", again, err)
+	}
+
+	if _, err := client.CreateNetwork(ctx, "test", "hlf_dev"); err == nil {
+		t.Error("CreateNetwork() should refuse a network owned by another hlf network")
+	}
+
+	if _, err := client.CreateNetwork(ctx, "dev", "unmanaged"); err == nil {
+		t.Error("CreateNetwork() should refuse an unlabeled network")
+	}
+
+	if err := client.RemoveNetwork(ctx, "dev", "unmanaged"); err == nil {
+		t.Error("RemoveNetwork() should refuse to remove an unlabeled network")
+	}
+}
+
+func TestClient_FindOrphans(t *testing.T) {
+	ctx := context.Background()
+	fake := dockertest.New()
+	fake.AddImage(types.ImageSummary{RepoTags: []string{"hyperledger/fabric-peer:1.1.0"}})
+
+	client := docker.NewWithAPI(fake, docker.RuntimeDocker)
+	for _, network := range []string{"dev", "crashed", "stopped", "down"} {
+		if _, err := client.CreateNetwork(ctx, network, network); err != nil {
+			t.Fatal(err)
+		}
+
+		spec := peerSpec(network, "peer0."+network)
+		if _, err := client.CreateVolume(ctx, spec.Node, "peer0."+network); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := client.CreateContainer(ctx, spec); err != nil {
+			t.Fatal(err)
+		}
+		if err := client.Start(ctx, spec.Node.Name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fake.Exit("peer0.crashed", 2)
+	fake.Exit("peer0.stopped", 0)
+	if err := client.Remove(ctx, "peer0.down", true); err != nil {
+		t.Fatal(err)
+	}
+
+	// crashed has no spec, the containers of down were removed keeping its
+	// ledgers and stopped was stopped on purpose
+	orphans, err := client.FindOrphans(ctx, map[string]bool{"dev": true, "stopped": true, "down": true}, nil)
+	if err != nil {
+		t.Fatalf("FindOrphans() error = %v", err)
+	}
+
+	want := &docker.Orphans{
+		Containers: []string{"peer0.crashed"},
+		Networks:   []string{"crashed", "down"},
+		Volumes:    []string{"peer0.crashed", "peer0.down"},
+	}
+	if !reflect.DeepEqual(orphans, want) {
+		t.Errorf("FindOrphans() = %+v, want %+v", orphans, want)
+	}
+
+	if err := client.RemoveOrphans(ctx, orphans, false); err != nil {
+		t.Fatalf("RemoveOrphans() error = %v", err)
+	}
+
+	if _, err := client.InspectVolume(ctx, "peer0.crashed"); err != nil {
+		t.Errorf("RemoveOrphans() removed a volume without volumes set: %v", err)
+	}
+
+	if _, err := client.InspectNetwork(ctx, "crashed"); !docker.IsNotFound(err) {
+		t.Errorf("RemoveOrphans() did not remove the orphaned network: %v", err)
+	}
+}
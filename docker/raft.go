@@ -0,0 +1,108 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// RaftStatus is the raft consensus of a channel as its consenters log it.
+type RaftStatus struct {
+	Channel string `json:"channel"`
+
+	// Leader is the node elected by a majority of the consenters, empty while
+	// there is none.
+	Leader string `json:"leader"`
+
+	// Consenters are the running orderer nodes that take part in the consensus
+	// of the channel, Following are those that follow the leader.
+	Consenters []string `json:"consenters"`
+	Following  []string `json:"following"`
+}
+
+// raftNode matches the log lines of the etcdraft chain of a channel, which end
+// with the channel and the raft ID of the node e.g
+// Raft leader changed: 0 -> 2 channel=system-channel node=1
+var (
+	raftNode   = regexp.MustCompile(`channel=(\S+) node=(\d+)`)
+	raftLeader = regexp.MustCompile(`Raft leader changed: \d+ -> (\d+) channel=(\S+) node=\d+`)
+)
+
+// RaftStatuses returns the raft consensus of every channel of the running
+// orderer nodes with all the given labels, sorted by channel. It is read from
+// the logs of the nodes: the raft ID each node logs and the last leader change
+// it logged.
+func (c Client) RaftStatuses(ctx context.Context, labels map[string]string) ([]RaftStatus, error) {
+	containers, err := c.List(ctx, labels)
+	if err != nil {
+		return nil, err
+	}
+
+	// the node of every raft ID and the leader every node follows, by channel
+	nodes := map[string]map[int]string{}
+	leaders := map[string]map[string]int{}
+	for _, cont := range containers {
+		if Role(cont.Labels[LabelRole]) != RoleOrderer || cont.State != "running" {
+			continue
+		}
+		name := ContainerName(cont)
+
+		logs, err := c.logs(ctx, name, "all")
+		if err != nil {
+			return nil, fmt.Errorf("error reading the logs of %s: %s", name, err.Error())
+		}
+
+		for _, match := range raftNode.FindAllStringSubmatch(string(logs), -1) {
+			id, _ := strconv.Atoi(match[2])
+			if nodes[match[1]] == nil {
+				nodes[match[1]] = map[int]string{}
+				leaders[match[1]] = map[string]int{}
+			}
+			nodes[match[1]][id] = name
+			if _, ok := leaders[match[1]][name]; !ok {
+				leaders[match[1]][name] = 0
+			}
+		}
+		for _, match := range raftLeader.FindAllStringSubmatch(string(logs), -1) {
+			id, _ := strconv.Atoi(match[1])
+			leaders[match[2]][name] = id
+		}
+	}
+
+	var statuses []RaftStatus
+	for channel, following := range leaders {
+		status := RaftStatus{Channel: channel, Consenters: []string{}, Following: []string{}}
+
+		votes := map[int]int{}
+		for name, id := range following {
+			status.Consenters = append(status.Consenters, name)
+			if id != 0 {
+				votes[id]++
+			}
+		}
+		sort.Strings(status.Consenters)
+
+		for id, count := range votes {
+			if count*2 <= len(following) {
+				continue
+			}
+			status.Leader = nodes[channel][id]
+			if status.Leader == "" {
+				status.Leader = fmt.Sprintf("raft node %d", id)
+			}
+			for _, name := range status.Consenters {
+				if following[name] == id {
+					status.Following = append(status.Following, name)
+				}
+			}
+		}
+		statuses = append(statuses, status)
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Channel < statuses[j].Channel
+	})
+	return statuses, nil
+}
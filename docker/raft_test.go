@@ -0,0 +1,59 @@
+package docker_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/gangachris/hlf/docker"
+	"github.com/gangachris/hlf/docker/dockertest"
+)
+
+func TestClient_RaftStatuses(t *testing.T) {
+	fake := dockertest.New()
+	fake.AddImage(types.ImageSummary{RepoTags: []string{"hyperledger/fabric-orderer:2.2.0"}})
+
+	client := docker.NewWithAPI(fake, docker.RuntimeDocker)
+	ctx := context.Background()
+	for _, name := range []string{"orderer0.example.com", "orderer1.example.com", "orderer2.example.com", "orderer3.example.com"} {
+		spec := docker.ContainerSpec{
+			Node:  docker.Node{Network: "dev", Org: "Orderer", Role: docker.RoleOrderer, Name: name},
+			Image: "hyperledger/fabric-orderer:2.2.0",
+		}
+		if _, err := client.RunContainer(ctx, spec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := fake.Exit("orderer3.example.com", 2); err != nil {
+		t.Fatal(err)
+	}
+
+	prefix := "2020-06-01 10:00:00.000 UTC [orderer.consensus.etcdraft] "
+	fake.Logs["orderer0.example.com"] = prefix + "Start -> INFO 001 Starting raft node as part of a new channel channel=system-channel node=1\n" +
+		prefix + "serveRequest -> INFO 002 Raft leader changed: 0 -> 2 channel=system-channel node=1\n" +
+		prefix + "Start -> INFO 003 Starting raft node as part of a new channel channel=mychannel node=1\n"
+	fake.Logs["orderer1.example.com"] = prefix + "Start -> INFO 001 Starting raft node as part of a new channel channel=system-channel node=2\n" +
+		prefix + "serveRequest -> INFO 002 Raft leader changed: 0 -> 2 channel=system-channel node=2\n" +
+		prefix + "Start -> INFO 003 Starting raft node as part of a new channel channel=mychannel node=2\n" +
+		prefix + "serveRequest -> INFO 004 Raft leader changed: 0 -> 3 channel=mychannel node=2\n"
+	fake.Logs["orderer2.example.com"] = prefix + "Start -> INFO 001 Starting raft node as part of a new channel channel=system-channel node=3\n" +
+		prefix + "serveRequest -> INFO 002 Raft leader changed: 0 -> 1 channel=system-channel node=3\n" +
+		prefix + "serveRequest -> INFO 003 Raft leader changed: 1 -> 2 channel=system-channel node=3\n" +
+		prefix + "Start -> INFO 004 Starting raft node as part of a new channel channel=mychannel node=3\n"
+	fake.Logs["orderer3.example.com"] = prefix + "serveRequest -> INFO 001 Raft leader changed: 0 -> 4 channel=system-channel node=4\n"
+
+	got, err := client.RaftStatuses(ctx, map[string]string{docker.LabelNetwork: "dev"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	consenters := []string{"orderer0.example.com", "orderer1.example.com", "orderer2.example.com"}
+	want := []docker.RaftStatus{
+		// only one of the three consenters follows a leader, there is no majority
+		{Channel: "mychannel", Consenters: consenters, Following: []string{}},
+		{Channel: "system-channel", Leader: "orderer1.example.com", Consenters: consenters, Following: consenters},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("RaftStatuses() = %+v, want %+v", got, want)
+	}
+}
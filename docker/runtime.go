@@ -0,0 +1,134 @@
+package docker
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Runtime is the container engine serving the docker API.
+type Runtime string
+
+const (
+	// RuntimeAuto detects the runtime from the sockets available on the host.
+	RuntimeAuto Runtime = ""
+
+	// RuntimeDocker is the rootful docker daemon.
+	RuntimeDocker Runtime = "docker"
+
+	// RuntimeRootlessDocker is a docker daemon running in rootless mode.
+	RuntimeRootlessDocker Runtime = "rootless-docker"
+
+	// RuntimePodman is podman's docker compatible API service.
+	RuntimePodman Runtime = "podman"
+)
+
+// Runtimes lists the runtimes that can be selected with hlf config set runtime.
+var Runtimes = []Runtime{RuntimeDocker, RuntimeRootlessDocker, RuntimePodman}
+
+// ParseRuntime returns the runtime for the given name. An empty name or auto
+// returns RuntimeAuto.
+func ParseRuntime(name string) (Runtime, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" || name == "auto" {
+		return RuntimeAuto, nil
+	}
+
+	for _, runtime := range Runtimes {
+		if Runtime(name) == runtime {
+			return runtime, nil
+		}
+	}
+
+	return RuntimeAuto, fmt.Errorf("unknown runtime %q, expected one of auto, docker, rootless-docker, podman", name)
+}
+
+// SupportsNetworkAliases reports whether containers can be given network
+// aliases. Podman only honours aliases on non default networks with netavark,
+// so we address containers by their name instead.
+func (r Runtime) SupportsNetworkAliases() bool {
+	return r != RuntimePodman
+}
+
+// BindOptions returns the options appended to bind mounts e.g /src:/dst:z.
+// Podman is mostly used on SELinux enabled hosts (Fedora/RHEL) where bind
+// mounts need to be relabeled for containers to read them.
+func (r Runtime) BindOptions() string {
+	if r == RuntimePodman {
+		return "z"
+	}
+	return ""
+}
+
+// Endpoint is the docker API endpoint of a runtime.
+type Endpoint struct {
+	Runtime Runtime
+
+	// Host is the docker host e.g unix:///var/run/docker.sock
+	Host string
+}
+
+// SocketPath returns the path of the unix socket, peers mount it to build
+// and launch chaincode containers.
+func (e Endpoint) SocketPath() string {
+	return strings.TrimPrefix(e.Host, "unix://")
+}
+
+// DetectEndpoint finds the docker API endpoint for the given runtime. DOCKER_HOST
+// takes precedence when set, otherwise the well known sockets are tried in order:
+// rootful docker, rootless docker and podman.
+func DetectEndpoint(runtime Runtime) (*Endpoint, error) {
+	if host := os.Getenv("DOCKER_HOST"); host != "" {
+		if runtime == RuntimeAuto {
+			runtime = RuntimeDocker
+			if strings.Contains(host, "podman") {
+				runtime = RuntimePodman
+			}
+		}
+		return &Endpoint{Runtime: runtime, Host: host}, nil
+	}
+
+	candidates := socketCandidates(runtime, os.Getenv("XDG_RUNTIME_DIR"), os.Getuid())
+	for _, candidate := range candidates {
+		info, err := os.Stat(candidate.SocketPath())
+		if err == nil && info.Mode()&os.ModeSocket != 0 {
+			endpoint := candidate
+			return &endpoint, nil
+		}
+	}
+
+	var tried []string
+	for _, candidate := range candidates {
+		tried = append(tried, candidate.SocketPath())
+	}
+
+	return nil, fmt.Errorf("cannot find a docker API socket (tried %s). Is the docker daemon or podman.socket running?", strings.Join(tried, ", "))
+}
+
+// socketCandidates returns the sockets to try, in order, for the given runtime.
+func socketCandidates(runtime Runtime, xdgRuntimeDir string, uid int) []Endpoint {
+	if xdgRuntimeDir == "" {
+		xdgRuntimeDir = fmt.Sprintf("/run/user/%d", uid)
+	}
+
+	all := []Endpoint{
+		{Runtime: RuntimeDocker, Host: "unix:///var/run/docker.sock"},
+		{Runtime: RuntimeRootlessDocker, Host: "unix://" + filepath.Join(xdgRuntimeDir, "docker.sock")},
+		{Runtime: RuntimePodman, Host: "unix://" + filepath.Join(xdgRuntimeDir, "podman", "podman.sock")},
+		{Runtime: RuntimePodman, Host: "unix:///run/podman/podman.sock"},
+	}
+
+	if runtime == RuntimeAuto {
+		return all
+	}
+
+	var candidates []Endpoint
+	for _, candidate := range all {
+		if candidate.Runtime == runtime {
+			candidates = append(candidates, candidate)
+		}
+	}
+
+	return candidates
+}
@@ -0,0 +1,108 @@
+package docker
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseRuntime(t *testing.T) {
+	tests := []struct {
+		name    string
+		arg     string
+		want    Runtime
+		wantErr bool
+	}{
+		{
+			name: "empty runtime is auto",
+			arg:  "",
+			want: RuntimeAuto,
+		},
+		{
+			name: "auto runtime",
+			arg:  "auto",
+			want: RuntimeAuto,
+		},
+		{
+			name: "podman runtime ignoring case",
+			arg:  "Podman",
+			want: RuntimePodman,
+		},
+		{
+			name:    "unknown runtime",
+			arg:     "containerd",
+			want:    RuntimeAuto,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRuntime(tt.arg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseRuntime() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ParseRuntime() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_socketCandidates(t *testing.T) {
+	type args struct {
+		runtime       Runtime
+		xdgRuntimeDir string
+		uid           int
+	}
+	tests := []struct {
+		name string
+		args args
+		want []string
+	}{
+		{
+			name: "auto tries every socket",
+			args: args{
+				runtime:       RuntimeAuto,
+				xdgRuntimeDir: "/run/user/1000",
+			},
+			want: []string{
+				"/var/run/docker.sock",
+				"/run/user/1000/docker.sock",
+				"/run/user/1000/podman/podman.sock",
+				"/run/podman/podman.sock",
+			},
+		},
+		{
+			name: "podman sockets only",
+			args: args{
+				runtime:       RuntimePodman,
+				xdgRuntimeDir: "/run/user/1000",
+			},
+			want: []string{
+				"/run/user/1000/podman/podman.sock",
+				"/run/podman/podman.sock",
+			},
+		},
+		{
+			name: "rootless docker without XDG_RUNTIME_DIR",
+			args: args{
+				runtime: RuntimeRootlessDocker,
+				uid:     1001,
+			},
+			want: []string{
+				"/run/user/1001/docker.sock",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, endpoint := range socketCandidates(tt.args.runtime, tt.args.xdgRuntimeDir, tt.args.uid) {
+				got = append(got, endpoint.SocketPath())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("socketCandidates() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types"
+)
+
+// NodeStats is a resource usage sample of a container.
+type NodeStats struct {
+	Time      time.Time `json:"time"`
+	Container string    `json:"container"`
+	Role      Role      `json:"role"`
+
+	CPUPercent float64 `json:"cpu_percent"`
+
+	MemoryUsage   uint64  `json:"memory_usage"`
+	MemoryLimit   uint64  `json:"memory_limit"`
+	MemoryPercent float64 `json:"memory_percent"`
+
+	NetworkRx uint64 `json:"network_rx"`
+	NetworkTx uint64 `json:"network_tx"`
+
+	BlockRead  uint64 `json:"block_read"`
+	BlockWrite uint64 `json:"block_write"`
+
+	PIDs uint64 `json:"pids"`
+}
+
+// statsJSON is a sample as decoded from the daemon, the vendored API types
+// predate the online CPUs reported by API 1.27 and newer.
+type statsJSON struct {
+	types.StatsJSON
+	PreCPUStats cpuStats `json:"precpu_stats"`
+	CPUStats    cpuStats `json:"cpu_stats"`
+}
+
+type cpuStats struct {
+	types.CPUStats
+	OnlineCPUs uint32 `json:"online_cpus,omitempty"`
+}
+
+// Stats samples the resource usage of the running containers with all the given
+// labels and of the chaincode containers their peers started, sorted by name.
+func (c Client) Stats(ctx context.Context, labels map[string]string) ([]NodeStats, error) {
+	containers, err := c.List(ctx, labels)
+	if err != nil {
+		return nil, err
+	}
+
+	// chaincode containers run in the docker network of their peer
+	peers := map[string][]string{}
+	for _, cont := range containers {
+		if NodeFromLabels(cont.Labels).Role != RolePeer || cont.NetworkSettings == nil {
+			continue
+		}
+		for network := range cont.NetworkSettings.Networks {
+			peers[network] = append(peers[network], ContainerName(cont))
+		}
+	}
+
+	chaincodes, err := c.chaincodeContainers(ctx, peers)
+	if err != nil {
+		return nil, err
+	}
+	containers = append(containers, chaincodes...)
+
+	var running []types.Container
+	for _, cont := range containers {
+		if cont.State == "running" {
+			running = append(running, cont)
+		}
+	}
+
+	var wg sync.WaitGroup
+	stats := make([]NodeStats, len(running))
+	errs := make(chan error, len(running))
+	for i, cont := range running {
+		wg.Add(1)
+		go func(i int, cont types.Container) {
+			defer wg.Done()
+			sample, err := c.stats(ctx, ContainerName(cont))
+			if err != nil {
+				errs <- err
+				return
+			}
+
+			sample.Role = NodeFromLabels(cont.Labels).Role
+			if sample.Role == "" {
+				sample.Role = RoleChaincode
+			}
+			stats[i] = sample
+		}(i, cont)
+	}
+	wg.Wait()
+	close(errs)
+
+	if err := <-errs; err != nil {
+		return nil, err
+	}
+
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Container < stats[j].Container })
+	return stats, nil
+}
+
+// stats takes a single sample of a container, the daemon includes the previous
+// sample which the CPU usage is computed against.
+func (c Client) stats(ctx context.Context, name string) (NodeStats, error) {
+	response, err := c.api.ContainerStats(ctx, name, false)
+	if err != nil {
+		return NodeStats{}, err
+	}
+	defer response.Body.Close()
+
+	var sample statsJSON
+	if err := json.NewDecoder(response.Body).Decode(&sample); err != nil {
+		return NodeStats{}, err
+	}
+
+	stats := NodeStats{
+		Time:        sample.Read,
+		Container:   name,
+		CPUPercent:  cpuPercent(sample.PreCPUStats, sample.CPUStats),
+		MemoryUsage: memoryUsage(sample.MemoryStats),
+		MemoryLimit: sample.MemoryStats.Limit,
+		PIDs:        sample.PidsStats.Current,
+	}
+
+	if stats.MemoryLimit > 0 {
+		stats.MemoryPercent = float64(stats.MemoryUsage) / float64(stats.MemoryLimit) * 100
+	}
+
+	for _, network := range sample.Networks {
+		stats.NetworkRx += network.RxBytes
+		stats.NetworkTx += network.TxBytes
+	}
+
+	for _, entry := range sample.BlkioStats.IoServiceBytesRecursive {
+		switch strings.ToLower(entry.Op) {
+		case "read":
+			stats.BlockRead += entry.Value
+		case "write":
+			stats.BlockWrite += entry.Value
+		}
+	}
+
+	return stats, nil
+}
+
+// cpuPercent is the share of the host's CPUs used between two samples, 100% per CPU.
+// The online CPUs are counted when the daemon reports them, there is no per CPU
+// usage with cgroup v2.
+func cpuPercent(previous, current cpuStats) float64 {
+	cpuDelta := float64(current.CPUUsage.TotalUsage) - float64(previous.CPUUsage.TotalUsage)
+	systemDelta := float64(current.SystemUsage) - float64(previous.SystemUsage)
+	if cpuDelta <= 0 || systemDelta <= 0 {
+		return 0
+	}
+
+	cpus := int(current.OnlineCPUs)
+	if cpus == 0 {
+		cpus = len(current.CPUUsage.PercpuUsage)
+	}
+	if cpus == 0 {
+		cpus = 1
+	}
+
+	return cpuDelta / systemDelta * float64(cpus) * 100
+}
+
+// memoryUsage leaves out the page cache the kernel can reclaim, the way docker
+// stats does, inactive_file is reported by cgroup v2 and total_inactive_file by v1.
+func memoryUsage(stats types.MemoryStats) uint64 {
+	for _, key := range []string{"total_inactive_file", "inactive_file"} {
+		if inactive, ok := stats.Stats[key]; ok && inactive < stats.Usage {
+			return stats.Usage - inactive
+		}
+	}
+
+	return stats.Usage
+}
@@ -0,0 +1,80 @@
+package docker_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/gangachris/hlf/docker"
+	"github.com/gangachris/hlf/docker/dockertest"
+)
+
+func TestClient_Stats(t *testing.T) {
+	fake := dockertest.New()
+	client := startPeer(t, fake, "peer0.org1.example.com")
+	ctx := context.Background()
+
+	// chaincode containers are started by the peer without hlf labels, in its
+	// docker network
+	fake.AddImage(types.ImageSummary{RepoTags: []string{"dev-peer0.org1.example.com-mycc-1.0"}})
+	for _, name := range []string{"dev-peer0.org1.example.com-mycc-1.0", "dev-peer1.org2.example.com-mycc-1.0"} {
+		config := &container.Config{Image: "dev-peer0.org1.example.com-mycc-1.0"}
+		if _, err := fake.ContainerCreate(ctx, config, &container.HostConfig{NetworkMode: "dev"}, nil, name); err != nil {
+			t.Fatal(err)
+		}
+		if err := fake.ContainerStart(ctx, name, types.ContainerStartOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var peer types.StatsJSON
+	peer.PreCPUStats.CPUUsage.TotalUsage = 1000
+	peer.PreCPUStats.SystemUsage = 10000
+	peer.CPUStats.CPUUsage.TotalUsage = 2000
+	peer.CPUStats.CPUUsage.PercpuUsage = []uint64{1000, 1000}
+	peer.CPUStats.SystemUsage = 20000
+	peer.MemoryStats.Usage = 300
+	peer.MemoryStats.Limit = 1000
+	peer.MemoryStats.Stats = map[string]uint64{"total_inactive_file": 100}
+	peer.PidsStats.Current = 12
+	peer.Networks = map[string]types.NetworkStats{
+		"eth0": {RxBytes: 10, TxBytes: 20},
+		"eth1": {RxBytes: 1, TxBytes: 2},
+	}
+	peer.BlkioStats.IoServiceBytesRecursive = []types.BlkioStatEntry{
+		{Op: "Read", Value: 5},
+		{Op: "Write", Value: 7},
+		{Op: "Total", Value: 12},
+	}
+	fake.Stats["peer0.org1.example.com"] = peer
+
+	stats, err := client.Stats(ctx, map[string]string{docker.LabelNetwork: "dev"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []docker.NodeStats{
+		{
+			Container: "dev-peer0.org1.example.com-mycc-1.0",
+			Role:      docker.RoleChaincode,
+		},
+		{
+			Container:     "peer0.org1.example.com",
+			Role:          docker.RolePeer,
+			CPUPercent:    20,
+			MemoryUsage:   200,
+			MemoryLimit:   1000,
+			MemoryPercent: 20,
+			NetworkRx:     11,
+			NetworkTx:     22,
+			BlockRead:     5,
+			BlockWrite:    7,
+			PIDs:          12,
+		},
+	}
+	if !reflect.DeepEqual(stats, want) {
+		t.Errorf("Client.Stats() = %+v, want %+v", stats, want)
+	}
+}
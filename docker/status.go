@@ -0,0 +1,96 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// node health, reported by the operations endpoint of nodes that have one
+const (
+	HealthHealthy   = "healthy"
+	HealthUnhealthy = "unhealthy"
+)
+
+// NodeStatus is the state of the container of a node.
+type NodeStatus struct {
+	Name  string `json:"name"`
+	Role  Role   `json:"role"`
+	Org   string `json:"org,omitempty"`
+	State string `json:"state"`
+
+	// Health is healthy or unhealthy for running nodes with an operations
+	// endpoint, empty otherwise.
+	Health string `json:"health,omitempty"`
+
+	Image   string `json:"image"`
+	Version string `json:"version"`
+
+	// Ports are the published ports e.g 7053->7051.
+	Ports []string `json:"ports"`
+}
+
+// NodeStatuses returns the status of the containers with all the given labels
+// sorted by name, the operations endpoints are queried concurrently.
+func (c Client) NodeStatuses(ctx context.Context, labels map[string]string) ([]NodeStatus, error) {
+	containers, err := c.List(ctx, labels)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]NodeStatus, len(containers))
+	var wg sync.WaitGroup
+	for i, cont := range containers {
+		node := NodeFromLabels(cont.Labels)
+		statuses[i] = NodeStatus{
+			Name:    ContainerName(cont),
+			Role:    node.Role,
+			Org:     node.Org,
+			State:   cont.State,
+			Image:   cont.Image,
+			Version: ImageVersion(cont.Image),
+			Ports:   []string{},
+		}
+
+		for _, port := range cont.Ports {
+			if port.PublicPort != 0 {
+				statuses[i].Ports = append(statuses[i].Ports, fmt.Sprintf("%d->%d", port.PublicPort, port.PrivatePort))
+			}
+		}
+		sort.Strings(statuses[i].Ports)
+
+		port := cont.Labels[LabelOperationsPort]
+		if port == "" || cont.State != "running" {
+			continue
+		}
+		wg.Add(1)
+		go func(status *NodeStatus, url string) {
+			defer wg.Done()
+			status.Health = HealthUnhealthy
+			if healthy(ctx, url) {
+				status.Health = HealthHealthy
+			}
+		}(&statuses[i], "http://"+net.JoinHostPort("localhost", port)+"/healthz")
+	}
+	wg.Wait()
+
+	return statuses, nil
+}
+
+// ImageVersion returns the version of an image from its tag e.g 1.4.4 for
+// hyperledger/fabric-peer:x86_64-1.4.4, the tag without a machine prefix otherwise.
+func ImageVersion(image string) string {
+	i := strings.LastIndex(image, ":")
+	if i < 0 || strings.Contains(image[i:], "/") {
+		return "latest"
+	}
+
+	tag := image[i+1:]
+	if j := strings.LastIndex(tag, "-"); j >= 0 {
+		return tag[j+1:]
+	}
+	return tag
+}
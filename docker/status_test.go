@@ -0,0 +1,60 @@
+package docker_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/gangachris/hlf/docker"
+	"github.com/gangachris/hlf/docker/dockertest"
+)
+
+func TestClient_NodeStatuses(t *testing.T) {
+	fake := dockertest.New()
+	fake.AddImage(types.ImageSummary{RepoTags: []string{"hyperledger/fabric-peer:1.1.0"}})
+
+	client := docker.NewWithAPI(fake, docker.RuntimeDocker)
+	ctx := context.Background()
+	for _, name := range []string{"peer1.org1.example.com", "peer0.org1.example.com"} {
+		spec := peerSpec("dev", name)
+		if name == "peer0.org1.example.com" {
+			spec.Ports = map[int]int{7051: 7053}
+		}
+		if _, err := client.RunContainer(ctx, spec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := fake.Exit("peer1.org1.example.com", 1); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := client.NodeStatuses(ctx, map[string]string{docker.LabelNetwork: "dev"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []docker.NodeStatus{
+		{Name: "peer0.org1.example.com", Role: docker.RolePeer, Org: "Org1", State: "running", Image: "hyperledger/fabric-peer:1.1.0", Version: "1.1.0", Ports: []string{"7053->7051"}},
+		{Name: "peer1.org1.example.com", Role: docker.RolePeer, Org: "Org1", State: "exited", Image: "hyperledger/fabric-peer:1.1.0", Version: "1.1.0", Ports: []string{"7051->7051"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("NodeStatuses() = %+v, want %+v", got, want)
+	}
+}
+
+func TestImageVersion(t *testing.T) {
+	tests := []struct {
+		image string
+		want  string
+	}{
+		{"hyperledger/fabric-peer:x86_64-1.4.4", "1.4.4"},
+		{"hyperledger/fabric-peer:2.2", "2.2"},
+		{"localhost:5000/hyperledger/fabric-peer", "latest"},
+		{"hyperledger/fabric-couchdb:amd64-0.4.6", "0.4.6"},
+	}
+	for _, tt := range tests {
+		if got := docker.ImageVersion(tt.image); got != tt.want {
+			t.Errorf("ImageVersion(%q) = %q, want %q", tt.image, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package docker
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	// streams of a multiplexed stream as written by the daemon for containers without a TTY
+	stdinStream  byte = 0
+	stdoutStream byte = 1
+	stderrStream byte = 2
+
+	// stdHeaderLen is the size of the header preceding each frame: [stream, 0, 0, 0, size (4 bytes)]
+	stdHeaderLen = 8
+)
+
+// Demux copies a multiplexed stream (logs, exec output of containers without a TTY)
+// into stdout and stderr until src is exhausted.
+func Demux(stdout, stderr io.Writer, src io.Reader) error {
+	header := make([]byte, stdHeaderLen)
+	for {
+		if _, err := io.ReadFull(src, header); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+
+		var dst io.Writer
+		switch header[0] {
+		case stdinStream, stdoutStream:
+			dst = stdout
+		case stderrStream:
+			dst = stderr
+		default:
+			return fmt.Errorf("error demultiplexing stream: unknown stream %d", header[0])
+		}
+
+		size := int64(binary.BigEndian.Uint32(header[4:]))
+		if _, err := io.CopyN(dst, src, size); err != nil {
+			return err
+		}
+	}
+}
+
+// MuxFrame returns a single frame of a multiplexed stream, the inverse of Demux.
+func MuxFrame(stderr bool, payload []byte) []byte {
+	frame := make([]byte, stdHeaderLen, stdHeaderLen+len(payload))
+	frame[0] = stdoutStream
+	if stderr {
+		frame[0] = stderrStream
+	}
+	binary.BigEndian.PutUint32(frame[4:], uint32(len(payload)))
+	return append(frame, payload...)
+}
+
+// jsonMessage is a progress message streamed by the daemon e.g when pulling images.
+type jsonMessage struct {
+	Status      string `json:"status,omitempty"`
+	ID          string `json:"id,omitempty"`
+	Error       string `json:"error,omitempty"`
+	ErrorDetail *struct {
+		Message string `json:"message"`
+	} `json:"errorDetail,omitempty"`
+}
+
+// readJSONMessages reads a progress stream to the end, the daemon only finishes
+// pulling once the stream is consumed. Failures are reported inside the stream.
+func readJSONMessages(stream io.Reader) error {
+	decoder := json.NewDecoder(stream)
+	for {
+		var message jsonMessage
+		if err := decoder.Decode(&message); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+
+		if message.ErrorDetail != nil && message.ErrorDetail.Message != "" {
+			return errors.New(message.ErrorDetail.Message)
+		}
+
+		if message.Error != "" {
+			return errors.New(message.Error)
+		}
+	}
+}
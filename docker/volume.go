@@ -0,0 +1,70 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/docker/docker/api/types"
+	volumetypes "github.com/docker/docker/api/types/volume"
+)
+
+// CreateVolume creates a named volume for a node of an hlf network e.g the ledger
+// of a peer. An existing volume of the same hlf network is reused, any other
+// volume with the name is an error.
+func (c Client) CreateVolume(ctx context.Context, node Node, name string) (string, error) {
+	existing, err := c.api.VolumeInspect(ctx, name)
+	if err == nil {
+		if err := ownedBy("volume", name, existing.Labels, node.Network); err != nil {
+			return "", err
+		}
+		return existing.Name, nil
+	}
+
+	if !IsNotFound(err) {
+		return "", err
+	}
+
+	created, err := c.api.VolumeCreate(ctx, volumetypes.VolumesCreateBody{
+		Name:   name,
+		Driver: "local",
+		Labels: node.Labels(),
+	})
+	if err != nil {
+		return "", fmt.Errorf("error creating volume %s: %s", name, err.Error())
+	}
+
+	return created.Name, nil
+}
+
+// InspectVolume returns a volume.
+func (c Client) InspectVolume(ctx context.Context, name string) (types.Volume, error) {
+	return c.api.VolumeInspect(ctx, name)
+}
+
+// Volumes returns the volumes with all the given labels.
+func (c Client) Volumes(ctx context.Context, labels map[string]string) ([]*types.Volume, error) {
+	volumes, err := c.api.VolumeList(ctx, LabelFilters(labels))
+	if err != nil {
+		return nil, err
+	}
+
+	return volumes.Volumes, nil
+}
+
+// RemoveVolume removes a volume of an hlf network, removing a missing volume is not an error.
+func (c Client) RemoveVolume(ctx context.Context, hlfNetwork, name string) error {
+	existing, err := c.api.VolumeInspect(ctx, name)
+	if IsNotFound(err) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if err := ownedBy("volume", name, existing.Labels, hlfNetwork); err != nil {
+		return err
+	}
+
+	return c.api.VolumeRemove(ctx, name, false)
+}
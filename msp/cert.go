@@ -0,0 +1,246 @@
+package msp
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// privateKeyFile is the name of private keys in keystore, ca and tlsca
+// directories from fabric 2.0.
+const privateKeyFile = "priv_sk"
+
+// keyFile returns the name of a private key in keystore, ca and tlsca
+// directories, named after its subject key identifier before fabric 2.0.
+func (o Options) keyFile(key *ecdsa.PublicKey) string {
+	if o.SKIKeyNames {
+		return hex.EncodeToString(subjectKeyID(key)) + "_sk"
+	}
+	return privateKeyFile
+}
+
+// ca is a certificate authority of an organization, signing or TLS.
+type ca struct {
+	cert    *x509.Certificate
+	certPEM []byte
+	key     *ecdsa.PrivateKey
+}
+
+func newKey() (*ecdsa.PrivateKey, error) {
+	return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+}
+
+// subjectKeyID is the SHA-256 of the public key, as cryptogen computes it.
+func subjectKeyID(key *ecdsa.PublicKey) []byte {
+	sum := sha256.Sum256(elliptic.Marshal(key.Curve, key.X, key.Y))
+	return sum[:]
+}
+
+func serialNumber() (*big.Int, error) {
+	return rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+}
+
+// notBefore is a little in the past so that clocks of containers slightly
+// behind the host accept new certificates.
+func notBefore() time.Time {
+	return time.Now().Round(time.Minute).Add(-5 * time.Minute).UTC()
+}
+
+// subject returns a subject with the fields of the options and a common name.
+func (o Options) subject(commonName string, organizationalUnits ...string) pkix.Name {
+	name := pkix.Name{CommonName: commonName, OrganizationalUnit: organizationalUnits}
+	if o.Subject.Country != "" {
+		name.Country = []string{o.Subject.Country}
+	}
+	if o.Subject.Province != "" {
+		name.Province = []string{o.Subject.Province}
+	}
+	if o.Subject.Locality != "" {
+		name.Locality = []string{o.Subject.Locality}
+	}
+	return name
+}
+
+// newCA creates a self-signed CA of an organization domain.
+func newCA(commonName, domain string, opts Options) (*ca, error) {
+	key, err := newKey()
+	if err != nil {
+		return nil, err
+	}
+	serial, err := serialNumber()
+	if err != nil {
+		return nil, err
+	}
+
+	subject := opts.subject(commonName)
+	subject.Organization = []string{domain}
+	if opts.Subject.OrganizationalUnit != "" {
+		subject.OrganizationalUnit = []string{opts.Subject.OrganizationalUnit}
+	}
+
+	start := notBefore()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               subject,
+		NotBefore:             start,
+		NotAfter:              start.Add(opts.CAValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment | x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		SubjectKeyId:          subjectKeyID(&key.PublicKey),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	return &ca{cert: cert, certPEM: encodeCert(der), key: key}, nil
+}
+
+// loadOrCreateCA reads the CA of a directory, it is created when the directory
+// has none.
+func loadOrCreateCA(dir, commonName, domain string, opts Options) (*ca, bool, error) {
+	certFile := filepath.Join(dir, commonName+"-cert.pem")
+	if _, err := os.Stat(certFile); err == nil {
+		authority, err := loadCA(certFile, dir)
+		return authority, false, err
+	}
+
+	authority, err := newCA(commonName, domain, opts)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := writeFile(certFile, authority.certPEM, 0644); err != nil {
+		return nil, false, err
+	}
+	if err := writeKey(filepath.Join(dir, opts.keyFile(&authority.key.PublicKey)), authority.key); err != nil {
+		return nil, false, err
+	}
+	return authority, true, nil
+}
+
+// loadCA reads the certificate of a CA and its key in dir, named after the
+// subject key identifier of the certificate or priv_sk, whatever the version
+// it was generated for.
+func loadCA(certFile, dir string) (*ca, error) {
+	certPEM, err := ioutil.ReadFile(certFile)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		return nil, fmt.Errorf("error reading %s: no certificate found", certFile)
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %s", certFile, err.Error())
+	}
+
+	keyFile := filepath.Join(dir, hex.EncodeToString(cert.SubjectKeyId)+"_sk")
+	if _, err := os.Stat(keyFile); err != nil {
+		keyFile = filepath.Join(dir, privateKeyFile)
+	}
+	keyPEM, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	block, _ = pem.Decode(keyPEM)
+	if block == nil {
+		return nil, fmt.Errorf("error reading %s: no private key found", keyFile)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %s", keyFile, err.Error())
+	}
+	key, ok := parsed.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("error reading %s: expected an ECDSA key", keyFile)
+	}
+	return &ca{cert: cert, certPEM: certPEM, key: key}, nil
+}
+
+// issue signs a certificate for a new key.
+func (c *ca) issue(template *x509.Certificate, validity time.Duration) ([]byte, *ecdsa.PrivateKey, error) {
+	key, err := newKey()
+	if err != nil {
+		return nil, nil, err
+	}
+	serial, err := serialNumber()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	template.SerialNumber = serial
+	template.NotBefore = notBefore()
+	template.NotAfter = template.NotBefore.Add(validity)
+	template.BasicConstraintsValid = true
+	template.SubjectKeyId = subjectKeyID(&key.PublicKey)
+
+	der, err := x509.CreateCertificate(rand.Reader, template, c.cert, &key.PublicKey, c.key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return encodeCert(der), key, nil
+}
+
+// signingTemplate is the template of an identity certificate, with its node OU.
+func signingTemplate(opts Options, commonName, nodeOU string) *x509.Certificate {
+	return &x509.Certificate{
+		Subject:  opts.subject(commonName, nodeOU),
+		KeyUsage: x509.KeyUsageDigitalSignature,
+	}
+}
+
+// tlsTemplate is the template of a TLS certificate, hosts are DNS names or IP
+// addresses.
+func tlsTemplate(opts Options, commonName string, hosts []string) *x509.Certificate {
+	template := &x509.Certificate{
+		Subject:     opts.subject(commonName),
+		KeyUsage:    x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+	return template
+}
+
+func encodeCert(der []byte) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func writeKey(path string, key *ecdsa.PrivateKey) error {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return err
+	}
+	return writeFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600)
+}
+
+func writeFile(path string, data []byte, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, perm)
+}
@@ -0,0 +1,329 @@
+// Package msp generates the MSP and TLS material of the organizations of a
+// network in the directory layout of cryptogen, with ECDSA P-256 keys. Unlike
+// cryptogen it keeps the CAs of an organization once they exist, so nodes and
+// users can be added and their certificates rotated without generating the
+// material of the whole network again.
+package msp
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"text/template"
+	"time"
+
+	"github.com/gangachris/hlf/semver"
+	"github.com/gangachris/hlf/spec"
+)
+
+// node OUs, the organizational unit of an identity certificate that tells its role
+const (
+	ClientOU  = "client"
+	PeerOU    = "peer"
+	AdminOU   = "admin"
+	OrdererOU = "orderer"
+)
+
+// AdminUser is the name of the admin user of every organization.
+const AdminUser = "Admin"
+
+// Options configure the certificates.
+type Options struct {
+	// CAValidity is how long the CA certificates are valid.
+	CAValidity time.Duration
+
+	// Validity is how long node and user certificates are valid.
+	Validity time.Duration
+
+	// Subject are the country, province, locality and organizational unit of
+	// the certificates.
+	Subject spec.Subject
+
+	// SANs are extra subject alternative names of the node TLS certificates.
+	SANs []string
+
+	// AdminOU identifies admins and orderers by their node OU, fabric 1.4.3 and
+	// newer. Before, admin certificates are copied to the admincerts of every
+	// MSP and orderers are identified as peers.
+	AdminOU bool
+
+	// SKIKeyNames names private keys <subject key identifier>_sk as cryptogen
+	// does before fabric 2.0, they are named priv_sk from 2.0.
+	SKIKeyNames bool
+}
+
+// OptionsFromSpec returns the options of the crypto section of a network spec.
+func OptionsFromSpec(network *spec.Network) (Options, error) {
+	caValidity, err := time.ParseDuration(network.Crypto.CAValidity)
+	if err != nil {
+		return Options{}, fmt.Errorf("error parsing the CA validity: %s", err.Error())
+	}
+	validity, err := time.ParseDuration(network.Crypto.Validity)
+	if err != nil {
+		return Options{}, fmt.Errorf("error parsing the validity: %s", err.Error())
+	}
+
+	adminOU, err := semver.CorrectVersion("1.4.3", network.Fabric)
+	adminOU = err == nil && adminOU
+	privSK, err := semver.CorrectVersion("2.0", network.Fabric)
+	return Options{
+		CAValidity:  caValidity,
+		Validity:    validity,
+		Subject:     network.Crypto.Subject,
+		SANs:        network.Crypto.SANs,
+		AdminOU:     adminOU,
+		SKIKeyNames: err == nil && !privSK,
+	}, nil
+}
+
+// Org is an organization to generate the material of.
+type Org struct {
+	Domain string
+
+	// Orderer is set for orderer organizations, their nodes are orderers.
+	Orderer bool
+
+	// Nodes are the host names of the nodes in the domain e.g peer0.
+	Nodes []string
+
+	// Users is the number of users besides the admin, User1, User2...
+	Users int
+}
+
+// FromSpec returns the orderer and peer organizations of a network spec.
+func FromSpec(network *spec.Network) []Org {
+	var orgs []Org
+	for _, org := range network.Orderer.Organizations {
+		o := Org{Domain: org.Domain, Orderer: true}
+		for _, node := range network.OrdererNodes() {
+			if node.Organization.Name == org.Name {
+				o.Nodes = append(o.Nodes, node.Name())
+			}
+		}
+		orgs = append(orgs, o)
+	}
+
+	for _, org := range network.Organizations {
+		o := Org{Domain: org.Domain, Users: org.Users}
+		for i := 0; i < org.Peers; i++ {
+			o.Nodes = append(o.Nodes, org.PeerName(i))
+		}
+		orgs = append(orgs, o)
+	}
+	return orgs
+}
+
+// Dir returns the directory of the organization in the output directory e.g
+// peerOrganizations/org1.example.com.
+func (o Org) Dir() string {
+	if o.Orderer {
+		return filepath.Join("ordererOrganizations", o.Domain)
+	}
+	return filepath.Join("peerOrganizations", o.Domain)
+}
+
+// nodesDir is the directory of the nodes in the organization directory.
+func (o Org) nodesDir() string {
+	if o.Orderer {
+		return "orderers"
+	}
+	return "peers"
+}
+
+// Result lists the identities of a generation.
+type Result struct {
+	// Generated are the identities that were issued a certificate.
+	Generated []string
+	// Kept are the existing identities.
+	Kept []string
+}
+
+// Generator writes the material of organizations to an output directory, the
+// crypto-config directory of the artifacts.
+type Generator struct {
+	Dir     string
+	Options Options
+}
+
+// identity is a node or a user of an organization.
+type identity struct {
+	// name is the fully qualified name e.g peer0.org1.example.com or Admin@org1.example.com.
+	name   string
+	dir    string
+	nodeOU string
+	// hosts are the subject alternative names of the TLS certificate of a node.
+	hosts []string
+	user  bool
+}
+
+// Generate writes the material of an organization. The CAs and the existing
+// identities are kept, rotate issues new keys and certificates for the
+// identities while keeping the CAs.
+func (g Generator) Generate(org Org, rotate bool) (Result, error) {
+	var result Result
+	dir := filepath.Join(g.Dir, org.Dir())
+
+	signCA, created, err := loadOrCreateCA(filepath.Join(dir, "ca"), "ca."+org.Domain, org.Domain, g.Options)
+	if err != nil {
+		return result, fmt.Errorf("error creating the CA of %s: %s", org.Domain, err.Error())
+	}
+	tlsCA, tlsCreated, err := loadOrCreateCA(filepath.Join(dir, "tlsca"), "tlsca."+org.Domain, org.Domain, g.Options)
+	if err != nil {
+		return result, fmt.Errorf("error creating the TLS CA of %s: %s", org.Domain, err.Error())
+	}
+	// identities of a new CA are issued again, the old ones are not trusted anymore
+	rotate = rotate || created || tlsCreated
+
+	identities := []identity{g.user(org, dir, AdminUser)}
+	for _, node := range org.Nodes {
+		identities = append(identities, g.node(org, dir, node))
+	}
+	for i := 1; i <= org.Users; i++ {
+		identities = append(identities, g.user(org, dir, fmt.Sprintf("User%d", i)))
+	}
+
+	for _, id := range identities {
+		certFile := filepath.Join(id.dir, "msp", "signcerts", id.name+"-cert.pem")
+		if _, err := os.Stat(certFile); err == nil && !rotate {
+			result.Kept = append(result.Kept, id.name)
+			continue
+		}
+		if err := g.issue(id, certFile, signCA, tlsCA); err != nil {
+			return result, fmt.Errorf("error generating the material of %s: %s", id.name, err.Error())
+		}
+		result.Generated = append(result.Generated, id.name)
+	}
+
+	// the MSP files every identity shares are written again, the admin
+	// certificate changes when it is rotated
+	var adminCert []byte
+	if !g.Options.AdminOU {
+		admin := identities[0]
+		if adminCert, err = ioutil.ReadFile(filepath.Join(admin.dir, "msp", "signcerts", admin.name+"-cert.pem")); err != nil {
+			return result, err
+		}
+	}
+
+	mspDirs := []string{filepath.Join(dir, "msp")}
+	for _, id := range identities {
+		mspDirs = append(mspDirs, filepath.Join(id.dir, "msp"))
+	}
+	for _, mspDir := range mspDirs {
+		if err := g.writeMSP(mspDir, org, signCA, tlsCA, adminCert); err != nil {
+			return result, fmt.Errorf("error writing %s: %s", mspDir, err.Error())
+		}
+	}
+	return result, nil
+}
+
+func (g Generator) node(org Org, dir, host string) identity {
+	name := host + "." + org.Domain
+	nodeOU := PeerOU
+	if org.Orderer && g.Options.AdminOU {
+		nodeOU = OrdererOU
+	}
+	return identity{
+		name:   name,
+		dir:    filepath.Join(dir, org.nodesDir(), name),
+		nodeOU: nodeOU,
+		hosts:  append([]string{name, host, "localhost", "127.0.0.1"}, g.Options.SANs...),
+	}
+}
+
+func (g Generator) user(org Org, dir, user string) identity {
+	name := user + "@" + org.Domain
+	nodeOU := ClientOU
+	if user == AdminUser && g.Options.AdminOU {
+		nodeOU = AdminOU
+	}
+	return identity{
+		name:   name,
+		dir:    filepath.Join(dir, "users", name),
+		nodeOU: nodeOU,
+		user:   true,
+	}
+}
+
+// issue writes the signing and TLS keys and certificates of an identity.
+func (g Generator) issue(id identity, certFile string, signCA, tlsCA *ca) error {
+	cert, key, err := signCA.issue(signingTemplate(g.Options, id.name, id.nodeOU), g.Options.Validity)
+	if err != nil {
+		return err
+	}
+	// the keystore only holds the key of the current certificate
+	keystore := filepath.Join(id.dir, "msp", "keystore")
+	if err := os.RemoveAll(keystore); err != nil {
+		return err
+	}
+	if err := writeKey(filepath.Join(keystore, g.Options.keyFile(&key.PublicKey)), key); err != nil {
+		return err
+	}
+	if err := writeFile(certFile, cert, 0644); err != nil {
+		return err
+	}
+
+	tlsCert, tlsKey, err := tlsCA.issue(tlsTemplate(g.Options, id.name, id.hosts), g.Options.Validity)
+	if err != nil {
+		return err
+	}
+	prefix := "server"
+	if id.user {
+		prefix = "client"
+	}
+	tlsDir := filepath.Join(id.dir, "tls")
+	if err := writeFile(filepath.Join(tlsDir, "ca.crt"), tlsCA.certPEM, 0644); err != nil {
+		return err
+	}
+	if err := writeFile(filepath.Join(tlsDir, prefix+".crt"), tlsCert, 0644); err != nil {
+		return err
+	}
+	return writeKey(filepath.Join(tlsDir, prefix+".key"), tlsKey)
+}
+
+// writeMSP writes the CA certificates, the admin certificate when admins are
+// not identified by their OU and the node OUs config of an MSP directory.
+func (g Generator) writeMSP(dir string, org Org, signCA, tlsCA *ca, adminCert []byte) error {
+	caFile := "ca." + org.Domain + "-cert.pem"
+	if err := writeFile(filepath.Join(dir, "cacerts", caFile), signCA.certPEM, 0644); err != nil {
+		return err
+	}
+	if err := writeFile(filepath.Join(dir, "tlscacerts", "tlsca."+org.Domain+"-cert.pem"), tlsCA.certPEM, 0644); err != nil {
+		return err
+	}
+
+	if adminCert != nil {
+		if err := writeFile(filepath.Join(dir, "admincerts", AdminUser+"@"+org.Domain+"-cert.pem"), adminCert, 0644); err != nil {
+			return err
+		}
+	}
+
+	var buf bytes.Buffer
+	err := nodeOUsTemplate.Execute(&buf, struct {
+		CACert  string
+		AdminOU bool
+	}{filepath.ToSlash(filepath.Join("cacerts", caFile)), g.Options.AdminOU})
+	if err != nil {
+		return err
+	}
+	return writeFile(filepath.Join(dir, "config.yaml"), buf.Bytes(), 0644)
+}
+
+var nodeOUsTemplate = template.Must(template.New("config.yaml").Parse(`NodeOUs:
+  Enable: true
+  ClientOUIdentifier:
+    Certificate: {{ .CACert }}
+    OrganizationalUnitIdentifier: client
+  PeerOUIdentifier:
+    Certificate: {{ .CACert }}
+    OrganizationalUnitIdentifier: peer
+{{- if .AdminOU }}
+  AdminOUIdentifier:
+    Certificate: {{ .CACert }}
+    OrganizationalUnitIdentifier: admin
+  OrdererOUIdentifier:
+    Certificate: {{ .CACert }}
+    OrganizationalUnitIdentifier: orderer
+{{- end }}
+`))
@@ -0,0 +1,253 @@
+package msp
+
+import (
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gangachris/hlf/spec"
+)
+
+func readCert(t *testing.T, path string) *x509.Certificate {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		t.Fatalf("%s: no certificate found", path)
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+// verify checks a certificate was issued by a CA.
+func verify(t *testing.T, cert, ca *x509.Certificate) {
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+	_, err := cert.Verify(x509.VerifyOptions{Roots: roots, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny}})
+	if err != nil {
+		t.Errorf("%s: %v", cert.Subject.CommonName, err)
+	}
+}
+
+func TestGenerator_Generate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hlf-msp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	g := Generator{
+		Dir: dir,
+		Options: Options{
+			CAValidity: 48 * time.Hour,
+			Validity:   24 * time.Hour,
+			Subject:    spec.Subject{Country: "KE", Locality: "Nairobi"},
+			SANs:       []string{"peers.example.org", "10.0.0.5"},
+			AdminOU:    true,
+		},
+	}
+	org := Org{Domain: "org1.example.com", Nodes: []string{"peer0"}, Users: 1}
+
+	result, err := g.Generate(org, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"Admin@org1.example.com", "peer0.org1.example.com", "User1@org1.example.com"}
+	if !reflect.DeepEqual(result.Generated, want) {
+		t.Errorf("Generate() generated %v, want %v", result.Generated, want)
+	}
+
+	orgDir := filepath.Join(dir, "peerOrganizations", "org1.example.com")
+	for _, file := range []string{
+		"ca/priv_sk",
+		"tlsca/priv_sk",
+		"msp/cacerts/ca.org1.example.com-cert.pem",
+		"msp/tlscacerts/tlsca.org1.example.com-cert.pem",
+		"msp/config.yaml",
+		"peers/peer0.org1.example.com/msp/keystore/priv_sk",
+		"peers/peer0.org1.example.com/msp/config.yaml",
+		"peers/peer0.org1.example.com/tls/ca.crt",
+		"peers/peer0.org1.example.com/tls/server.key",
+		"users/Admin@org1.example.com/tls/client.crt",
+		"users/User1@org1.example.com/msp/signcerts/User1@org1.example.com-cert.pem",
+	} {
+		if _, err := os.Stat(filepath.Join(orgDir, file)); err != nil {
+			t.Errorf("missing %s", file)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(orgDir, "msp", "admincerts")); err == nil {
+		t.Error("admincerts written, admins are identified by their OU")
+	}
+
+	ca := readCert(t, filepath.Join(orgDir, "ca", "ca.org1.example.com-cert.pem"))
+	tlsCA := readCert(t, filepath.Join(orgDir, "tlsca", "tlsca.org1.example.com-cert.pem"))
+	if !ca.IsCA || ca.Subject.Organization[0] != "org1.example.com" || ca.Subject.Country[0] != "KE" {
+		t.Errorf("CA subject = %v, want a CA of org1.example.com in KE", ca.Subject)
+	}
+	if got := ca.NotAfter.Sub(ca.NotBefore); got != 48*time.Hour {
+		t.Errorf("CA validity = %s, want 48h", got)
+	}
+
+	peer := readCert(t, filepath.Join(orgDir, "peers/peer0.org1.example.com/msp/signcerts/peer0.org1.example.com-cert.pem"))
+	verify(t, peer, ca)
+	if !reflect.DeepEqual(peer.Subject.OrganizationalUnit, []string{PeerOU}) || peer.Subject.Locality[0] != "Nairobi" {
+		t.Errorf("peer subject = %v, want the peer OU in Nairobi", peer.Subject)
+	}
+	if got := peer.NotAfter.Sub(peer.NotBefore); got != 24*time.Hour {
+		t.Errorf("peer validity = %s, want 24h", got)
+	}
+
+	admin := readCert(t, filepath.Join(orgDir, "users/Admin@org1.example.com/msp/signcerts/Admin@org1.example.com-cert.pem"))
+	if !reflect.DeepEqual(admin.Subject.OrganizationalUnit, []string{AdminOU}) {
+		t.Errorf("admin OU = %v, want %s", admin.Subject.OrganizationalUnit, AdminOU)
+	}
+
+	server := readCert(t, filepath.Join(orgDir, "peers/peer0.org1.example.com/tls/server.crt"))
+	verify(t, server, tlsCA)
+	if err := server.VerifyHostname("peer0"); err != nil {
+		t.Error(err)
+	}
+	if err := server.VerifyHostname("peers.example.org"); err != nil {
+		t.Error(err)
+	}
+	if err := server.VerifyHostname("10.0.0.5"); err != nil {
+		t.Error(err)
+	}
+
+	config, err := ioutil.ReadFile(filepath.Join(orgDir, "msp", "config.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(config), "Certificate: cacerts/ca.org1.example.com-cert.pem\n    OrganizationalUnitIdentifier: admin") {
+		t.Errorf("config.yaml =\n%s\nwant the admin OU", config)
+	}
+
+	// a node is added, the CAs and the other identities are kept
+	org.Nodes = append(org.Nodes, "peer1")
+	result, err = g.Generate(org, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(result.Generated, []string{"peer1.org1.example.com"}) {
+		t.Errorf("Generate() generated %v, want peer1.org1.example.com", result.Generated)
+	}
+	if got := readCert(t, filepath.Join(orgDir, "ca", "ca.org1.example.com-cert.pem")); !got.Equal(ca) {
+		t.Error("the CA was generated again")
+	}
+	verify(t, readCert(t, filepath.Join(orgDir, "peers/peer1.org1.example.com/msp/signcerts/peer1.org1.example.com-cert.pem")), ca)
+
+	// rotation issues new certificates with the same CA
+	result, err = g.Generate(org, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Generated) != 4 || len(result.Kept) != 0 {
+		t.Errorf("Generate() = %+v, want every identity rotated", result)
+	}
+	rotated := readCert(t, filepath.Join(orgDir, "peers/peer0.org1.example.com/msp/signcerts/peer0.org1.example.com-cert.pem"))
+	if rotated.Equal(peer) {
+		t.Error("the peer certificate was not rotated")
+	}
+	verify(t, rotated, ca)
+}
+
+func TestGenerator_Generate_admincerts(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hlf-msp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	g := Generator{Dir: dir, Options: Options{CAValidity: time.Hour, Validity: time.Hour}}
+	if _, err := g.Generate(Org{Domain: "example.com", Orderer: true, Nodes: []string{"orderer"}}, false); err != nil {
+		t.Fatal(err)
+	}
+
+	orgDir := filepath.Join(dir, "ordererOrganizations", "example.com")
+	admin, err := ioutil.ReadFile(filepath.Join(orgDir, "users/Admin@example.com/msp/signcerts/Admin@example.com-cert.pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, mspDir := range []string{"msp", "orderers/orderer.example.com/msp"} {
+		got, err := ioutil.ReadFile(filepath.Join(orgDir, mspDir, "admincerts", "Admin@example.com-cert.pem"))
+		if err != nil || string(got) != string(admin) {
+			t.Errorf("%s/admincerts does not have the admin certificate", mspDir)
+		}
+	}
+
+	// before the orderer OU, orderers are identified as peers
+	orderer := readCert(t, filepath.Join(orgDir, "orderers/orderer.example.com/msp/signcerts/orderer.example.com-cert.pem"))
+	if !reflect.DeepEqual(orderer.Subject.OrganizationalUnit, []string{PeerOU}) {
+		t.Errorf("orderer OU = %v, want %s", orderer.Subject.OrganizationalUnit, PeerOU)
+	}
+}
+
+func TestGenerator_Generate_keyNames(t *testing.T) {
+	tests := []struct {
+		fabric  string
+		skiKeys bool
+	}{
+		{"1.4.4", true},
+		{"2.0.0", false},
+		{"2.2.0", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fabric, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "hlf-msp")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+
+			options, err := OptionsFromSpec(&spec.Network{Fabric: tt.fabric, Crypto: spec.Crypto{CAValidity: "1h", Validity: "1h"}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if options.SKIKeyNames != tt.skiKeys {
+				t.Fatalf("SKIKeyNames = %v, want %v", options.SKIKeyNames, tt.skiKeys)
+			}
+
+			// keys are named like cryptogen names them, once rotated the
+			// keystore only holds the new key
+			g := Generator{Dir: dir, Options: options}
+			org := Org{Domain: "org1.example.com", Nodes: []string{"peer0"}}
+			for _, rotate := range []bool{false, true} {
+				if _, err := g.Generate(org, rotate); err != nil {
+					t.Fatal(err)
+				}
+
+				orgDir := filepath.Join(dir, "peerOrganizations", "org1.example.com")
+				for _, keyDir := range []struct{ dir, cert string }{
+					{"ca", "ca/ca.org1.example.com-cert.pem"},
+					{"tlsca", "tlsca/tlsca.org1.example.com-cert.pem"},
+					{"peers/peer0.org1.example.com/msp/keystore", "peers/peer0.org1.example.com/msp/signcerts/peer0.org1.example.com-cert.pem"},
+				} {
+					want := "priv_sk"
+					if tt.skiKeys {
+						want = hex.EncodeToString(readCert(t, filepath.Join(orgDir, keyDir.cert)).SubjectKeyId) + "_sk"
+					}
+
+					keys, err := filepath.Glob(filepath.Join(orgDir, keyDir.dir, "*_sk"))
+					if err != nil {
+						t.Fatal(err)
+					}
+					if len(keys) != 1 || filepath.Base(keys[0]) != want {
+						t.Errorf("%s keys = %v, want %s", keyDir.dir, keys, want)
+					}
+				}
+			}
+		})
+	}
+}
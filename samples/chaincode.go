@@ -0,0 +1,260 @@
+package samples
+
+// chaincodeExample02 is chaincode_example02 of fabric-samples: two accounts
+// and transfers between them, on the shim of fabric 1.x.
+const chaincodeExample02 = `package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// SimpleChaincode keeps the balances of two accounts.
+type SimpleChaincode struct {
+}
+
+// Init creates the accounts: init A Aval B Bval
+func (t *SimpleChaincode) Init(stub shim.ChaincodeStubInterface) pb.Response {
+	_, args := stub.GetFunctionAndParameters()
+	if len(args) != 4 {
+		return shim.Error("Incorrect number of arguments. Expecting 4")
+	}
+
+	for i := 0; i < 4; i += 2 {
+		if _, err := strconv.Atoi(args[i+1]); err != nil {
+			return shim.Error("Expecting integer value for asset holding")
+		}
+		if err := stub.PutState(args[i], []byte(args[i+1])); err != nil {
+			return shim.Error(err.Error())
+		}
+	}
+	return shim.Success(nil)
+}
+
+// Invoke runs invoke, delete or query.
+func (t *SimpleChaincode) Invoke(stub shim.ChaincodeStubInterface) pb.Response {
+	function, args := stub.GetFunctionAndParameters()
+	switch function {
+	case "invoke":
+		return t.invoke(stub, args)
+	case "delete":
+		return t.delete(stub, args)
+	case "query":
+		return t.query(stub, args)
+	}
+	return shim.Error("Invalid invoke function name. Expecting \"invoke\" \"delete\" \"query\"")
+}
+
+// invoke moves X units from A to B: invoke A B X
+func (t *SimpleChaincode) invoke(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 3 {
+		return shim.Error("Incorrect number of arguments. Expecting 3")
+	}
+
+	a, b := args[0], args[1]
+	aval, err := t.balance(stub, a)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	bval, err := t.balance(stub, b)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	x, err := strconv.Atoi(args[2])
+	if err != nil {
+		return shim.Error("Invalid transaction amount, expecting a integer value")
+	}
+
+	aval, bval = aval-x, bval+x
+	fmt.Printf("Aval = %d, Bval = %d\n", aval, bval)
+
+	if err := stub.PutState(a, []byte(strconv.Itoa(aval))); err != nil {
+		return shim.Error(err.Error())
+	}
+	if err := stub.PutState(b, []byte(strconv.Itoa(bval))); err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(nil)
+}
+
+// delete removes an account: delete A
+func (t *SimpleChaincode) delete(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting 1")
+	}
+
+	if err := stub.DelState(args[0]); err != nil {
+		return shim.Error("Failed to delete state")
+	}
+	return shim.Success(nil)
+}
+
+// query returns the balance of an account: query A
+func (t *SimpleChaincode) query(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 1 {
+		return shim.Error("Incorrect number of arguments. Expecting name of the person to query")
+	}
+
+	value, err := stub.GetState(args[0])
+	if err != nil {
+		return shim.Error("{\"Error\":\"Failed to get state for " + args[0] + "\"}")
+	}
+	if value == nil {
+		return shim.Error("{\"Error\":\"Nil amount for " + args[0] + "\"}")
+	}
+	return shim.Success(value)
+}
+
+func (t *SimpleChaincode) balance(stub shim.ChaincodeStubInterface, account string) (int, error) {
+	value, err := stub.GetState(account)
+	if err != nil {
+		return 0, fmt.Errorf("Failed to get state")
+	}
+	if value == nil {
+		return 0, fmt.Errorf("Entity not found")
+	}
+	return strconv.Atoi(string(value))
+}
+
+func main() {
+	if err := shim.Start(new(SimpleChaincode)); err != nil {
+		fmt.Printf("Error starting Simple chaincode: %s", err)
+	}
+}
+`
+
+// assetTransferPackage is the package.json of the javascript
+// asset-transfer-basic chaincode of fabric-samples.
+const assetTransferPackage = `{
+  "name": "asset-transfer-basic",
+  "version": "1.0.0",
+  "description": "Asset transfer basic contract implemented in JavaScript",
+  "main": "index.js",
+  "engines": {
+    "node": ">=12",
+    "npm": ">=5"
+  },
+  "scripts": {
+    "start": "fabric-chaincode-node start"
+  },
+  "license": "Apache-2.0",
+  "dependencies": {
+    "fabric-contract-api": "^2.0.0",
+    "fabric-shim": "^2.0.0"
+  }
+}
+`
+
+const assetTransferIndex = `'use strict';
+
+const assetTransfer = require('./lib/assetTransfer');
+
+module.exports.AssetTransfer = assetTransfer;
+module.exports.contracts = [assetTransfer];
+`
+
+// assetTransferContract is the contract of asset-transfer-basic: assets with
+// an owner, created by InitLedger, that are read, updated and transferred.
+const assetTransferContract = `'use strict';
+
+const { Contract } = require('fabric-contract-api');
+
+class AssetTransfer extends Contract {
+
+    async InitLedger(ctx) {
+        const assets = [
+            { ID: 'asset1', Color: 'blue', Size: 5, Owner: 'Tomoko', AppraisedValue: 300 },
+            { ID: 'asset2', Color: 'red', Size: 5, Owner: 'Brad', AppraisedValue: 400 },
+            { ID: 'asset3', Color: 'green', Size: 10, Owner: 'Jin Soo', AppraisedValue: 500 },
+            { ID: 'asset4', Color: 'yellow', Size: 10, Owner: 'Max', AppraisedValue: 600 },
+            { ID: 'asset5', Color: 'black', Size: 15, Owner: 'Adriana', AppraisedValue: 700 },
+            { ID: 'asset6', Color: 'white', Size: 15, Owner: 'Michel', AppraisedValue: 800 },
+        ];
+
+        for (const asset of assets) {
+            asset.docType = 'asset';
+            await ctx.stub.putState(asset.ID, Buffer.from(JSON.stringify(asset)));
+        }
+    }
+
+    async CreateAsset(ctx, id, color, size, owner, appraisedValue) {
+        if (await this.AssetExists(ctx, id)) {
+            throw new Error('The asset ' + id + ' already exists');
+        }
+
+        const asset = {
+            ID: id,
+            Color: color,
+            Size: Number(size),
+            Owner: owner,
+            AppraisedValue: Number(appraisedValue),
+        };
+        await ctx.stub.putState(id, Buffer.from(JSON.stringify(asset)));
+        return JSON.stringify(asset);
+    }
+
+    async ReadAsset(ctx, id) {
+        const assetJSON = await ctx.stub.getState(id);
+        if (!assetJSON || assetJSON.length === 0) {
+            throw new Error('The asset ' + id + ' does not exist');
+        }
+        return assetJSON.toString();
+    }
+
+    async UpdateAsset(ctx, id, color, size, owner, appraisedValue) {
+        if (!await this.AssetExists(ctx, id)) {
+            throw new Error('The asset ' + id + ' does not exist');
+        }
+
+        const asset = {
+            ID: id,
+            Color: color,
+            Size: Number(size),
+            Owner: owner,
+            AppraisedValue: Number(appraisedValue),
+        };
+        return ctx.stub.putState(id, Buffer.from(JSON.stringify(asset)));
+    }
+
+    async DeleteAsset(ctx, id) {
+        if (!await this.AssetExists(ctx, id)) {
+            throw new Error('The asset ' + id + ' does not exist');
+        }
+        return ctx.stub.deleteState(id);
+    }
+
+    async AssetExists(ctx, id) {
+        const assetJSON = await ctx.stub.getState(id);
+        return assetJSON && assetJSON.length > 0;
+    }
+
+    async TransferAsset(ctx, id, newOwner) {
+        const asset = JSON.parse(await this.ReadAsset(ctx, id));
+        asset.Owner = newOwner;
+        return ctx.stub.putState(id, Buffer.from(JSON.stringify(asset)));
+    }
+
+    async GetAllAssets(ctx) {
+        const allResults = [];
+        const iterator = await ctx.stub.getStateByRange('', '');
+        let result = await iterator.next();
+        while (!result.done) {
+            const strValue = Buffer.from(result.value.value.toString()).toString('utf8');
+            let record;
+            try {
+                record = JSON.parse(strValue);
+            } catch (err) {
+                record = strValue;
+            }
+            allResults.push({ Key: result.value.key, Record: record });
+            result = await iterator.next();
+        }
+        return JSON.stringify(allResults);
+    }
+}
+
+module.exports = AssetTransfer;
+`
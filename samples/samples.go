@@ -0,0 +1,263 @@
+// Package samples holds the networks of fabric-samples that hlf brings up
+// without the samples repository or its scripts: the network spec of their
+// topology and the source of their chaincode.
+package samples
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/gangachris/hlf/semver"
+	"github.com/gangachris/hlf/spec"
+)
+
+// Sample is a fabric-samples network.
+type Sample struct {
+	Name        string
+	Description string
+
+	// MinFabric is the first fabric version the sample runs on, and MaxFabric
+	// the first version it does not run on, none when empty.
+	MinFabric string
+	MaxFabric string
+
+	// spec is the template of the network spec, executed with the fabric version.
+	spec string
+
+	// files are the chaincode files by path relative to the spec.
+	files map[string]string
+}
+
+var samples = []Sample{
+	{
+		Name:        "basic",
+		Description: "basic-network: 1 organization with 1 peer and a CA, solo orderer, couchdb, no TLS",
+		MaxFabric:   "2.0",
+		spec:        basicSpec,
+	},
+	{
+		Name:        "first-network",
+		Description: "byfn: 2 organizations with 2 peers, solo orderer, TLS, chaincode_example02 as mycc",
+		MaxFabric:   "2.0",
+		spec:        firstNetworkSpec,
+		files: map[string]string{
+			"chaincode/chaincode_example02/chaincode_example02.go": chaincodeExample02,
+		},
+	},
+	{
+		Name:        "test-network",
+		Description: "test-network: 2 organizations with 1 peer, etcdraft orderer, TLS, asset-transfer-basic as basic",
+		MinFabric:   "2.0",
+		spec:        testNetworkSpec,
+		files: map[string]string{
+			"chaincode/asset-transfer-basic/package.json":         assetTransferPackage,
+			"chaincode/asset-transfer-basic/index.js":             assetTransferIndex,
+			"chaincode/asset-transfer-basic/lib/assetTransfer.js": assetTransferContract,
+		},
+	},
+}
+
+// Names returns the names of the samples.
+func Names() []string {
+	var names []string
+	for _, sample := range samples {
+		names = append(names, sample.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Get returns a sample by name.
+func Get(name string) (Sample, error) {
+	for _, sample := range samples {
+		if sample.Name == name {
+			return sample, nil
+		}
+	}
+	return Sample{}, fmt.Errorf("error: unknown sample %s, expected one of %s", name, strings.Join(Names(), ", "))
+}
+
+// Supports returns an error when the sample does not run on a fabric version.
+func (s Sample) Supports(fabric string) error {
+	if s.MinFabric != "" {
+		ok, err := semver.CorrectVersion(s.MinFabric, fabric)
+		if err != nil {
+			return fmt.Errorf("error reading fabric version %s: %s", fabric, err.Error())
+		}
+		if !ok {
+			return fmt.Errorf("error: the %s sample needs fabric %s or later, the network uses %s", s.Name, s.MinFabric, fabric)
+		}
+	}
+
+	if s.MaxFabric != "" {
+		ok, err := semver.CorrectVersion(s.MaxFabric, fabric)
+		if err != nil {
+			return fmt.Errorf("error reading fabric version %s: %s", fabric, err.Error())
+		}
+		if ok {
+			return fmt.Errorf("error: the %s sample runs on fabric before %s, the network uses %s, use %s", s.Name, s.MaxFabric, fabric, s.successor())
+		}
+	}
+	return nil
+}
+
+// successor returns the samples that run on later fabric versions.
+func (s Sample) successor() string {
+	var names []string
+	for _, sample := range samples {
+		if sample.MinFabric != "" && sample.MinFabric == s.MaxFabric {
+			names = append(names, sample.Name)
+		}
+	}
+	return strings.Join(names, " or ")
+}
+
+// Spec returns the network spec of the sample for a fabric version.
+func (s Sample) Spec(fabric string) ([]byte, error) {
+	tmpl, err := template.New(spec.FileName).Parse(s.spec)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, struct {
+		Version int
+		Fabric  string
+	}{spec.Version, fabric})
+	return buf.Bytes(), err
+}
+
+// Write writes the network spec of the sample for a fabric version and its
+// chaincode to dir, and returns the path of the spec. Existing files are
+// overwritten, the artifacts generated next to them are kept.
+func (s Sample) Write(dir, fabric string) (string, error) {
+	if err := s.Supports(fabric); err != nil {
+		return "", err
+	}
+
+	data, err := s.Spec(fabric)
+	if err != nil {
+		return "", err
+	}
+
+	files := map[string]string{spec.FileName: string(data)}
+	for path, content := range s.files {
+		files[path] = content
+	}
+
+	for path, content := range files {
+		path = filepath.Join(dir, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return "", err
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			return "", fmt.Errorf("error writing the %s sample: %s", s.Name, err.Error())
+		}
+	}
+	return filepath.Join(dir, spec.FileName), nil
+}
+
+const basicSpec = `# The basic-network of fabric-samples, written by hlf network up --sample basic.
+version: {{ .Version }}
+name: basic
+fabric: "{{ .Fabric }}"
+domain: example.com
+tls:
+  enabled: false
+crypto:
+  generator: native
+portBase: 7050
+stateDatabase: couchdb
+
+orderer:
+  type: solo
+  organizations:
+    - name: Orderer
+      nodes: 1
+
+organizations:
+  - name: Org1
+    peers: 1
+    ca: true
+
+channels:
+  - name: mychannel
+`
+
+const firstNetworkSpec = `# The first-network of fabric-samples (byfn), written by hlf network up --sample first-network.
+version: {{ .Version }}
+name: first-network
+fabric: "{{ .Fabric }}"
+domain: example.com
+tls:
+  enabled: true
+crypto:
+  generator: native
+portBase: 7050
+stateDatabase: leveldb
+
+orderer:
+  type: solo
+  organizations:
+    - name: Orderer
+      nodes: 1
+
+organizations:
+  - name: Org1
+    peers: 2
+  - name: Org2
+    peers: 2
+
+channels:
+  - name: mychannel
+    organizations: [Org1, Org2]
+
+chaincodes:
+  - name: mycc
+    version: "1.0"
+    language: golang
+    path: ./chaincode/chaincode_example02
+    endorsementPolicy: "AND ('Org1MSP.peer','Org2MSP.peer')"
+    init: [init, a, "100", b, "200"]
+`
+
+const testNetworkSpec = `# The test-network of fabric-samples, written by hlf network up --sample test-network.
+version: {{ .Version }}
+name: test-network
+fabric: "{{ .Fabric }}"
+domain: example.com
+tls:
+  enabled: true
+crypto:
+  generator: native
+portBase: 7050
+stateDatabase: leveldb
+
+orderer:
+  type: etcdraft
+  organizations:
+    - name: Orderer
+      nodes: 1
+
+organizations:
+  - name: Org1
+    peers: 1
+  - name: Org2
+    peers: 1
+
+channels:
+  - name: mychannel
+    organizations: [Org1, Org2]
+
+chaincodes:
+  - name: basic
+    version: "1.0"
+    language: node
+    path: ./chaincode/asset-transfer-basic
+`
@@ -0,0 +1,96 @@
+package samples
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gangachris/hlf/spec"
+)
+
+func TestSample_Write(t *testing.T) {
+	tests := []struct {
+		sample string
+		fabric string
+		peers  int
+	}{
+		{"basic", "1.4.4", 1},
+		{"first-network", "1.1.0", 4},
+		{"test-network", "2.2.0", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.sample, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "hlf-sample")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+
+			sample, err := Get(tt.sample)
+			if err != nil {
+				t.Fatal(err)
+			}
+			file, err := sample.Write(dir, tt.fabric)
+			if err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+
+			network, err := spec.Load(file)
+			if err != nil {
+				t.Fatalf("the spec of %s is invalid: %v", tt.sample, err)
+			}
+			if network.Name != tt.sample || network.Fabric != tt.fabric {
+				t.Errorf("network %s fabric %s, want %s fabric %s", network.Name, network.Fabric, tt.sample, tt.fabric)
+			}
+
+			peers := 0
+			for _, org := range network.Organizations {
+				peers += org.Peers
+			}
+			if peers != tt.peers {
+				t.Errorf("%s has %d peers, want %d", tt.sample, peers, tt.peers)
+			}
+
+			// the chaincodes of the spec are written next to it
+			for _, chaincode := range network.Chaincodes {
+				if _, err := os.Stat(filepath.Join(dir, chaincode.Path)); err != nil {
+					t.Errorf("chaincode %s: %v", chaincode.Name, err)
+				}
+			}
+		})
+	}
+}
+
+func TestSample_Supports(t *testing.T) {
+	tests := []struct {
+		sample  string
+		fabric  string
+		wantErr string
+	}{
+		{"basic", "1.4.4", ""},
+		{"first-network", "2.2.0", "use test-network"},
+		{"test-network", "2.0.0", ""},
+		{"test-network", "1.4.4", "needs fabric 2.0 or later"},
+		{"test-network", "latest", "error reading fabric version"},
+	}
+	for _, tt := range tests {
+		sample, err := Get(tt.sample)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = sample.Supports(tt.fabric)
+		if tt.wantErr == "" && err != nil {
+			t.Errorf("%s Supports(%s) error = %v", tt.sample, tt.fabric, err)
+		}
+		if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("%s Supports(%s) error = %v, want %q", tt.sample, tt.fabric, err, tt.wantErr)
+		}
+	}
+
+	if _, err := Get("balance-transfer"); err == nil {
+		t.Errorf("Get(balance-transfer) error = nil, want unknown sample")
+	}
+}
@@ -15,6 +15,13 @@ var errInvalidSemver = errors.New("invalid semver")
 func getSemverFromString(semver string) (semanticVersion, error) {
 	smv := semanticVersion{}
 
+	// tools such as docker compose v2 report versions as v2.20.2, and
+	// pre-release or build metadata (2.20.2-desktop.1) is not compared.
+	semver = strings.TrimPrefix(strings.TrimSpace(semver), "v")
+	if i := strings.IndexAny(semver, "-+"); i != -1 {
+		semver = semver[:i]
+	}
+
 	splitSemver := strings.Split(semver, ".")
 	if len(splitSemver) == 0 {
 		return smv, errInvalidSemver
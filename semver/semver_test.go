@@ -48,6 +48,36 @@ func Test_getSemverFromString(t *testing.T) {
 				patch: 0,
 			},
 		},
+		{
+			name: "correct semver retrieved with v prefix",
+			args: args{
+				semver: "v2.20.2",
+			},
+			want: semanticVersion{
+				major: 2,
+				minor: 20,
+				patch: 2,
+			},
+		},
+		{
+			name: "correct semver retrieved with pre-release suffix",
+			args: args{
+				semver: "2.21.0-desktop.1",
+			},
+			want: semanticVersion{
+				major: 2,
+				minor: 21,
+				patch: 0,
+			},
+		},
+		{
+			name: "invalid semver",
+			args: args{
+				semver: "compose",
+			},
+			want:    semanticVersion{},
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
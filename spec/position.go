@@ -0,0 +1,189 @@
+package spec
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Position is a line and column in a network spec file, both start at 1.
+type Position struct {
+	Line   int
+	Column int
+}
+
+// Error is a problem found in a network spec file. Path is the field the problem
+// is about e.g organizations[1].peers, Position is 0:0 when it is unknown.
+type Error struct {
+	File string
+	Position
+	Path    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	var location string
+	switch {
+	case e.Line > 0 && e.Column > 0:
+		location = fmt.Sprintf("%s:%d:%d: ", e.File, e.Line, e.Column)
+	case e.Line > 0:
+		location = fmt.Sprintf("%s:%d: ", e.File, e.Line)
+	case e.File != "":
+		location = e.File + ": "
+	}
+
+	if e.Path == "" {
+		return location + e.Message
+	}
+	return location + e.Path + ": " + e.Message
+}
+
+// Errors are all the problems found in a network spec file, in file order.
+type Errors []*Error
+
+func (e Errors) Error() string {
+	var lines []string
+	for _, err := range e {
+		lines = append(lines, err.Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+var (
+	keyPattern  = regexp.MustCompile(`^("[^"]*"|'[^']*'|[^\s:#'"\[\]{},][^:#]*?)\s*:(\s|$)`)
+	linePattern = regexp.MustCompile(`^line (\d+): `)
+)
+
+// positions indexes the position of every key and sequence item of a block
+// style yaml document by path e.g orderer.type or channels[0].organizations[1].
+// Flow style collections are not indexed, their position is the one of their key.
+func positions(data []byte) map[string]Position {
+	type entry struct {
+		indent int
+		path   string
+		item   bool
+	}
+
+	index := map[string]Position{}
+	items := map[string]int{}
+	var stack []entry
+
+	// lines of block scalars, indented deeper than their key, are skipped
+	block := -1
+
+	pop := func(indent int, item bool) {
+		for len(stack) > 0 {
+			top := stack[len(stack)-1]
+			// a sequence at the indentation of its key is a child of the key
+			if top.indent < indent || (item && top.indent == indent && !top.item) {
+				return
+			}
+			stack = stack[:len(stack)-1]
+		}
+	}
+
+	parent := func() string {
+		if len(stack) == 0 {
+			return ""
+		}
+		return stack[len(stack)-1].path
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		trimmed := strings.TrimLeft(text, " ")
+		indent := len(text) - len(trimmed)
+		if trimmed == "" || (block != -1 && indent > block) {
+			continue
+		}
+		block = -1
+		if strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, "---") {
+			continue
+		}
+
+		for {
+			if trimmed == "-" || strings.HasPrefix(trimmed, "- ") {
+				pop(indent, true)
+				p := parent()
+				path := fmt.Sprintf("%s[%d]", p, items[p])
+				items[p]++
+				index[path] = Position{Line: line, Column: indent + 1}
+				stack = append(stack, entry{indent: indent, path: path, item: true})
+
+				rest := strings.TrimLeft(trimmed[1:], " ")
+				if rest == "" {
+					break
+				}
+				indent += len(trimmed) - len(rest)
+				trimmed = rest
+				continue
+			}
+
+			if match := keyPattern.FindStringSubmatch(trimmed); match != nil {
+				pop(indent, false)
+				key := strings.Trim(strings.TrimSpace(match[1]), `"'`)
+				path := key
+				if p := parent(); p != "" {
+					path = p + "." + key
+				}
+				delete(items, path)
+				index[path] = Position{Line: line, Column: indent + 1}
+				stack = append(stack, entry{indent: indent, path: path})
+
+				if value := strings.TrimSpace(trimmed[len(match[0]):]); strings.HasPrefix(value, "|") || strings.HasPrefix(value, ">") {
+					block = indent
+				}
+			}
+			break
+		}
+	}
+
+	return index
+}
+
+// lookup returns the position of a path, or of its closest indexed parent.
+func lookup(index map[string]Position, path string) Position {
+	for path != "" {
+		if position, ok := index[path]; ok {
+			return position
+		}
+
+		i := strings.LastIndexAny(path, ".[")
+		if i == -1 {
+			break
+		}
+		path = path[:i]
+	}
+	return Position{}
+}
+
+// yamlErrors converts the errors of the yaml decoder, which look like
+// "yaml: line 3: ..." or "line 3: field x not found in type spec.Network", to
+// errors with the column of the first non blank character of the line.
+func yamlErrors(file string, data []byte, err error) Errors {
+	lines := strings.Split(string(data), "\n")
+
+	var errs Errors
+	for _, message := range strings.Split(err.Error(), "\n") {
+		message = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(message), "yaml:"))
+		if message == "" || strings.HasPrefix(message, "unmarshal errors") {
+			continue
+		}
+
+		e := &Error{File: file, Message: message}
+		if match := linePattern.FindStringSubmatch(message); match != nil {
+			e.Line, _ = strconv.Atoi(match[1])
+			e.Message = strings.TrimPrefix(message, match[0])
+			if e.Line <= len(lines) {
+				text := lines[e.Line-1]
+				e.Column = len(text) - len(strings.TrimLeft(text, " ")) + 1
+			}
+		}
+		errs = append(errs, e)
+	}
+	return errs
+}
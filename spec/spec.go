@@ -0,0 +1,515 @@
+// Package spec defines hlf.yaml, the declarative specification of a fabric
+// network: its organizations, orderers, channels and chaincodes. Network
+// commands read everything they need about a network from this one file.
+package spec
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+const (
+	// Version is the version of the network spec schema.
+	Version = 1
+
+	// FileName is the name network commands look for in the working directory.
+	FileName = "hlf.yaml"
+)
+
+// orderer types
+const (
+	OrdererSolo     = "solo"
+	OrdererKafka    = "kafka"
+	OrdererEtcdRaft = "etcdraft"
+)
+
+// state databases
+const (
+	LevelDB = "leveldb"
+	CouchDB = "couchdb"
+)
+
+// chaincode languages
+const (
+	Golang = "golang"
+	Node   = "node"
+	Java   = "java"
+)
+
+// crypto material generators
+const (
+	GeneratorCryptogen = "cryptogen"
+	GeneratorNative    = "native"
+)
+
+// ports the nodes listen on in their containers, the ports exposed on the
+// host are allocated from the port base
+const (
+	OrdererPort   = 7050
+	PeerPort      = 7051
+	KafkaPort     = 9092
+	ZookeeperPort = 2181
+)
+
+// defaults
+const (
+	DefaultDomain            = "example.com"
+	DefaultPortBase          = 7050
+	DefaultArtifacts         = "artifacts"
+	DefaultBatchTimeout      = "2s"
+	DefaultMaxMessages       = 10
+	DefaultOrdererName       = "Orderer"
+	DefaultKafkaBrokers      = 4
+	DefaultZookeepers        = 3
+	DefaultTickInterval      = "500ms"
+	DefaultElectionTick      = 10
+	DefaultHeartbeatTick     = 1
+	DefaultMaxInflightBlocks = 5
+	DefaultSnapshotInterval  = "16 MB"
+	DefaultChaincodeVersion  = "1.0"
+	DefaultChaincodeLanguage = Golang
+	DefaultCAValidity        = "87600h"
+	DefaultValidity          = "87600h"
+)
+
+// Network is the specification of a fabric network.
+type Network struct {
+	// Version is the version of the schema, see Version.
+	Version int `yaml:"version"`
+
+	// Name is the name of the hlf network, containers are labelled with it.
+	Name string `yaml:"name"`
+
+	// Fabric is the fabric version of the network, the configured fabric
+	// version when empty.
+	Fabric string `yaml:"fabric,omitempty"`
+
+	// configuredFabric is set when Fabric is the configured fabric version
+	// rather than the one of the spec.
+	configuredFabric bool
+
+	// Domain is the domain of the orderer organizations and the parent domain
+	// of the peer organizations that do not set one.
+	Domain string `yaml:"domain"`
+
+	// TLS configures TLS between the nodes and with clients.
+	TLS TLS `yaml:"tls"`
+
+	// Crypto configures how the crypto material is generated.
+	Crypto Crypto `yaml:"crypto"`
+
+	// PortBase is the first host port exposed for the nodes.
+	PortBase int `yaml:"portBase"`
+
+	// StateDatabase is the state database of the peers of organizations that do not set one.
+	StateDatabase string `yaml:"stateDatabase"`
+
+	// Artifacts is the directory crypto material, channel artifacts and compose
+	// files are generated in, relative to the spec file.
+	Artifacts string `yaml:"artifacts"`
+
+	Orderer       Orderer        `yaml:"orderer"`
+	Organizations []Organization `yaml:"organizations"`
+	Channels      []Channel      `yaml:"channels"`
+	Chaincodes    []Chaincode    `yaml:"chaincodes,omitempty"`
+
+	// Dir is the directory of the spec file.
+	Dir string `yaml:"-"`
+}
+
+// TLS configures TLS between the nodes and with clients.
+type TLS struct {
+	Enabled bool `yaml:"enabled"`
+
+	// ClientAuth requires clients to present a certificate, mutual TLS.
+	ClientAuth bool `yaml:"clientAuth"`
+}
+
+// Crypto configures how the crypto material is generated.
+type Crypto struct {
+	// Generator is cryptogen, the fabric tool, or native to generate the
+	// material with hlf, which does not need the fabric binaries and can add
+	// nodes and rotate certificates while keeping the CAs.
+	Generator string `yaml:"generator"`
+
+	// CAValidity is how long the CA certificates are valid e.g 87600h, native only.
+	CAValidity string `yaml:"caValidity"`
+
+	// Validity is how long node and user certificates are valid, native only.
+	Validity string `yaml:"validity"`
+
+	// Subject are the subject fields of the certificates besides the names.
+	Subject Subject `yaml:"subject,omitempty"`
+
+	// SANs are extra subject alternative names of the TLS certificates of every
+	// node, host names or IP addresses.
+	SANs []string `yaml:"sans,omitempty"`
+}
+
+// Subject are the subject fields of the certificates.
+type Subject struct {
+	Country            string `yaml:"country,omitempty"`
+	Province           string `yaml:"province,omitempty"`
+	Locality           string `yaml:"locality,omitempty"`
+	OrganizationalUnit string `yaml:"organizationalUnit,omitempty"`
+}
+
+// Orderer is the ordering service of a network.
+type Orderer struct {
+	// Type is the consensus type: solo, kafka or etcdraft.
+	Type string `yaml:"type"`
+
+	// BatchTimeout is the time to wait before creating a batch e.g 2s.
+	BatchTimeout string `yaml:"batchTimeout"`
+
+	// MaxMessageCount is the maximum number of transactions in a block.
+	MaxMessageCount int `yaml:"maxMessageCount"`
+
+	// Organizations are the orderer organizations and their nodes.
+	Organizations []OrdererOrganization `yaml:"organizations"`
+
+	// Kafka configures the kafka cluster of the kafka orderer type.
+	Kafka Kafka `yaml:"kafka,omitempty"`
+
+	// EtcdRaft configures the raft consensus of the etcdraft orderer type,
+	// every orderer node of every orderer organization is a consenter.
+	EtcdRaft EtcdRaft `yaml:"etcdRaft,omitempty"`
+}
+
+// OrdererOrganization is an organization running orderer nodes.
+type OrdererOrganization struct {
+	Name   string `yaml:"name"`
+	MSPID  string `yaml:"mspID"`
+	Domain string `yaml:"domain"`
+
+	// Nodes is the number of orderer nodes of the organization.
+	Nodes int `yaml:"nodes"`
+}
+
+// Kafka configures the kafka cluster of the kafka orderer type.
+type Kafka struct {
+	Brokers    int `yaml:"brokers"`
+	Zookeepers int `yaml:"zookeepers"`
+}
+
+// EtcdRaft configures the raft consensus of the etcdraft orderer type.
+type EtcdRaft struct {
+	// TickInterval is the time between two raft ticks e.g 500ms.
+	TickInterval string `yaml:"tickInterval"`
+
+	// ElectionTick is the number of ticks without a heartbeat from the leader
+	// after which a follower starts an election.
+	ElectionTick int `yaml:"electionTick"`
+
+	// HeartbeatTick is the number of ticks between two heartbeats of the leader.
+	HeartbeatTick int `yaml:"heartbeatTick"`
+
+	// MaxInflightBlocks is the number of blocks replicated to a follower
+	// before it acknowledges them.
+	MaxInflightBlocks int `yaml:"maxInflightBlocks"`
+
+	// SnapshotIntervalSize is how much data is written between two snapshots
+	// of the write ahead log e.g 16 MB.
+	SnapshotIntervalSize string `yaml:"snapshotIntervalSize"`
+}
+
+// Organization is a peer organization.
+type Organization struct {
+	Name   string `yaml:"name"`
+	MSPID  string `yaml:"mspID"`
+	Domain string `yaml:"domain"`
+
+	// Peers is the number of peers of the organization.
+	Peers int `yaml:"peers"`
+
+	// Users is the number of users besides the admin.
+	Users int `yaml:"users"`
+
+	// CA runs a fabric-ca for the organization.
+	CA bool `yaml:"ca"`
+
+	// StateDatabase is leveldb or couchdb, the network one when empty.
+	StateDatabase string `yaml:"stateDatabase"`
+
+	// AnchorPeers are the indexes of the anchor peers, peer0 when empty.
+	AnchorPeers []int `yaml:"anchorPeers"`
+}
+
+// Channel is an application channel.
+type Channel struct {
+	Name string `yaml:"name"`
+
+	// Organizations are the names of the member organizations.
+	Organizations []string `yaml:"organizations"`
+}
+
+// Chaincode is a chaincode deployed on channels of the network.
+type Chaincode struct {
+	Name    string `yaml:"name"`
+	Version string `yaml:"version"`
+
+	// Language is golang, node or java.
+	Language string `yaml:"language"`
+
+	// Path is the directory of the chaincode source, relative to the spec file.
+	Path string `yaml:"path"`
+
+	// Channels are the channels the chaincode is deployed on, all when empty.
+	Channels []string `yaml:"channels"`
+
+	// EndorsementPolicy is the endorsement policy e.g "OR('Org1MSP.peer')",
+	// any member of the channel when empty.
+	EndorsementPolicy string `yaml:"endorsementPolicy,omitempty"`
+
+	// Init are the arguments of the init function e.g [init, a, "100"], the
+	// chaincode is not initialized when empty.
+	Init []string `yaml:"init,omitempty"`
+}
+
+// Load reads and validates a network spec file.
+func Load(path string) (*Network, error) {
+	return LoadWithFabric(path, "")
+}
+
+// LoadWithFabric is Load for networks that run the fabric version fabric
+// unless the spec sets one, the spec is validated against that version.
+func LoadWithFabric(path, fabric string) (*Network, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading network spec: %s", err.Error())
+	}
+
+	network, err := ParseWithFabric(filepath.Base(path), data, fabric)
+	if err != nil {
+		return nil, err
+	}
+
+	if network.Dir, err = filepath.Abs(filepath.Dir(path)); err != nil {
+		return nil, err
+	}
+	return network, nil
+}
+
+// Parse decodes and validates a network spec, file is the name errors refer to.
+// Defaults are set for everything that is not set. The error is Errors when
+// the spec is invalid.
+func Parse(file string, data []byte) (*Network, error) {
+	return ParseWithFabric(file, data, "")
+}
+
+// ParseWithFabric is Parse for networks that run the fabric version fabric
+// unless the spec sets one, the spec is validated against that version.
+func ParseWithFabric(file string, data []byte, fabric string) (*Network, error) {
+	network := &Network{
+		TLS: TLS{Enabled: true},
+		Crypto: Crypto{
+			Generator:  GeneratorCryptogen,
+			CAValidity: DefaultCAValidity,
+			Validity:   DefaultValidity,
+		},
+		Domain:        DefaultDomain,
+		PortBase:      DefaultPortBase,
+		StateDatabase: LevelDB,
+		Artifacts:     DefaultArtifacts,
+		Orderer: Orderer{
+			Type:            OrdererSolo,
+			BatchTimeout:    DefaultBatchTimeout,
+			MaxMessageCount: DefaultMaxMessages,
+		},
+	}
+
+	if err := yaml.UnmarshalStrict(data, network); err != nil {
+		return nil, yamlErrors(file, data, err)
+	}
+
+	if network.Fabric == "" && fabric != "" {
+		network.Fabric = fabric
+		network.configuredFabric = true
+	}
+	network.setDefaults()
+
+	if errs := network.validate(); len(errs) > 0 {
+		index := positions(data)
+		for _, err := range errs {
+			err.File = file
+			err.Position = lookup(index, err.Path)
+		}
+		return nil, errs.sorted()
+	}
+	return network, nil
+}
+
+// setDefaults sets the defaults that depend on other fields.
+func (n *Network) setDefaults() {
+	if len(n.Orderer.Organizations) == 0 {
+		n.Orderer.Organizations = []OrdererOrganization{{}}
+	}
+	for i := range n.Orderer.Organizations {
+		org := &n.Orderer.Organizations[i]
+		if org.Name == "" && i == 0 {
+			org.Name = DefaultOrdererName
+		}
+		if org.MSPID == "" && org.Name != "" {
+			org.MSPID = org.Name + "MSP"
+		}
+		if org.Domain == "" && i == 0 {
+			org.Domain = n.Domain
+		}
+		if org.Domain == "" && org.Name != "" {
+			org.Domain = strings.ToLower(org.Name) + "." + n.Domain
+		}
+		if org.Nodes == 0 {
+			org.Nodes = 1
+		}
+	}
+
+	if n.Orderer.Type == OrdererKafka {
+		if n.Orderer.Kafka.Brokers == 0 {
+			n.Orderer.Kafka.Brokers = DefaultKafkaBrokers
+		}
+		if n.Orderer.Kafka.Zookeepers == 0 {
+			n.Orderer.Kafka.Zookeepers = DefaultZookeepers
+		}
+	}
+
+	if n.Orderer.Type == OrdererEtcdRaft {
+		raft := &n.Orderer.EtcdRaft
+		if raft.TickInterval == "" {
+			raft.TickInterval = DefaultTickInterval
+		}
+		if raft.ElectionTick == 0 {
+			raft.ElectionTick = DefaultElectionTick
+		}
+		if raft.HeartbeatTick == 0 {
+			raft.HeartbeatTick = DefaultHeartbeatTick
+		}
+		if raft.MaxInflightBlocks == 0 {
+			raft.MaxInflightBlocks = DefaultMaxInflightBlocks
+		}
+		if raft.SnapshotIntervalSize == "" {
+			raft.SnapshotIntervalSize = DefaultSnapshotInterval
+		}
+	}
+
+	for i := range n.Organizations {
+		org := &n.Organizations[i]
+		if org.MSPID == "" && org.Name != "" {
+			org.MSPID = org.Name + "MSP"
+		}
+		if org.Domain == "" && org.Name != "" {
+			org.Domain = strings.ToLower(org.Name) + "." + n.Domain
+		}
+		if org.Peers == 0 {
+			org.Peers = 1
+		}
+		if org.StateDatabase == "" {
+			org.StateDatabase = n.StateDatabase
+		}
+		if len(org.AnchorPeers) == 0 {
+			org.AnchorPeers = []int{0}
+		}
+	}
+
+	for i := range n.Channels {
+		channel := &n.Channels[i]
+		if len(channel.Organizations) == 0 {
+			for _, org := range n.Organizations {
+				channel.Organizations = append(channel.Organizations, org.Name)
+			}
+		}
+	}
+
+	for i := range n.Chaincodes {
+		chaincode := &n.Chaincodes[i]
+		if chaincode.Version == "" {
+			chaincode.Version = DefaultChaincodeVersion
+		}
+		if chaincode.Language == "" {
+			chaincode.Language = DefaultChaincodeLanguage
+		}
+		if len(chaincode.Channels) == 0 {
+			for _, channel := range n.Channels {
+				chaincode.Channels = append(chaincode.Channels, channel.Name)
+			}
+		}
+	}
+}
+
+// ArtifactsDir returns the absolute directory artifacts are generated in.
+func (n *Network) ArtifactsDir() string {
+	if filepath.IsAbs(n.Artifacts) {
+		return n.Artifacts
+	}
+	return filepath.Join(n.Dir, n.Artifacts)
+}
+
+// Organization returns a peer organization by name.
+func (n *Network) Organization(name string) (*Organization, bool) {
+	for i := range n.Organizations {
+		if n.Organizations[i].Name == name {
+			return &n.Organizations[i], true
+		}
+	}
+	return nil, false
+}
+
+// OrdererNodes returns the orderer nodes of every orderer organization.
+func (n *Network) OrdererNodes() []OrdererNode {
+	var nodes []OrdererNode
+	for i := range n.Orderer.Organizations {
+		org := &n.Orderer.Organizations[i]
+		for j := 0; j < org.Nodes; j++ {
+			nodes = append(nodes, OrdererNode{Organization: org, Index: j})
+		}
+	}
+	return nodes
+}
+
+// OrdererNode is an orderer node of an orderer organization.
+type OrdererNode struct {
+	Organization *OrdererOrganization
+	Index        int
+}
+
+// Name returns the host name of the node in its organization: orderer for the
+// only node of an organization, orderer0, orderer1... otherwise.
+func (o OrdererNode) Name() string {
+	if o.Organization.Nodes == 1 {
+		return "orderer"
+	}
+	return fmt.Sprintf("orderer%d", o.Index)
+}
+
+// Host returns the fully qualified host name of the node e.g orderer.example.com.
+func (o OrdererNode) Host() string {
+	return o.Name() + "." + o.Organization.Domain
+}
+
+// KafkaHost returns the host name of a kafka broker e.g kafka0.
+func KafkaHost(i int) string {
+	return fmt.Sprintf("kafka%d", i)
+}
+
+// ZookeeperHost returns the host name of a zookeeper node e.g zookeeper0.
+func ZookeeperHost(i int) string {
+	return fmt.Sprintf("zookeeper%d", i)
+}
+
+// PeerName returns the host name of a peer in its organization e.g peer0.
+func (o Organization) PeerName(i int) string {
+	return fmt.Sprintf("peer%d", i)
+}
+
+// PeerHost returns the fully qualified host name of a peer e.g peer0.org1.example.com.
+func (o Organization) PeerHost(i int) string {
+	return o.PeerName(i) + "." + o.Domain
+}
+
+// Marshal encodes a network spec to yaml.
+func Marshal(n *Network) ([]byte, error) {
+	return yaml.Marshal(n)
+}
@@ -0,0 +1,312 @@
+package spec
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const twoOrgs = `version: 1
+name: dev
+organizations:
+  - name: Org1
+    peers: 2
+    ca: true
+  - name: Org2
+    stateDatabase: couchdb
+channels:
+  - name: mychannel
+chaincodes:
+  - name: mycc
+    path: ./chaincode/mycc
+`
+
+func TestParse_defaults(t *testing.T) {
+	network, err := Parse(FileName, []byte(twoOrgs))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &Network{
+		Version:       1,
+		Name:          "dev",
+		Domain:        "example.com",
+		TLS:           TLS{Enabled: true},
+		Crypto:        Crypto{Generator: GeneratorCryptogen, CAValidity: "87600h", Validity: "87600h"},
+		PortBase:      7050,
+		StateDatabase: LevelDB,
+		Artifacts:     "artifacts",
+		Orderer: Orderer{
+			Type:            OrdererSolo,
+			BatchTimeout:    "2s",
+			MaxMessageCount: 10,
+			Organizations: []OrdererOrganization{
+				{Name: "Orderer", MSPID: "OrdererMSP", Domain: "example.com", Nodes: 1},
+			},
+		},
+		Organizations: []Organization{
+			{Name: "Org1", MSPID: "Org1MSP", Domain: "org1.example.com", Peers: 2, CA: true, StateDatabase: LevelDB, AnchorPeers: []int{0}},
+			{Name: "Org2", MSPID: "Org2MSP", Domain: "org2.example.com", Peers: 1, StateDatabase: CouchDB, AnchorPeers: []int{0}},
+		},
+		Channels: []Channel{
+			{Name: "mychannel", Organizations: []string{"Org1", "Org2"}},
+		},
+		Chaincodes: []Chaincode{
+			{Name: "mycc", Version: "1.0", Language: Golang, Path: "./chaincode/mycc", Channels: []string{"mychannel"}},
+		},
+	}
+	if !reflect.DeepEqual(network, want) {
+		t.Errorf("Parse() = %+v, want %+v", network, want)
+	}
+
+	if got := network.Organizations[0].PeerHost(1); got != "peer1.org1.example.com" {
+		t.Errorf("PeerHost() = %s, want peer1.org1.example.com", got)
+	}
+	if nodes := network.OrdererNodes(); len(nodes) != 1 || nodes[0].Host() != "orderer.example.com" {
+		t.Errorf("OrdererNodes() = %+v, want orderer.example.com", nodes)
+	}
+}
+
+func TestParse_etcdRaft(t *testing.T) {
+	network, err := Parse(FileName, []byte(`version: 1
+name: dev
+orderer:
+  type: etcdraft
+  etcdRaft:
+    snapshotIntervalSize: 32 MB
+  organizations:
+    - name: Orderer
+      nodes: 2
+    - name: Orderer2
+organizations:
+  - name: Org1
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := EtcdRaft{TickInterval: "500ms", ElectionTick: 10, HeartbeatTick: 1, MaxInflightBlocks: 5, SnapshotIntervalSize: "32 MB"}
+	if network.Orderer.EtcdRaft != want {
+		t.Errorf("EtcdRaft = %+v, want %+v", network.Orderer.EtcdRaft, want)
+	}
+
+	// the consenters of every orderer organization
+	var hosts []string
+	for _, node := range network.OrdererNodes() {
+		hosts = append(hosts, node.Host())
+	}
+	if want := []string{"orderer0.example.com", "orderer1.example.com", "orderer.orderer2.example.com"}; !reflect.DeepEqual(hosts, want) {
+		t.Errorf("OrdererNodes() = %v, want %v", hosts, want)
+	}
+}
+
+func TestParseWithFabric(t *testing.T) {
+	raft := "version: 1\nname: dev\norderer:\n  type: etcdraft\norganizations:\n  - name: Org1\n"
+
+	network, err := ParseWithFabric(FileName, []byte(raft), "1.4.4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if network.Fabric != "1.4.4" {
+		t.Errorf("Fabric = %s, want 1.4.4", network.Fabric)
+	}
+
+	// the configured version is validated like the one of the spec
+	_, err = ParseWithFabric(FileName, []byte(raft), "1.2.0")
+	want := "hlf.yaml:4:3: orderer.type: the etcdraft orderer needs fabric 1.4.1 or later, the network uses the configured fabric version 1.2.0, set fabric in the spec"
+	if err == nil || err.Error() != want {
+		t.Errorf("ParseWithFabric() error = %v, want %s", err, want)
+	}
+
+	// the version of the spec wins
+	network, err = ParseWithFabric(FileName, []byte("fabric: 2.2.0\n"+raft), "1.2.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if network.Fabric != "2.2.0" {
+		t.Errorf("Fabric = %s, want 2.2.0", network.Fabric)
+	}
+}
+
+func TestParse_errors(t *testing.T) {
+	tests := []struct {
+		name string
+		spec string
+		want []string
+	}{
+		{
+			name: "yaml syntax",
+			spec: "version: 1\nname: [dev\n",
+			want: []string{"hlf.yaml:2:1: did not find expected ',' or ']'"},
+		},
+		{
+			name: "unknown field",
+			spec: "version: 1\nname: dev\norganizations:\n  - name: Org1\n    peer: 2\n",
+			want: []string{"hlf.yaml:5:5: field peer not found in type spec.Organization"},
+		},
+		{
+			name: "wrong type",
+			spec: "version: 1\nname: dev\nportBase: high\norganizations:\n  - name: Org1\n",
+			want: []string{"hlf.yaml:3:1: cannot unmarshal !!str `high` into int"},
+		},
+		{
+			name: "missing version and organizations",
+			spec: "name: dev\n",
+			want: []string{
+				"hlf.yaml: version: version is required, the current version is 1",
+				"hlf.yaml: organizations: at least one peer organization is required",
+			},
+		},
+		{
+			name: "invalid fields",
+			spec: `version: 1
+name: Dev
+orderer:
+  type: raft
+  batchTimeout: soon
+organizations:
+  - name: Org1
+    peers: -1
+  - name: Org1
+    stateDatabase: mongodb
+    anchorPeers: [1]
+channels:
+  - name: mychannel
+    organizations:
+      - Org1
+      - Org3
+chaincodes:
+  - name: mycc
+    language: rust
+    channels: [other]
+`,
+			want: []string{
+				"hlf.yaml:2:1: name: invalid name Dev: expected lowercase letters, digits, '_' or '-'",
+				"hlf.yaml:4:3: orderer.type: invalid orderer type raft: expected solo, kafka or etcdraft",
+				"hlf.yaml:5:3: orderer.batchTimeout: invalid batch timeout soon: expected a duration such as 2s",
+				"hlf.yaml:8:5: organizations[0].peers: invalid number of peers -1: expected a number greater than 0",
+				"hlf.yaml:9:5: organizations[1].name: duplicate organization Org1, already used by organizations[0].name",
+				"hlf.yaml:9:3: organizations[1].mspID: duplicate MSP ID Org1MSP, already used by organizations[0].mspID",
+				"hlf.yaml:9:3: organizations[1].domain: duplicate domain org1.example.com, already used by organizations[0].domain",
+				"hlf.yaml:10:5: organizations[1].stateDatabase: invalid state database mongodb: expected leveldb or couchdb",
+				"hlf.yaml:11:5: organizations[1].anchorPeers[0]: invalid anchor peer 1: the organization has peers 0 to 0",
+				"hlf.yaml:16:7: channels[0].organizations[1]: unknown organization Org3, expected one of Org1, Org1",
+				"hlf.yaml:18:3: chaincodes[0].path: path is required",
+				"hlf.yaml:19:5: chaincodes[0].language: invalid language rust: expected golang, node or java",
+				"hlf.yaml:20:5: chaincodes[0].channels[0]: unknown channel other",
+			},
+		},
+		{
+			name: "invalid crypto",
+			spec: "version: 1\nname: dev\ncrypto:\n  generator: openssl\n  validity: 1y\n  sans: [peer.example.com, 10.0.0.1, Peer_1]\norganizations:\n  - name: Org1\n",
+			want: []string{
+				"hlf.yaml:4:3: crypto.generator: invalid generator openssl: expected cryptogen or native",
+				"hlf.yaml:5:3: crypto.validity: invalid validity 1y: expected a duration such as 8760h",
+				"hlf.yaml:6:3: crypto.sans[2]: invalid SAN Peer_1: expected a lowercase host name or an IP address",
+			},
+		},
+		{
+			name: "solo with several nodes",
+			spec: "version: 1\nname: dev\norderer:\n  organizations:\n    - name: Orderer\n      nodes: 3\norganizations:\n  - name: Org1\n",
+			want: []string{"hlf.yaml:3:1: orderer.type: the solo orderer has a single node, 3 are declared"},
+		},
+		{
+			name: "invalid etcdraft",
+			spec: `version: 1
+name: dev
+fabric: 1.4.0
+tls:
+  enabled: false
+orderer:
+  type: etcdraft
+  etcdRaft:
+    tickInterval: fast
+    electionTick: 1
+    snapshotIntervalSize: lots
+organizations:
+  - name: Org1
+`,
+			want: []string{
+				"hlf.yaml:7:3: orderer.type: the etcdraft orderer needs TLS, set tls.enabled",
+				"hlf.yaml:7:3: orderer.type: the etcdraft orderer needs fabric 1.4.1 or later, the network uses 1.4.0",
+				"hlf.yaml:9:5: orderer.etcdRaft.tickInterval: invalid tick interval fast: expected a duration such as 500ms",
+				"hlf.yaml:10:5: orderer.etcdRaft.electionTick: invalid election tick 1: expected a number greater than the heartbeat tick 1",
+				"hlf.yaml:11:5: orderer.etcdRaft.snapshotIntervalSize: invalid snapshot interval size lots: expected a size such as 16 MB",
+			},
+		},
+		{
+			name: "etcdraft options with solo",
+			spec: "version: 1\nname: dev\norderer:\n  etcdRaft:\n    electionTick: 20\norganizations:\n  - name: Org1\n",
+			want: []string{"hlf.yaml:4:3: orderer.etcdRaft: etcdRaft is only used by the etcdraft orderer type"},
+		},
+		{
+			name: "orderer and peer organizations with the same name",
+			spec: "version: 1\nname: dev\norderer:\n  organizations:\n    - name: Org1\n      mspID: OrdererMSP\n      domain: example.com\norganizations:\n  - name: Org1\n",
+			want: []string{"hlf.yaml:9:5: organizations[0].name: duplicate organization Org1, already used by orderer.organizations[0].name"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(FileName, []byte(tt.spec))
+			if err == nil {
+				t.Fatal("Parse() error = nil, want errors")
+			}
+
+			errs, ok := err.(Errors)
+			if !ok {
+				t.Fatalf("Parse() error = %T, want Errors", err)
+			}
+
+			var got []string
+			for _, e := range errs {
+				got = append(got, e.Error())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse() errors =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func Test_positions(t *testing.T) {
+	data := `# a network
+version: 1
+orderer:
+  type: solo
+organizations:
+- name: Org1
+  peers: 2
+- name: Org2
+  description: |
+    name: not a key
+    - not an item
+channels:
+  - name: mychannel
+    organizations: [Org1, Org2]
+`
+	want := map[string]Position{
+		"version":                      {Line: 2, Column: 1},
+		"orderer":                      {Line: 3, Column: 1},
+		"orderer.type":                 {Line: 4, Column: 3},
+		"organizations":                {Line: 5, Column: 1},
+		"organizations[0]":             {Line: 6, Column: 1},
+		"organizations[0].name":        {Line: 6, Column: 3},
+		"organizations[0].peers":       {Line: 7, Column: 3},
+		"organizations[1]":             {Line: 8, Column: 1},
+		"organizations[1].name":        {Line: 8, Column: 3},
+		"organizations[1].description": {Line: 9, Column: 3},
+		"channels":                     {Line: 12, Column: 1},
+		"channels[0]":                  {Line: 13, Column: 3},
+		"channels[0].name":             {Line: 13, Column: 5},
+		"channels[0].organizations":    {Line: 14, Column: 5},
+	}
+
+	got := positions([]byte(data))
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("positions() = %v, want %v", got, want)
+	}
+
+	if got := lookup(got, "channels[0].organizations[1]"); got != (Position{Line: 14, Column: 5}) {
+		t.Errorf("lookup() = %v, want the position of channels[0].organizations", got)
+	}
+}
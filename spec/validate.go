@@ -0,0 +1,353 @@
+package spec
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gangachris/hlf/semver"
+)
+
+var (
+	versionPattern   = regexp.MustCompile(`^\d+\.\d+(\.\d+)?$`)
+	networkPattern   = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+	domainPattern    = regexp.MustCompile(`^([a-z0-9]([a-z0-9-]*[a-z0-9])?\.)*[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
+	orgPattern       = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9-]*$`)
+	mspIDPattern     = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+	channelPattern   = regexp.MustCompile(`^[a-z][a-z0-9.-]{0,248}$`)
+	chaincodePattern = regexp.MustCompile(`^[A-Za-z0-9]+([A-Za-z0-9_-]*[A-Za-z0-9])?$`)
+	sizePattern      = regexp.MustCompile(`^\d+ ?(KB|MB|GB)$`)
+)
+
+// maxPort is the highest port, the nodes use ports from the port base upwards.
+const maxPort = 65535
+
+// validator collects the problems of a network spec.
+type validator struct {
+	errs Errors
+}
+
+func (v *validator) errorf(path, format string, args ...interface{}) {
+	v.errs = append(v.errs, &Error{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// unique reports values found more than once in a set of names.
+func (v *validator) unique(seen map[string]string, path, kind, value string) {
+	if value == "" {
+		return
+	}
+	if first, ok := seen[value]; ok {
+		v.errorf(path, "duplicate %s %s, already used by %s", kind, value, first)
+		return
+	}
+	seen[value] = path
+}
+
+// validate returns every problem of the network spec, with the path of the
+// field each one is about.
+func (n *Network) validate() Errors {
+	v := &validator{}
+
+	switch {
+	case n.Version == 0:
+		v.errorf("version", "version is required, the current version is %d", Version)
+	case n.Version != Version:
+		v.errorf("version", "unsupported version %d, the current version is %d", n.Version, Version)
+	}
+
+	switch {
+	case n.Name == "":
+		v.errorf("name", "name is required")
+	case !networkPattern.MatchString(n.Name):
+		v.errorf("name", "invalid name %s: expected lowercase letters, digits, '_' or '-'", n.Name)
+	}
+
+	if n.Fabric != "" && !versionPattern.MatchString(n.Fabric) {
+		v.errorf("fabric", "invalid fabric version %s: expected a version such as 1.4.4", n.Fabric)
+	}
+
+	if !domainPattern.MatchString(n.Domain) {
+		v.errorf("domain", "invalid domain %s: expected a lowercase domain such as example.com", n.Domain)
+	}
+
+	if n.PortBase < 1024 || n.PortBase > maxPort {
+		v.errorf("portBase", "invalid port base %d: expected a port between 1024 and %d", n.PortBase, maxPort)
+	}
+
+	n.validateStateDatabase(v, "stateDatabase", n.StateDatabase)
+
+	if n.Artifacts == "" {
+		v.errorf("artifacts", "artifacts directory is required")
+	}
+
+	n.validateCrypto(v)
+
+	// orderer and peer organizations share their names, MSP IDs and domains
+	names := map[string]string{}
+	mspIDs := map[string]string{}
+	domains := map[string]string{}
+	n.validateOrderer(v, names, mspIDs, domains)
+	n.validateOrganizations(v, names, mspIDs, domains)
+	n.validateChannels(v)
+	n.validateChaincodes(v)
+
+	return v.errs
+}
+
+func (n *Network) validateStateDatabase(v *validator, path, database string) {
+	if database != LevelDB && database != CouchDB {
+		v.errorf(path, "invalid state database %s: expected %s or %s", database, LevelDB, CouchDB)
+	}
+}
+
+func (n *Network) validateCrypto(v *validator) {
+	crypto := n.Crypto
+
+	switch crypto.Generator {
+	case GeneratorCryptogen, GeneratorNative:
+	default:
+		v.errorf("crypto.generator", "invalid generator %s: expected %s or %s", crypto.Generator, GeneratorCryptogen, GeneratorNative)
+	}
+
+	if validity, err := time.ParseDuration(crypto.CAValidity); err != nil || validity <= 0 {
+		v.errorf("crypto.caValidity", "invalid CA validity %s: expected a duration such as 87600h", crypto.CAValidity)
+	}
+	if validity, err := time.ParseDuration(crypto.Validity); err != nil || validity <= 0 {
+		v.errorf("crypto.validity", "invalid validity %s: expected a duration such as 8760h", crypto.Validity)
+	}
+
+	for i, san := range crypto.SANs {
+		if net.ParseIP(san) == nil && !domainPattern.MatchString(san) {
+			v.errorf(fmt.Sprintf("crypto.sans[%d]", i), "invalid SAN %s: expected a lowercase host name or an IP address", san)
+		}
+	}
+}
+
+func (n *Network) validateOrderer(v *validator, names, mspIDs, domains map[string]string) {
+	orderer := n.Orderer
+
+	switch orderer.Type {
+	case OrdererSolo, OrdererKafka, OrdererEtcdRaft:
+	default:
+		v.errorf("orderer.type", "invalid orderer type %s: expected %s, %s or %s", orderer.Type, OrdererSolo, OrdererKafka, OrdererEtcdRaft)
+	}
+
+	if timeout, err := time.ParseDuration(orderer.BatchTimeout); err != nil || timeout <= 0 {
+		v.errorf("orderer.batchTimeout", "invalid batch timeout %s: expected a duration such as 2s", orderer.BatchTimeout)
+	}
+
+	if orderer.MaxMessageCount < 1 {
+		v.errorf("orderer.maxMessageCount", "invalid max message count %d: expected a number greater than 0", orderer.MaxMessageCount)
+	}
+
+	nodes := 0
+	for i, org := range orderer.Organizations {
+		path := fmt.Sprintf("orderer.organizations[%d]", i)
+		n.validateOrganization(v, path, org.Name, org.MSPID, org.Domain)
+		v.unique(names, path+".name", "organization", org.Name)
+		v.unique(mspIDs, path+".mspID", "MSP ID", org.MSPID)
+		v.unique(domains, path+".domain", "domain", org.Domain)
+
+		if org.Nodes < 1 {
+			v.errorf(path+".nodes", "invalid number of nodes %d: expected a number greater than 0", org.Nodes)
+		}
+		nodes += org.Nodes
+	}
+
+	if orderer.Type == OrdererSolo && nodes > 1 {
+		v.errorf("orderer.type", "the %s orderer has a single node, %d are declared", OrdererSolo, nodes)
+	}
+
+	if orderer.Type == OrdererKafka {
+		if orderer.Kafka.Brokers < 1 {
+			v.errorf("orderer.kafka.brokers", "invalid number of brokers %d: expected a number greater than 0", orderer.Kafka.Brokers)
+		}
+		if orderer.Kafka.Zookeepers < 1 || orderer.Kafka.Zookeepers%2 == 0 {
+			v.errorf("orderer.kafka.zookeepers", "invalid number of zookeepers %d: expected an odd number such as 1 or 3", orderer.Kafka.Zookeepers)
+		}
+	} else if orderer.Kafka != (Kafka{}) {
+		v.errorf("orderer.kafka", "kafka is only used by the %s orderer type", OrdererKafka)
+	}
+
+	if orderer.Type == OrdererEtcdRaft {
+		n.validateEtcdRaft(v)
+	} else if orderer.EtcdRaft != (EtcdRaft{}) {
+		v.errorf("orderer.etcdRaft", "etcdRaft is only used by the %s orderer type", OrdererEtcdRaft)
+	}
+}
+
+// validateEtcdRaft checks the options of the raft consensus, the consenters
+// authenticate each other with their TLS certificates.
+func (n *Network) validateEtcdRaft(v *validator) {
+	raft := n.Orderer.EtcdRaft
+
+	if !n.TLS.Enabled {
+		v.errorf("orderer.type", "the %s orderer needs TLS, set tls.enabled", OrdererEtcdRaft)
+	}
+
+	if n.Fabric != "" && versionPattern.MatchString(n.Fabric) {
+		if ok, err := semver.CorrectVersion("1.4.1", n.Fabric); err == nil && !ok {
+			uses := n.Fabric
+			if n.configuredFabric {
+				uses = "the configured fabric version " + n.Fabric + ", set fabric in the spec"
+			}
+			v.errorf("orderer.type", "the %s orderer needs fabric 1.4.1 or later, the network uses %s", OrdererEtcdRaft, uses)
+		}
+	}
+
+	if interval, err := time.ParseDuration(raft.TickInterval); err != nil || interval <= 0 {
+		v.errorf("orderer.etcdRaft.tickInterval", "invalid tick interval %s: expected a duration such as 500ms", raft.TickInterval)
+	}
+
+	if raft.HeartbeatTick < 1 {
+		v.errorf("orderer.etcdRaft.heartbeatTick", "invalid heartbeat tick %d: expected a number greater than 0", raft.HeartbeatTick)
+	}
+	if raft.ElectionTick <= raft.HeartbeatTick {
+		v.errorf("orderer.etcdRaft.electionTick", "invalid election tick %d: expected a number greater than the heartbeat tick %d", raft.ElectionTick, raft.HeartbeatTick)
+	}
+
+	if raft.MaxInflightBlocks < 1 {
+		v.errorf("orderer.etcdRaft.maxInflightBlocks", "invalid max inflight blocks %d: expected a number greater than 0", raft.MaxInflightBlocks)
+	}
+
+	if !sizePattern.MatchString(raft.SnapshotIntervalSize) {
+		v.errorf("orderer.etcdRaft.snapshotIntervalSize", "invalid snapshot interval size %s: expected a size such as 16 MB", raft.SnapshotIntervalSize)
+	}
+}
+
+// validateOrganization checks the fields orderer and peer organizations share.
+func (n *Network) validateOrganization(v *validator, path, name, mspID, domain string) {
+	switch {
+	case name == "":
+		v.errorf(path+".name", "name is required")
+	case !orgPattern.MatchString(name):
+		v.errorf(path+".name", "invalid name %s: expected letters, digits or '-', starting with a letter", name)
+	}
+
+	if mspID != "" && !mspIDPattern.MatchString(mspID) {
+		v.errorf(path+".mspID", "invalid MSP ID %s: expected letters, digits, '.', '_' or '-'", mspID)
+	}
+
+	if domain != "" && !domainPattern.MatchString(domain) {
+		v.errorf(path+".domain", "invalid domain %s: expected a lowercase domain such as org1.example.com", domain)
+	}
+}
+
+func (n *Network) validateOrganizations(v *validator, names, mspIDs, domains map[string]string) {
+	if len(n.Organizations) == 0 {
+		v.errorf("organizations", "at least one peer organization is required")
+	}
+
+	for i, org := range n.Organizations {
+		path := fmt.Sprintf("organizations[%d]", i)
+		n.validateOrganization(v, path, org.Name, org.MSPID, org.Domain)
+		v.unique(names, path+".name", "organization", org.Name)
+		v.unique(mspIDs, path+".mspID", "MSP ID", org.MSPID)
+		v.unique(domains, path+".domain", "domain", org.Domain)
+
+		if org.Users < 0 {
+			v.errorf(path+".users", "invalid number of users %d: expected 0 or more", org.Users)
+		}
+		n.validateStateDatabase(v, path+".stateDatabase", org.StateDatabase)
+
+		if org.Peers < 1 {
+			v.errorf(path+".peers", "invalid number of peers %d: expected a number greater than 0", org.Peers)
+			continue
+		}
+
+		for j, anchor := range org.AnchorPeers {
+			if anchor < 0 || anchor >= org.Peers {
+				v.errorf(fmt.Sprintf("%s.anchorPeers[%d]", path, j), "invalid anchor peer %d: the organization has peers 0 to %d", anchor, org.Peers-1)
+			}
+		}
+	}
+}
+
+func (n *Network) validateChannels(v *validator) {
+	names := map[string]string{}
+	for i, channel := range n.Channels {
+		path := fmt.Sprintf("channels[%d]", i)
+		switch {
+		case channel.Name == "":
+			v.errorf(path+".name", "name is required")
+		case !channelPattern.MatchString(channel.Name):
+			v.errorf(path+".name", "invalid channel name %s: expected lowercase letters, digits, '.' or '-', starting with a letter", channel.Name)
+		}
+		v.unique(names, path+".name", "channel", channel.Name)
+
+		members := map[string]string{}
+		for j, name := range channel.Organizations {
+			memberPath := fmt.Sprintf("%s.organizations[%d]", path, j)
+			if _, ok := n.Organization(name); !ok {
+				v.errorf(memberPath, "unknown organization %s, expected one of %s", name, strings.Join(n.organizationNames(), ", "))
+				continue
+			}
+			v.unique(members, memberPath, "member", name)
+		}
+	}
+}
+
+func (n *Network) validateChaincodes(v *validator) {
+	names := map[string]string{}
+	for i, chaincode := range n.Chaincodes {
+		path := fmt.Sprintf("chaincodes[%d]", i)
+		switch {
+		case chaincode.Name == "":
+			v.errorf(path+".name", "name is required")
+		case !chaincodePattern.MatchString(chaincode.Name):
+			v.errorf(path+".name", "invalid chaincode name %s: expected letters, digits, '_' or '-'", chaincode.Name)
+		}
+		v.unique(names, path+".name", "chaincode", chaincode.Name)
+
+		switch chaincode.Language {
+		case Golang, Node, Java:
+		default:
+			v.errorf(path+".language", "invalid language %s: expected %s, %s or %s", chaincode.Language, Golang, Node, Java)
+		}
+
+		if chaincode.Path == "" {
+			v.errorf(path+".path", "path is required")
+		}
+
+		if len(chaincode.Channels) == 0 {
+			v.errorf(path+".channels", "the chaincode is deployed on channels, none are declared")
+		}
+		for j, name := range chaincode.Channels {
+			if !n.hasChannel(name) {
+				v.errorf(fmt.Sprintf("%s.channels[%d]", path, j), "unknown channel %s", name)
+			}
+		}
+	}
+}
+
+func (n *Network) organizationNames() []string {
+	var names []string
+	for _, org := range n.Organizations {
+		names = append(names, org.Name)
+	}
+	return names
+}
+
+func (n *Network) hasChannel(name string) bool {
+	for _, channel := range n.Channels {
+		if channel.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// sorted returns the errors in line order, errors without a position last.
+func (e Errors) sorted() Errors {
+	sort.SliceStable(e, func(i, j int) bool {
+		a, b := e[i].Line, e[j].Line
+		if a == 0 || b == 0 {
+			return a != 0 && b == 0
+		}
+		return a < b
+	})
+	return e
+}